@@ -0,0 +1,94 @@
+//go:build !slim || influxdb
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/output"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// RunBackfill computes the samples of a past time range and writes them to
+// InfluxDB, e.g. to give a new installation history to graph. Points are
+// identified by their time, so backfilling a range again replaces them.
+func RunBackfill(cfg *config.Configuration, args []string) error {
+	flags := flag.NewFlagSet("backfill", flag.ExitOnError)
+	from := flags.String("from", "", "RFC3339 time or 2006-01-02 date to start the backfill at")
+	to := flags.String("to", "", "RFC3339 time or 2006-01-02 date to end the backfill at; defaults to now")
+	interval := flags.Duration("interval", cfg.PollInterval, "time between the backfilled samples")
+	rate := flags.Float64("rate", 0, "points written per second at most; unlimited when 0")
+	checkpoint := flags.String("checkpoint", "", "file to save the progress to and resume an interrupted backfill from")
+	progress := flags.Duration("progress", 10*time.Second, "time between progress reports and checkpoints")
+	flags.Parse(args)
+	if *rate < 0 || *progress < 0 {
+		return fmt.Errorf("-rate and -progress must not be negative")
+	}
+
+	start, err := parseReplayTime(*from)
+	if err != nil {
+		return fmt.Errorf("invalid -from %q, %s", *from, err)
+	}
+	end := time.Now()
+	if *to != "" {
+		end, err = parseReplayTime(*to)
+		if err != nil {
+			return fmt.Errorf("invalid -to %q, %s", *to, err)
+		}
+	}
+	if !end.After(start) {
+		return fmt.Errorf("-to must be after -from")
+	}
+
+	bucket, err := config.InfluxWriteDestination(cfg)
+	if err != nil {
+		return err
+	}
+	client := output.NewInfluxClient(cfg, nil, "")
+	defer client.Close()
+	writeAPI := client.WriteAPI(cfg.InfluxDB.Organization, bucket)
+	var failed atomic.Int64
+	errorsDone := make(chan struct{})
+	go func() {
+		defer close(errorsDone)
+		for err := range writeAPI.Errors() {
+			if failed.Add(1) == 1 {
+				log.WithFields(log.Fields{
+					"op":    "RunBackfill",
+					"error": err,
+				}).Error("encountered error on writing to InfluxDB")
+			}
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	written, err := output.Backfill(ctx, *cfg, writeAPI, start, end, *interval, output.BackfillOptions{
+		Rate:       *rate,
+		Checkpoint: *checkpoint,
+		Progress:   *progress,
+	})
+	writeAPI.Flush()
+	client.Close()
+	<-errorsDone
+	log.WithFields(log.Fields{
+		"op":      "RunBackfill",
+		"from":    start.Format(time.RFC3339),
+		"to":      end.Format(time.RFC3339),
+		"written": written,
+	}).Info("backfilled samples")
+	if err != nil {
+		return fmt.Errorf("backfill interrupted, %s", err)
+	}
+	if n := failed.Load(); n > 0 {
+		return fmt.Errorf("%d batches failed to write", n)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"github.com/iwvelando/daylight-timeseries/pkg/output"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// BackfillToday writes the samples of the configured location at every
+// pollInterval boundary from the local midnight of now until now to the
+// collector's outputs, so that daily dashboards show a full day however late
+// the exporter started. The local midnight is that of localTime.timezone
+// when configured and of the host otherwise. The boundaries are those the
+// polling loop aligns to, so restarts rewrite the same points. It returns
+// how many samples were written. Samples wait for room in the output queues
+// rather than being dropped, and the backfill stops when ctx is done.
+func BackfillToday(ctx context.Context, cfg config.Configuration, collector *Collector, now time.Time) int {
+	location := cfg.LocalTime.Location()
+	local := now.In(location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+	interval := cfg.PollInterval
+	from := midnight.Truncate(interval)
+	if from.Before(midnight) {
+		from = from.Add(interval)
+	}
+
+	ctx = config.WithRequestID(ctx, config.NewRequestID())
+	poller := daylight.NewPoller(cfg.DaylightOptions(), from)
+	filter := &WriteFilter{Mode: cfg.WriteMode, Heartbeat: cfg.Heartbeat}
+	written := 0
+	for t := from; t.Before(now); t = t.Add(interval) {
+		if ctx.Err() != nil {
+			break
+		}
+		sample, transitions := poller.Poll(t)
+		if !filter.Due(t, len(transitions) > 0) {
+			continue
+		}
+		if len(cfg.Hooks) > 0 {
+			sample = output.ApplyHooks(ctx, cfg.Hooks, sample)
+		}
+		collector.WriteWait(ctx, []daylight.Sample{sample})
+		if ctx.Err() != nil {
+			break
+		}
+		written++
+	}
+	if ctx.Err() != nil {
+		config.Logger(ctx).WithFields(log.Fields{
+			"op":      "BackfillToday",
+			"written": written,
+		}).Warn("stopped backfilling today's samples")
+		return written
+	}
+	collector.Flush()
+	config.Logger(ctx).WithFields(log.Fields{
+		"op":      "BackfillToday",
+		"from":    from.Format(time.RFC3339),
+		"to":      now.Format(time.RFC3339),
+		"written": written,
+	}).Info("backfilled today's samples")
+	return written
+}
@@ -0,0 +1,267 @@
+//go:build !slim || influxdb
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	influx "github.com/influxdata/influxdb-client-go/v2"
+	influxAPI "github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/influxdata/influxdb-client-go/v2/domain"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"github.com/iwvelando/daylight-timeseries/pkg/output"
+	"net/http"
+	"time"
+)
+
+// BootstrapOptions parameterizes the tasks and dashboard installed by the
+// bootstrap subcommand
+type BootstrapOptions struct {
+	Bucket           string
+	Measurement      string
+	DownsampleBucket string
+	DownsampleWindow time.Duration
+	DownsampleEvery  time.Duration
+	TransitionsEvery time.Duration
+	Dashboard        string
+}
+
+// fluxDuration formats a whole number of minutes as a Flux duration literal
+func fluxDuration(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dm", int(d.Minutes()))
+}
+
+// BootstrapTasks returns the Flux of the recommended tasks keyed by task name
+func BootstrapTasks(options BootstrapOptions) map[string]string {
+	bucket := output.FluxString(options.Bucket)
+	measurement := output.FluxString(options.Measurement)
+
+	// Booleans become 0/1 so that the hourly mean of daylight is the fraction
+	// of the hour that was in daylight
+	downsample := fmt.Sprintf(`from(bucket: %s)
+  |> range(start: -%s)
+  |> filter(fn: (r) => r._measurement == %s)
+  |> toFloat()
+  |> aggregateWindow(every: %s, fn: mean, createEmpty: false)
+  |> set(key: "_measurement", value: %s)
+  |> to(bucket: %s)
+`, bucket, fluxDuration(options.DownsampleEvery), measurement,
+		fluxDuration(options.DownsampleWindow), output.FluxString(options.Measurement+"_"+fluxDuration(options.DownsampleWindow)),
+		output.FluxString(options.DownsampleBucket))
+
+	// The range overlaps the previous run so that a transition right at a run
+	// boundary is still seen; rewriting a transition is harmless
+	transitions := fmt.Sprintf(`from(bucket: %s)
+  |> range(start: -%s)
+  |> filter(fn: (r) => r._measurement == %s and r._field == "daylight")
+  |> toInt()
+  |> difference()
+  |> filter(fn: (r) => r._value != 0)
+  |> map(fn: (r) => ({r with _measurement: %s, _field: "event", _value: if r._value > 0 then "sunrise" else "sunset"}))
+  |> to(bucket: %s)
+`, bucket, fluxDuration(options.TransitionsEvery+10*time.Minute), measurement,
+		output.FluxString(options.Measurement+"_transitions"), bucket)
+
+	return map[string]string{
+		options.Measurement + " downsample":  downsample,
+		options.Measurement + " transitions": transitions,
+	}
+}
+
+// taskEvery returns the schedule of a bootstrap task
+func (options BootstrapOptions) taskEvery(name string) time.Duration {
+	if name == options.Measurement+" downsample" {
+		return options.DownsampleEvery
+	}
+	return options.TransitionsEvery
+}
+
+// BootstrapDashboard returns an InfluxDB template with a dashboard of the
+// main fields
+func BootstrapDashboard(options BootstrapOptions) map[string]interface{} {
+	query := func(filter string, conversion string) string {
+		return fmt.Sprintf(`from(bucket: %s)
+  |> range(start: v.timeRangeStart, stop: v.timeRangeStop)
+  |> filter(fn: (r) => r._measurement == %s and %s)%s
+  |> aggregateWindow(every: v.windowPeriod, fn: last, createEmpty: false)`,
+			output.FluxString(options.Bucket), output.FluxString(options.Measurement), filter, conversion)
+	}
+	chart := func(name string, y int, geom string, flux string) map[string]interface{} {
+		return map[string]interface{}{
+			"kind":    "Xy",
+			"name":    name,
+			"xPos":    0,
+			"yPos":    y,
+			"width":   12,
+			"height":  3,
+			"geom":    geom,
+			"queries": []map[string]string{{"query": flux}},
+			"axes": []map[string]string{
+				{"name": "x", "base": "10", "scale": "linear"},
+				{"name": "y", "base": "10", "scale": "linear"},
+			},
+			"colors": []map[string]string{
+				{"type": "scale", "hex": "#31C0F6", "name": "Nineteen Eighty Four"},
+				{"type": "scale", "hex": "#A500A5", "name": "Nineteen Eighty Four"},
+				{"type": "scale", "hex": "#FF7E27", "name": "Nineteen Eighty Four"},
+			},
+		}
+	}
+
+	return map[string]interface{}{
+		"apiVersion": "influxdata.com/v2alpha1",
+		"kind":       "Dashboard",
+		"metadata":   map[string]string{"name": "daylight-timeseries"},
+		"spec": map[string]interface{}{
+			"name":        options.Dashboard,
+			"description": "Daylight written by daylight-timeseries",
+			"charts": []map[string]interface{}{
+				chart("Daylight", 0, "step", query(`(r._field == "daylight" or r._field == "daylight_offset")`, "\n  |> toInt()")),
+				chart("Declination", 3, "line", query(`r._field == "declination"`, "")),
+				chart("Equation of time", 6, "line", query(`r._field == "equation_of_time"`, "")),
+			},
+		},
+	}
+}
+
+// InstallTasks creates the bootstrap tasks, updating the Flux of tasks that
+// already exist with the same name
+func InstallTasks(ctx context.Context, client influx.Client, org *domain.Organization, options BootstrapOptions) error {
+	tasksAPI := client.TasksAPI()
+	for name, flux := range BootstrapTasks(options) {
+		existing, err := tasksAPI.FindTasks(ctx, &influxAPI.TaskFilter{Name: name, OrgID: *org.Id})
+		if err != nil {
+			return fmt.Errorf("unable to look up task %s, %s", name, err)
+		}
+		every := fluxDuration(options.taskEvery(name))
+		if len(existing) > 0 {
+			task := existing[0]
+			task.Flux = fmt.Sprintf("option task = {name: %s, every: %s}\n\n%s", output.FluxString(name), every, flux)
+			task.Every = &every
+			_, err = tasksAPI.UpdateTask(ctx, &task)
+			if err != nil {
+				return fmt.Errorf("unable to update task %s, %s", name, err)
+			}
+			fmt.Printf("updated task %s\n", name)
+			continue
+		}
+		_, err = tasksAPI.CreateTaskWithEvery(ctx, name, flux, every, *org.Id)
+		if err != nil {
+			return fmt.Errorf("unable to create task %s, %s", name, err)
+		}
+		fmt.Printf("created task %s\n", name)
+	}
+	return nil
+}
+
+// InstallDashboard applies the dashboard template unless a dashboard with the
+// same name already exists
+func InstallDashboard(ctx context.Context, client influx.Client, org *domain.Organization, options BootstrapOptions) error {
+	dashboards, err := client.APIClient().GetDashboards(ctx, &domain.GetDashboardsParams{OrgID: org.Id})
+	if err != nil {
+		return fmt.Errorf("unable to list dashboards, %s", err)
+	}
+	if dashboards.Dashboards != nil {
+		for _, dashboard := range *dashboards.Dashboards {
+			if dashboard.Name == options.Dashboard {
+				fmt.Printf("dashboard %s already exists\n", options.Dashboard)
+				return nil
+			}
+		}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"orgID": *org.Id,
+		"template": map[string]interface{}{
+			"contentType": "json",
+			"contents":    []interface{}{BootstrapDashboard(options)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to encode dashboard template, %s", err)
+	}
+
+	service := client.HTTPService()
+	perr := service.DoPostRequest(ctx, service.ServerAPIURL()+"templates/apply", bytes.NewReader(body),
+		func(req *http.Request) {
+			req.Header.Set("Content-Type", "application/json")
+		},
+		func(resp *http.Response) error {
+			return resp.Body.Close()
+		})
+	if perr != nil {
+		return fmt.Errorf("unable to apply dashboard template, %s", perr)
+	}
+	fmt.Printf("created dashboard %s\n", options.Dashboard)
+	return nil
+}
+
+// RunBootstrap implements the bootstrap subcommand
+func RunBootstrap(cfg *config.Configuration, args []string) error {
+	flags := flag.NewFlagSet("bootstrap", flag.ExitOnError)
+	options := BootstrapOptions{Bucket: cfg.InfluxDB.Bucket, Measurement: daylight.Measurement}
+	flags.StringVar(&options.Bucket, "bucket", options.Bucket, "bucket the daylight points are written to")
+	flags.StringVar(&options.Measurement, "measurement", options.Measurement, "measurement the daylight points are written to")
+	flags.StringVar(&options.DownsampleBucket, "downsample-bucket", "", "bucket for downsampled points; defaults to -bucket")
+	flags.DurationVar(&options.DownsampleWindow, "downsample-window", time.Hour, "window the downsampling task averages over")
+	flags.DurationVar(&options.DownsampleEvery, "downsample-every", 24*time.Hour, "how often the downsampling task runs")
+	flags.DurationVar(&options.TransitionsEvery, "transitions-every", time.Hour, "how often the transition detection task runs")
+	flags.StringVar(&options.Dashboard, "dashboard", "Daylight", "name of the dashboard to create")
+	tasks := flags.Bool("tasks", true, "install the Flux tasks")
+	dashboard := flags.Bool("install-dashboard", true, "install the dashboard")
+	dryRun := flags.Bool("dry-run", false, "print the tasks and dashboard template instead of installing them")
+	flags.Parse(args)
+
+	if options.Bucket == "" {
+		return fmt.Errorf("bootstrap requires an InfluxDB v2 bucket")
+	}
+	if options.DownsampleBucket == "" {
+		options.DownsampleBucket = options.Bucket
+	}
+	for _, d := range []time.Duration{options.DownsampleWindow, options.DownsampleEvery, options.TransitionsEvery} {
+		if d < time.Minute || d%time.Minute != 0 {
+			return fmt.Errorf("task durations must be whole minutes")
+		}
+	}
+
+	if *dryRun {
+		for name, flux := range BootstrapTasks(options) {
+			fmt.Printf("# task %s, every %s\n%s\n", name, fluxDuration(options.taskEvery(name)), flux)
+		}
+		template, err := json.MarshalIndent([]interface{}{BootstrapDashboard(options)}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("# dashboard template\n%s\n", template)
+		return nil
+	}
+
+	client := output.NewInfluxClient(cfg, nil, "")
+	defer client.Close()
+	ctx := context.Background()
+	org, err := client.OrganizationsAPI().FindOrganizationByName(ctx, cfg.InfluxDB.Organization)
+	if err != nil {
+		return fmt.Errorf("unable to find organization %s, %s", cfg.InfluxDB.Organization, err)
+	}
+
+	if *tasks {
+		err = InstallTasks(ctx, client, org, options)
+		if err != nil {
+			return err
+		}
+	}
+	if *dashboard {
+		err = InstallDashboard(ctx, client, org, options)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"github.com/iwvelando/daylight-timeseries/pkg/output"
+	log "github.com/sirupsen/logrus"
+	"maps"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Collector is the collector role: it writes every sample, along with the
+// named locations and location groups, to the selected outputs
+type Collector struct {
+	// mu guards outputs, which a reload replaces while shutdown may be
+	// flushing them
+	mu      sync.Mutex
+	outputs []output.Output
+	// settings are what each output was opened with, by name, to tell which
+	// outputs a reload changes
+	settings     map[string][]interface{}
+	groups       []*daylight.GroupPoller
+	locations    []*daylight.Poller
+	supplemental *daylight.SupplementalPoller
+	forecast     *daylight.ForecastPoller
+	// filters select the samples to write in transitions mode: the first for
+	// the configured location, then one per named location and group
+	filters []*WriteFilter
+	// groupStates are the in_daylight counts of the groups last polled
+	groupStates []interface{}
+	// tags adds the dynamic tags, when configured, refreshed until stopTags
+	tags     *DynamicTagSource
+	stopTags context.CancelFunc
+	// drift estimates the clock offset from the light sensor, when
+	// configured, read until stopDrift
+	drift     *ClockDrift
+	stopDrift context.CancelFunc
+}
+
+// StartCollector opens the outputs of the configuration
+func StartCollector(cfg *config.Configuration, tracker *output.StatusTracker) (*Collector, error) {
+	outputs, err := output.OpenOutputs(cfg, tracker)
+	if err != nil {
+		return nil, err
+	}
+	c := &Collector{outputs: outputs, settings: map[string][]interface{}{}}
+	for _, output := range cfg.OutputConfigs() {
+		c.settings[output.Name] = cfg.OutputSettings(output)
+	}
+	c.configure(cfg)
+	return c, nil
+}
+
+// startLocationPollers returns a poller per named location, resolving their
+// place tags when geocoding is enabled
+func startLocationPollers(cfg config.Configuration, now time.Time) []*daylight.Poller {
+	var pollers []*daylight.Poller
+	for _, location := range cfg.Locations {
+		locationConfig := cfg.ForLocation(location)
+		if cfg.Geocode.Enabled {
+			locationConfig.ResolvePlace(context.Background())
+		}
+		pollers = append(pollers, daylight.NewPoller(locationConfig.DaylightOptions(), now))
+	}
+	return pollers
+}
+
+// configure sets up everything but the outputs from the configuration
+func (c *Collector) configure(cfg *config.Configuration) {
+	c.locations, c.groups = startLocationPollers(*cfg, time.Now()), nil
+	for _, group := range cfg.LocationGroups {
+		c.groups = append(c.groups, daylight.NewGroupPoller(group))
+	}
+	c.supplemental, c.forecast = nil, nil
+	if cfg.SupplementalLight.Enabled() {
+		c.supplemental = daylight.NewSupplementalPoller(cfg.DaylightOptions())
+	}
+	if cfg.Forecast.Enabled() {
+		c.forecast = daylight.NewForecastPoller(cfg.DaylightOptions())
+	}
+	c.filters = nil
+	for i := 0; i <= len(c.locations)+len(c.groups); i++ {
+		c.filters = append(c.filters, &WriteFilter{Mode: cfg.WriteMode, Heartbeat: cfg.Heartbeat})
+	}
+	c.groupStates = make([]interface{}, len(c.groups))
+	if c.stopTags != nil {
+		c.stopTags()
+	}
+	c.tags, c.stopTags = nil, nil
+	if cfg.DynamicTags.Enabled() {
+		var ctx context.Context
+		ctx, c.stopTags = context.WithCancel(context.Background())
+		c.tags = NewDynamicTagSource(ctx, cfg.DynamicTags)
+		go output.Supervise(ctx, "dynamic_tags", c.tags.Run)
+	}
+	// A reload keeps the daily estimates unless the sensor or longitude changed
+	enabled := cfg.LightSensor.Enabled() && cfg.FieldEnabled("clock_offset_seconds")
+	if c.drift != nil && enabled && c.drift.longitude == cfg.Longitude &&
+		reflect.DeepEqual(c.drift.config, cfg.LightSensor.WithDefaults()) {
+		return
+	}
+	if c.stopDrift != nil {
+		c.stopDrift()
+	}
+	c.drift, c.stopDrift = nil, nil
+	if enabled {
+		var ctx context.Context
+		ctx, c.stopDrift = context.WithCancel(context.Background())
+		c.drift = NewClockDrift(cfg.LightSensor, cfg.Longitude)
+		go output.Supervise(ctx, "light_sensor", c.drift.Run)
+	}
+}
+
+// currentOutputs returns the outputs samples go to
+func (c *Collector) currentOutputs() []output.Output {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.outputs
+}
+
+// Collect writes a sample computed for time t with the transitions since the
+// previous one, along with those of the named locations and location groups
+// at t and the daily supplemental light and forecast samples, to every output. In
+// transitions mode a series is only written when it changed or its
+// heartbeat is due; for groups a change is one of their count in daylight.
+func (c *Collector) Collect(ctx context.Context, sample daylight.Sample, transitions []daylight.Transition, t time.Time) {
+	if c.drift != nil {
+		if offset, ok := c.drift.Offset(); ok {
+			// The sample was already published to the stream and /v1/recent,
+			// which read its fields concurrently, so add to a copy
+			sample.Fields = maps.Clone(sample.Fields)
+			sample.Fields["clock_offset_seconds"] = offset.Seconds()
+		}
+	}
+	var samples []daylight.Sample
+	if c.filters[0].Due(t, len(transitions) > 0) {
+		samples = append(samples, sample)
+	}
+	for i, poller := range c.locations {
+		s, changed := poller.Poll(t)
+		s.Measurement = daylight.Measurement
+		if c.filters[1+i].Due(t, len(changed) > 0) {
+			samples = append(samples, s)
+		}
+	}
+	for i, groupPoller := range c.groups {
+		s := groupPoller.Poll(t)
+		changed := c.groupStates[i] != nil && c.groupStates[i] != s.Fields["in_daylight"]
+		c.groupStates[i] = s.Fields["in_daylight"]
+		if c.filters[1+len(c.locations)+i].Due(t, changed) {
+			samples = append(samples, s)
+		}
+	}
+	if c.supplemental != nil {
+		if daily, ok := c.supplemental.Poll(t); ok {
+			samples = append(samples, daily)
+		}
+	}
+	if c.forecast != nil {
+		samples = append(samples, c.forecast.Poll(t)...)
+	}
+	c.Write(ctx, samples)
+	config.Logger(ctx).WithFields(log.Fields{
+		"op":   "main",
+		"time": t.Format(time.RFC3339Nano),
+	}).Debug("sample written")
+}
+
+// Write writes samples to every output, with the dynamic tags
+func (c *Collector) Write(ctx context.Context, samples []daylight.Sample) {
+	c.write(ctx, samples, false)
+}
+
+// WriteWait writes samples like Write, but waits for room in the output
+// queues instead of dropping samples, until ctx is done; it suits writing
+// many samples at once, as backfills do
+func (c *Collector) WriteWait(ctx context.Context, samples []daylight.Sample) {
+	c.write(ctx, samples, true)
+}
+
+// waitingOutput is implemented by outputs that can wait for room to queue a
+// sample
+type waitingOutput interface {
+	WriteWait(ctx context.Context, sample daylight.Sample) error
+}
+
+func (c *Collector) write(ctx context.Context, samples []daylight.Sample, wait bool) {
+	if c.tags != nil {
+		for i := range samples {
+			samples[i] = c.tags.Apply(samples[i])
+		}
+	}
+	for _, output := range c.currentOutputs() {
+		for _, s := range samples {
+			var err error
+			if waiting, ok := output.(waitingOutput); ok && wait {
+				err = waiting.WriteWait(ctx, s)
+			} else {
+				err = output.Write(ctx, s)
+			}
+			if wait && ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				config.Logger(ctx).WithFields(log.Fields{
+					"op":     "Collector.Write",
+					"output": output.Name(),
+					"error":  err,
+				}).Error("failed to write sample")
+			}
+		}
+	}
+}
+
+// Flush delivers the samples buffered by the outputs
+func (c *Collector) Flush() {
+	for _, output := range c.currentOutputs() {
+		output.Flush()
+	}
+}
+
+// Close flushes and closes the outputs, returning an error when any of them
+// failed to
+func (c *Collector) Close() error {
+	if c.stopTags != nil {
+		c.stopTags()
+	}
+	if c.stopDrift != nil {
+		c.stopDrift()
+	}
+	failed := 0
+	for _, output := range c.currentOutputs() {
+		err := output.Close()
+		if err != nil {
+			failed++
+			log.WithFields(log.Fields{
+				"op":     "Collector.Close",
+				"output": output.Name(),
+				"error":  err,
+			}).Error("failed to close output")
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of the outputs failed to close", failed)
+	}
+	return nil
+}
+
+// NextChange returns the earliest instant after now at which the samples of
+// a named location or location group can change
+func (c *Collector) NextChange(now time.Time) time.Time {
+	var instants []time.Time
+	for _, poller := range c.locations {
+		instants = append(instants, poller.NextChange(now))
+	}
+	for _, group := range c.groups {
+		instants = append(instants, group.NextChange(now))
+	}
+	return daylight.EarliestAfter(now, instants...)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ColorTemperaturePoint is one step of a color temperature schedule
+type ColorTemperaturePoint struct {
+	Time      time.Time `json:"time"`
+	Elevation float64   `json:"elevation"`
+	Kelvin    int       `json:"kelvin"`
+}
+
+// CalculateColorTemperatureSchedule returns the suggested color temperature
+// every step from from until from plus duration
+func CalculateColorTemperatureSchedule(cfg config.Configuration, from time.Time, duration, step time.Duration) []ColorTemperaturePoint {
+	var points []ColorTemperaturePoint
+	for t := from; !t.After(from.Add(duration)); t = t.Add(step) {
+		elevation := daylight.CalculateSolarPosition(cfg.Latitude, cfg.Longitude, t).Elevation
+		points = append(points, ColorTemperaturePoint{
+			Time:      t,
+			Elevation: elevation,
+			Kelvin:    cfg.ColorTemperature.Kelvin(elevation),
+		})
+	}
+	return points
+}
+
+// WriteColorTemperatureSchedule encodes a schedule as csv or json
+func WriteColorTemperatureSchedule(w io.Writer, points []ColorTemperaturePoint, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(points)
+	case "csv":
+		out := csv.NewWriter(w)
+		out.Write([]string{"time", "elevation", "kelvin"})
+		for _, p := range points {
+			out.Write([]string{
+				p.Time.Format(time.RFC3339),
+				strconv.FormatFloat(p.Elevation, 'f', 2, 64),
+				strconv.Itoa(p.Kelvin),
+			})
+		}
+		out.Flush()
+		return out.Error()
+	}
+	return fmt.Errorf("unknown format %q, expected csv or json", format)
+}
+
+// ColorTemperatureHandler serves the color temperature schedule starting now;
+// the hours, step and format query parameters default to 24, 15m and json
+func ColorTemperatureHandler(cfg config.Configuration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hours := 24
+		if value := r.URL.Query().Get("hours"); value != "" {
+			var err error
+			hours, err = strconv.Atoi(value)
+			if err != nil || hours < 0 || hours > 24*31 {
+				http.Error(w, "hours must be between 0 and 744", http.StatusBadRequest)
+				return
+			}
+		}
+		step := 15 * time.Minute
+		if value := r.URL.Query().Get("step"); value != "" {
+			var err error
+			step, err = time.ParseDuration(value)
+			if err != nil || step < time.Minute {
+				http.Error(w, "step must be a duration of at least 1m", http.StatusBadRequest)
+				return
+			}
+		}
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "json"
+		}
+		if format != "json" && format != "csv" {
+			http.Error(w, "format must be csv or json", http.StatusBadRequest)
+			return
+		}
+
+		points := CalculateColorTemperatureSchedule(cfg, time.Now().Truncate(time.Minute), time.Duration(hours)*time.Hour, step)
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv")
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		WriteColorTemperatureSchedule(w, points, format)
+	})
+}
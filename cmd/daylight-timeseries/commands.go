@@ -0,0 +1,135 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"sort"
+)
+
+// Command is a one-shot subcommand that runs instead of the polling loop
+type Command struct {
+	Description string
+	NoConfig    bool
+	Run         func(cfg *config.Configuration, args []string) error
+}
+
+var Commands = map[string]Command{
+	"report": {
+		Description: "compare computed sunrise/sunset against a reference CSV",
+		Run:         RunReport,
+	},
+	"status": {
+		Description: "exit 0 if the running instance wrote recently, 1 otherwise",
+		Run:         RunStatus,
+	},
+	"ephemeris": {
+		Description: "pre-generate an ephemeris file for the configured location",
+		Run:         RunEphemeris,
+	},
+	"verify": {
+		Description: "replay a time range with a fake clock and check the transitions",
+		Run:         RunVerify,
+	},
+	"backfill": {
+		Description: "compute the samples of a past time range and write them to InfluxDB",
+		Run:         RunBackfill,
+	},
+	"migrate": {
+		Description: "rewrite historical points in InfluxDB after a schema change",
+		Run:         RunMigrate,
+	},
+	"schedule": {
+		Description: "export a twilight based lighting schedule as CSV or JSON",
+		Run:         RunSchedule,
+	},
+	"sweep": {
+		Description: "compare day length and sunrise/sunset across candidate locations",
+		NoConfig:    true,
+		Run:         RunSweep,
+	},
+	"bootstrap": {
+		Description: "install recommended Flux tasks and a dashboard into InfluxDB 2.x",
+		Run:         RunBootstrap,
+	},
+	"display": {
+		Description: "render the e-ink display summary image as PNG or SVG",
+		Run:         RunDisplay,
+	},
+	"migrate-config": {
+		Description: "rewrite bare integer durations in the configuration as duration strings",
+		NoConfig:    true,
+		Run:         RunMigrateConfig,
+	},
+	"at": {
+		Description: "tell whether it was or will be daylight at a given time",
+		Run:         RunAt,
+	},
+	"print": {
+		Description: "print today's sun events; with -lat and -lon no configuration file is needed",
+		Run:         RunPrint,
+	},
+	"install-service": {
+		Description: "register the binary with this configuration as a systemd or launchd service",
+		Run:         RunInstallService,
+	},
+	"uninstall-service": {
+		Description: "stop and remove the service registered by install-service",
+		NoConfig:    true,
+		Run:         RunUninstallService,
+	},
+	"lookup": {
+		Description: "print the daylight state for a time from an ephemeris file",
+		NoConfig:    true,
+		Run:         RunLookup,
+	},
+}
+
+// Usage prints the global flags and available commands
+func Usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: daylight-timeseries [flags] [command [command flags]]\n\nFlags:\n")
+	flag.PrintDefaults()
+
+	names := make([]string, 0, len(Commands))
+	for name := range Commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(out, "\nCommands:\n")
+	for _, name := range names {
+		fmt.Fprintf(out, "  %-12s %s\n", name, Commands[name].Description)
+	}
+}
+
+// RunCommand loads the configuration if the command needs it and runs it
+func RunCommand(configPath string, name string, args []string) {
+	command, ok := Commands[name]
+	if !ok {
+		log.WithFields(log.Fields{
+			"op": "main.RunCommand",
+		}).Fatal(fmt.Sprintf("unknown command %s", name))
+	}
+
+	var cfg *config.Configuration
+	if !command.NoConfig {
+		var err error
+		cfg, err = loadConfiguration(configPath)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"op":    "main.LoadConfiguration",
+				"error": err,
+			}).Fatal("failed to load configuration")
+		}
+	}
+
+	err := command.Run(cfg, args)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"op":      "main.RunCommand",
+			"command": name,
+			"error":   err,
+		}).Fatal("command failed")
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// WriteDaylightAt writes a daylight state as text or JSON
+func WriteDaylightAt(w io.Writer, at daylight.DaylightAt, format string) error {
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(at)
+	}
+	sunrise, sunset := "-", "-"
+	if at.Sunrise != nil {
+		sunrise = at.Sunrise.Format(time.RFC3339)
+	}
+	if at.Sunset != nil {
+		sunset = at.Sunset.Format(time.RFC3339)
+	}
+	_, err := fmt.Fprintf(w, "time=%s daylight=%t daylight_offset=%t phase=%s elevation=%.2f sunrise=%s sunset=%s\n",
+		at.Time.Format(time.RFC3339), at.Daylight, at.DaylightOffset, at.Phase, at.Elevation, sunrise, sunset)
+	return err
+}
+
+// queryLocation returns the configuration for the lat and lon query
+// parameters, which ask about another location than the configured one at
+// sea level, along with the time zone named by timezone, defaulting to the
+// system one
+func queryLocation(cfg config.Configuration, query url.Values) (config.Configuration, *time.Location, error) {
+	location := time.Local
+	if value := query.Get("timezone"); value != "" {
+		var err error
+		location, err = time.LoadLocation(value)
+		if err != nil {
+			return cfg, nil, fmt.Errorf("invalid timezone %s", value)
+		}
+	}
+	latitude, longitude := query.Get("lat"), query.Get("lon")
+	if latitude == "" && longitude == "" {
+		return cfg, location, nil
+	}
+	if latitude == "" || longitude == "" {
+		return cfg, nil, fmt.Errorf("lat and lon must be given together")
+	}
+	var err error
+	cfg.Latitude, err = config.ParseCoordinate(latitude, "latitude")
+	if err != nil {
+		return cfg, nil, fmt.Errorf("invalid lat, %s", err)
+	}
+	cfg.Longitude, err = config.ParseCoordinate(longitude, "longitude")
+	if err != nil {
+		return cfg, nil, fmt.Errorf("invalid lon, %s", err)
+	}
+	err = config.ValidateCoordinates(cfg.Latitude, cfg.Longitude)
+	if err != nil {
+		return cfg, nil, err
+	}
+	cfg.Altitude = 0
+	return cfg, location, nil
+}
+
+// queryDayTimes returns DayTimes for a query location, reporting its
+// coordinates as requested rather than as public ones
+func queryDayTimes(cfg config.Configuration, query url.Values, t, now time.Time) DayTimes {
+	times := dayTimesWithNow(cfg, t, now)
+	if query.Get("lat") != "" {
+		times.Latitude, times.Longitude = cfg.Latitude, cfg.Longitude
+	}
+	return times
+}
+
+// DaylightAtHandler answers GET /v1/daylight?time=<RFC3339>, defaulting to
+// now, or with date=YYYY-MM-DD the sun events of that date; lat and lon ask
+// about another location
+func DaylightAtHandler(cfg config.Configuration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		cfg, location, err := queryLocation(cfg, query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if value := query.Get("date"); value != "" {
+			if query.Get("time") != "" {
+				http.Error(w, "only one of time and date may be given", http.StatusBadRequest)
+				return
+			}
+			day, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				http.Error(w, "date must be YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			times := queryDayTimes(cfg, query, solarDateTime(day, cfg.Longitude).In(location), time.Now().In(location))
+			WriteDayTimes(w, times, "json")
+			return
+		}
+
+		t := time.Now().In(location)
+		if value := query.Get("time"); value != "" {
+			t, err = time.Parse(time.RFC3339, value)
+			if err != nil {
+				http.Error(w, "time must be an RFC3339 timestamp", http.StatusBadRequest)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		WriteDaylightAt(w, daylight.CalculateDaylightAt(cfg.DaylightOptions(), t), "json")
+	})
+}
+
+// NowHandler answers GET /v1/now with today's sun events and the current
+// daylight state; lat and lon ask about another location
+func NowHandler(cfg config.Configuration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		cfg, location, err := queryLocation(cfg, query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		now := time.Now().In(location)
+		w.Header().Set("Content-Type", "application/json")
+		WriteDayTimes(w, queryDayTimes(cfg, query, now, now), "json")
+	})
+}
+
+// RunAt implements the at subcommand
+func RunAt(cfg *config.Configuration, args []string) error {
+	flags := flag.NewFlagSet("at", flag.ExitOnError)
+	at := flags.String("time", "", "RFC3339 time to answer for, in the past or future; defaults to now")
+	format := flags.String("format", "text", "output format, text or json")
+	flags.Parse(args)
+
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("-format must be text or json")
+	}
+	t := time.Now()
+	if *at != "" {
+		var err error
+		t, err = time.Parse(time.RFC3339, *at)
+		if err != nil {
+			return fmt.Errorf("invalid -time %s, %s", *at, err)
+		}
+	}
+	return WriteDaylightAt(os.Stdout, daylight.CalculateDaylightAt(cfg.DaylightOptions(), t), *format)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"github.com/iwvelando/daylight-timeseries/pkg/output"
+	"strings"
+	"time"
+)
+
+// DigestSummary is the content of a daily digest
+type DigestSummary struct {
+	Date            string     `json:"date"`
+	Sunrise         *time.Time `json:"sunrise,omitempty"`
+	Sunset          *time.Time `json:"sunset,omitempty"`
+	DayLength       float64    `json:"day_length"`
+	DayLengthChange float64    `json:"day_length_change"`
+	WriteErrors     *int64     `json:"write_errors,omitempty"`
+	LastError       string     `json:"last_error,omitempty"`
+	VerifyError     string     `json:"verify_error,omitempty"`
+	LastWrite       *time.Time `json:"last_write,omitempty"`
+}
+
+// SummarizeDigest computes the digest for the local day of now; writeErrors is the
+// number of write errors since the previous digest. Without a status, as in
+// the server role, the digest only covers the sun events.
+func SummarizeDigest(d config.Digest, cfg config.Configuration, now time.Time, loc *time.Location, writeErrors int64, status *output.Status) DigestSummary {
+	local := now.In(loc)
+	year, month, day := local.Date()
+	events := daylight.DayEvents(cfg.DaylightOptions(), year, month, day)
+	dayLength := daylight.DayLength(cfg.Latitude, cfg.Longitude, events, year, month, day)
+	yesterday := local.AddDate(0, 0, -1)
+	yesterdayEvents := daylight.DayEvents(cfg.DaylightOptions(), yesterday.Year(), yesterday.Month(), yesterday.Day())
+	yesterdayLength := daylight.DayLength(cfg.Latitude, cfg.Longitude, yesterdayEvents, yesterday.Year(), yesterday.Month(), yesterday.Day())
+
+	summary := DigestSummary{
+		Date:            local.Format("2006-01-02"),
+		DayLength:       dayLength.Seconds(),
+		DayLengthChange: (dayLength - yesterdayLength).Seconds(),
+	}
+	if status != nil {
+		summary.WriteErrors = &writeErrors
+		summary.VerifyError = status.VerifyError
+		summary.LastWrite = &status.LastWrite
+		if writeErrors > 0 {
+			summary.LastError = status.Error
+		}
+	}
+	if !events.Sunrise.IsZero() {
+		sunrise := events.Sunrise.In(loc)
+		summary.Sunrise = &sunrise
+	}
+	if !events.Sunset.IsZero() {
+		sunset := events.Sunset.In(loc)
+		summary.Sunset = &sunset
+	}
+	return summary
+}
+
+// Message formats the digest as text for chat and email
+func (s DigestSummary) Message() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Daylight digest for %s: ", s.Date)
+	if s.Sunrise != nil && s.Sunset != nil {
+		fmt.Fprintf(&b, "sunrise %s, sunset %s, ", s.Sunrise.Format("15:04"), s.Sunset.Format("15:04"))
+	} else {
+		b.WriteString("no sunrise or sunset, ")
+	}
+	change := time.Duration(s.DayLengthChange * float64(time.Second)).Round(time.Second)
+	sign := "+"
+	if change < 0 {
+		sign = ""
+	}
+	fmt.Fprintf(&b, "day length %s (%s%s versus yesterday).",
+		config.FormatDuration(time.Duration(s.DayLength*float64(time.Second)).Round(time.Minute)), sign, change)
+	if s.WriteErrors != nil {
+		fmt.Fprintf(&b, " %d write errors in the last 24h", *s.WriteErrors)
+		if s.LastError != "" {
+			fmt.Fprintf(&b, ", last: %s", s.LastError)
+		}
+		b.WriteString(".")
+	}
+	if s.VerifyError != "" {
+		fmt.Fprintf(&b, " Read-back verification failing: %s.", s.VerifyError)
+	}
+	return b.String()
+}
+
+// RunDigest sends the digest every day at the configured time until ctx is
+// done; tracker is nil when this process does not write
+func RunDigest(ctx context.Context, cfg config.Configuration, tracker *output.StatusTracker) {
+	loc, _ := cfg.Digest.Location()
+	errors := output.WriteErrors.Value()
+	for {
+		next := cfg.Digest.Next(time.Now(), loc)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+
+		ctx := config.WithRequestID(ctx, config.NewRequestID())
+		total := output.WriteErrors.Value()
+		var status *output.Status
+		if tracker != nil {
+			snapshot := tracker.Snapshot()
+			status = &snapshot
+		}
+		summary := SummarizeDigest(cfg.Digest, cfg, next, loc, total-errors, status)
+		errors = total
+		message := summary.Message()
+		config.Logger(ctx).WithField("op", "RunDigest").Info(message)
+		output.Notify(ctx, cfg.Notifiers, cfg.Digest.Notify, config.Notification{
+			Time:      next,
+			Rule:      "digest",
+			Message:   message,
+			RequestID: config.RequestID(ctx),
+			Data:      summary,
+		})
+	}
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DisplaySummary is what the display image shows at a moment in time
+type DisplaySummary struct {
+	Time      time.Time
+	Daylight  bool
+	Sunrise   time.Time
+	Sunset    time.Time
+	DayLength time.Duration
+	Yesterday time.Duration
+	// Trend holds the day length of TrendDays days either side of today,
+	// with today at index Today
+	Trend []time.Duration
+	Today int
+}
+
+// displayDayLength returns the day length of the local day of d
+func displayDayLength(cfg config.Configuration, d time.Time) time.Duration {
+	events := daylight.DayEvents(cfg.DaylightOptions(), d.Year(), d.Month(), d.Day())
+	return daylight.DayLength(cfg.Latitude, cfg.Longitude, events, d.Year(), d.Month(), d.Day())
+}
+
+// SummarizeDisplay collects the display contents for now
+func SummarizeDisplay(cfg config.Configuration, now time.Time) (DisplaySummary, error) {
+	display := config.DisplayDefaults(cfg.Display)
+	loc := time.Local
+	if display.Timezone != "" {
+		var err error
+		loc, err = time.LoadLocation(display.Timezone)
+		if err != nil {
+			return DisplaySummary{}, fmt.Errorf("invalid display timezone %s, %s", display.Timezone, err)
+		}
+	}
+	now = now.In(loc)
+
+	summary := DisplaySummary{Time: now, Today: display.TrendDays}
+	summary.Sunrise, summary.Sunset = daylight.SunriseSunset(cfg.DaylightOptions(), now.Year(), now.Month(), now.Day())
+	above := daylight.SunUp(daylight.CalculateSolarPosition(cfg.Latitude, cfg.Longitude, now).Elevation, cfg.Altitude)
+	summary.Daylight, _ = daylight.Daylight(summary.Sunrise, summary.Sunset, above, now, 0)
+	summary.DayLength = displayDayLength(cfg, now)
+	summary.Yesterday = displayDayLength(cfg, now.AddDate(0, 0, -1))
+	for i := -display.TrendDays; i <= display.TrendDays; i++ {
+		summary.Trend = append(summary.Trend, displayDayLength(cfg, now.AddDate(0, 0, i)))
+	}
+	return summary, nil
+}
+
+// lines returns the text rows of the display, the first one shown large
+func (s DisplaySummary) lines() []string {
+	state := "Dark"
+	if s.Daylight {
+		state = "Daylight"
+	}
+	clock := func(t time.Time) string {
+		if t.IsZero() {
+			return "--:--"
+		}
+		return t.In(s.Time.Location()).Format("15:04")
+	}
+	change := (s.DayLength - s.Yesterday).Round(time.Second)
+	return []string{
+		state,
+		fmt.Sprintf("as of %s", s.Time.Format("Mon 2 Jan 15:04")),
+		fmt.Sprintf("Sunrise %s  Sunset %s", clock(s.Sunrise), clock(s.Sunset)),
+		fmt.Sprintf("Day length %s (%s)", formatDayLength(s.DayLength), formatChange(change)),
+	}
+}
+
+func formatDayLength(d time.Duration) string {
+	d = d.Round(time.Minute)
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// formatChange formats the change in day length since yesterday with a sign
+func formatChange(d time.Duration) string {
+	if d >= 0 {
+		return "+" + d.String()
+	}
+	return d.String()
+}
+
+// sparkline maps the trend onto points within the given box
+func (s DisplaySummary) sparkline(x, y, width, height int) []image.Point {
+	points := make([]image.Point, len(s.Trend))
+	lowest, highest := s.Trend[0], s.Trend[0]
+	for _, d := range s.Trend {
+		lowest = min(lowest, d)
+		highest = max(highest, d)
+	}
+	span := highest - lowest
+	for i, d := range s.Trend {
+		px := x
+		if len(s.Trend) > 1 {
+			px = x + i*(width-1)/(len(s.Trend)-1)
+		}
+		py := y + height/2
+		if span > 0 {
+			py = y + height - 1 - int(int64(height-1)*int64(d-lowest)/int64(span))
+		}
+		points[i] = image.Point{px, py}
+	}
+	return points
+}
+
+// RenderDisplay writes the summary image in the configured size as png or svg
+func RenderDisplay(w io.Writer, summary DisplaySummary, display config.Display) error {
+	display = config.DisplayDefaults(display)
+	switch display.Format {
+	case "png":
+		return renderDisplayPNG(w, summary, display.Width, display.Height)
+	case "svg":
+		return renderDisplaySVG(w, summary, display.Width, display.Height)
+	}
+	return fmt.Errorf("unknown display format %q, expected png or svg", display.Format)
+}
+
+// displayLayout returns the text scale, margin and line height used by both
+// renderers so that the two formats look alike; text is sized to fit about 32
+// characters per line
+func displayLayout(width, height int) (scale, margin, lineHeight int) {
+	scale = max(1, min(height/160, width/(32*7)))
+	margin = 8 * scale
+	lineHeight = 16 * scale
+	return scale, margin, lineHeight
+}
+
+func renderDisplayPNG(w io.Writer, summary DisplaySummary, width, height int) error {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	scale, margin, lineHeight := displayLayout(width, height)
+	y := margin
+	for i, line := range summary.lines() {
+		lineScale := scale
+		if i == 0 {
+			lineScale = 2 * scale
+		}
+		drawText(img, margin, y, line, lineScale)
+		y += lineHeight * lineScale / scale
+	}
+
+	points := summary.sparkline(margin, y+margin, width-2*margin, height-y-2*margin)
+	for i := 1; i < len(points); i++ {
+		drawLine(img, points[i-1], points[i], max(1, scale/2))
+	}
+	if summary.Today < len(points) {
+		today := points[summary.Today]
+		draw.Draw(img, image.Rect(today.X-2*scale, today.Y-2*scale, today.X+2*scale, today.Y+2*scale), image.Black, image.Point{}, draw.Src)
+	}
+
+	return png.Encode(w, img)
+}
+
+// drawText draws s with its top left corner at x, y using the built in bitmap
+// font enlarged by scale, which stays crisp on e-ink panels
+func drawText(img *image.Gray, x, y int, s string, scale int) {
+	face := basicfont.Face7x13
+	mask := image.NewAlpha(image.Rect(0, 0, font.MeasureString(face, s).Ceil(), face.Height))
+	drawer := font.Drawer{Dst: mask, Src: image.Opaque, Face: face, Dot: fixed.P(0, face.Ascent)}
+	drawer.DrawString(s)
+	for my := 0; my < mask.Bounds().Dy(); my++ {
+		for mx := 0; mx < mask.Bounds().Dx(); mx++ {
+			if mask.AlphaAt(mx, my).A < 128 {
+				continue
+			}
+			rect := image.Rect(x+mx*scale, y+my*scale, x+(mx+1)*scale, y+(my+1)*scale)
+			draw.Draw(img, rect, image.Black, image.Point{}, draw.Src)
+		}
+	}
+}
+
+// drawLine draws a line of the given thickness between two points
+func drawLine(img *image.Gray, from, to image.Point, thickness int) {
+	steps := max(abs(to.X-from.X), abs(to.Y-from.Y), 1)
+	for i := 0; i <= steps; i++ {
+		x := from.X + (to.X-from.X)*i/steps
+		y := from.Y + (to.Y-from.Y)*i/steps
+		for dx := 0; dx < thickness; dx++ {
+			for dy := 0; dy < thickness; dy++ {
+				img.SetGray(x+dx, y+dy, color.Gray{})
+			}
+		}
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func renderDisplaySVG(w io.Writer, summary DisplaySummary, width, height int) error {
+	scale, margin, lineHeight := displayLayout(width, height)
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", width, height, width, height)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="white"/>`+"\n", width, height)
+
+	y := margin
+	for i, line := range summary.lines() {
+		size := lineHeight
+		if i == 0 {
+			size = 2 * lineHeight
+		}
+		var text bytes.Buffer
+		xml.EscapeText(&text, []byte(line))
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-family="monospace" font-size="%d" dominant-baseline="hanging" fill="black">%s</text>`+"\n",
+			margin, y, size*13/16, text.String())
+		y += size
+	}
+
+	points := summary.sparkline(margin, y+margin, width-2*margin, height-y-2*margin)
+	coordinates := make([]string, len(points))
+	for i, p := range points {
+		coordinates[i] = fmt.Sprintf("%d,%d", p.X, p.Y)
+	}
+	fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="black" stroke-width="%d"/>`+"\n", strings.Join(coordinates, " "), max(1, scale/2))
+	if summary.Today < len(points) {
+		today := points[summary.Today]
+		fmt.Fprintf(&b, `<circle cx="%d" cy="%d" r="%d" fill="black"/>`+"\n", today.X, today.Y, 2*scale)
+	}
+	b.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// displayContentType returns the MIME type of a display format
+func displayContentType(format string) string {
+	if format == "svg" {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// DisplayHandler renders the display image on request; the format query
+// parameter overrides the configured format
+func DisplayHandler(cfg config.Configuration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		display := config.DisplayDefaults(cfg.Display)
+		if format := r.URL.Query().Get("format"); format != "" {
+			if format != "png" && format != "svg" {
+				http.Error(w, "format must be png or svg", http.StatusBadRequest)
+				return
+			}
+			display.Format = format
+		}
+
+		summary, err := SummarizeDisplay(cfg, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var buf bytes.Buffer
+		err = RenderDisplay(&buf, summary, display)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", displayContentType(display.Format))
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write(buf.Bytes())
+	})
+}
+
+// WriteDisplayFile renders the display image for now and atomically replaces
+// path with it, so a dashboard never fetches a partially written image
+func WriteDisplayFile(cfg config.Configuration, path string, now time.Time) error {
+	summary, err := SummarizeDisplay(cfg, now)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	err = RenderDisplay(&buf, summary, cfg.Display)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("unable to create display file, %s", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to write display file %s, %s", tmp.Name(), err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("unable to write display file %s, %s", tmp.Name(), err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("unable to replace display file %s, %s", path, err)
+	}
+	return nil
+}
+
+// RunDisplayRenderer rewrites the configured display file every Interval
+// seconds until ctx is done
+func RunDisplayRenderer(ctx context.Context, cfg config.Configuration) {
+	ticker := time.NewTicker(time.Duration(cfg.Display.Interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		err := WriteDisplayFile(cfg, cfg.Display.Output, time.Now())
+		if err != nil {
+			log.WithFields(log.Fields{
+				"op":    "RunDisplayRenderer",
+				"error": err,
+			}).Error("failed to render display")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDisplay implements the display subcommand
+func RunDisplay(cfg *config.Configuration, args []string) error {
+	flags := flag.NewFlagSet("display", flag.ExitOnError)
+	at := flags.String("at", "", "RFC3339 time to render, defaults to now")
+	format := flags.String("format", "", "image format, png or svg, overriding display.format")
+	output := flags.String("output", "", "path to write the image to, - for stdout, defaults to display.output")
+	flags.Parse(args)
+
+	now := time.Now()
+	if *at != "" {
+		var err error
+		now, err = time.Parse(time.RFC3339, *at)
+		if err != nil {
+			return fmt.Errorf("invalid -at time %s, %s", *at, err)
+		}
+	}
+	if *format != "" {
+		cfg.Display.Format = *format
+	}
+	path := *output
+	if path == "" {
+		path = cfg.Display.Output
+	}
+	if path == "" {
+		return fmt.Errorf("-output is required when display.output is not configured")
+	}
+
+	if path == "-" {
+		summary, err := SummarizeDisplay(*cfg, now)
+		if err != nil {
+			return err
+		}
+		return RenderDisplay(os.Stdout, summary, cfg.Display)
+	}
+	return WriteDisplayFile(*cfg, path, now)
+}
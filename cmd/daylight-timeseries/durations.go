@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MigrateDurations rewrites bare integer durations in a YAML configuration
+// to duration strings, keeping comments, and returns the rewritten keys
+func MigrateDurations(root *yaml.Node) []string {
+	var migrated []string
+	for _, key := range config.LegacyDurationKeys() {
+		node := findYAMLKey(root, strings.Split(key, "."))
+		if node == nil || node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+			continue
+		}
+		value, err := strconv.ParseInt(node.Value, 0, 64)
+		if err != nil {
+			continue
+		}
+		node.Value = config.FormatDuration(time.Duration(value) * config.LegacyDurations[key])
+		node.Tag = "!!str"
+		node.Style = 0
+		migrated = append(migrated, key)
+	}
+	return migrated
+}
+
+// findYAMLKey returns the value node at path, matching keys case
+// insensitively as viper does
+func findYAMLKey(node *yaml.Node, path []string) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if len(path) == 0 {
+		return node
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if strings.EqualFold(node.Content[i].Value, path[0]) {
+			return findYAMLKey(node.Content[i+1], path[1:])
+		}
+	}
+	return nil
+}
+
+// RunMigrateConfig implements the migrate-config subcommand
+func RunMigrateConfig(cfg *config.Configuration, args []string) error {
+	flags := flag.NewFlagSet("migrate-config", flag.ExitOnError)
+	path := flags.String("file", flag.Lookup("config").Value.String(), "configuration file to migrate")
+	output := flags.String("output", "-", "path to write the migrated configuration to, - for stdout")
+	flags.Parse(args)
+
+	data, err := os.ReadFile(*path)
+	if err != nil {
+		return fmt.Errorf("unable to read config file %s, %s", *path, err)
+	}
+	var root yaml.Node
+	err = yaml.Unmarshal(data, &root)
+	if err != nil {
+		return fmt.Errorf("unable to parse config file %s, %s", *path, err)
+	}
+
+	migrated := MigrateDurations(&root)
+	for _, key := range migrated {
+		log.WithFields(log.Fields{
+			"op":  "main.RunMigrateConfig",
+			"key": key,
+		}).Info("rewrote bare integer duration")
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	err = encoder.Encode(&root)
+	if err != nil {
+		return fmt.Errorf("unable to encode config, %s", err)
+	}
+	encoder.Close()
+
+	if *output == "-" {
+		_, err = os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	err = os.WriteFile(*output, buf.Bytes(), 0644)
+	if err != nil {
+		return fmt.Errorf("unable to write config file %s, %s", *output, err)
+	}
+	return nil
+}
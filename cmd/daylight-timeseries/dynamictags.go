@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// DynamicTagSource holds the latest dynamic tags. A failed refresh keeps the
+// previous tags, so points stay in their series while the source is down.
+type DynamicTagSource struct {
+	config config.DynamicTags
+	mu     sync.RWMutex
+	tags   map[string]string
+}
+
+// NewDynamicTagSource fetches the tags once; a failure is logged and leaves
+// the tags empty until a refresh succeeds
+func NewDynamicTagSource(ctx context.Context, cfg config.DynamicTags) *DynamicTagSource {
+	s := &DynamicTagSource{config: cfg}
+	s.refresh(ctx)
+	return s
+}
+
+func (s *DynamicTagSource) refresh(ctx context.Context) {
+	tags, err := s.config.Fetch(ctx)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"op":    "DynamicTagSource.refresh",
+			"error": err,
+		}).Error("failed to refresh dynamic tags")
+		return
+	}
+	s.mu.Lock()
+	s.tags = tags
+	s.mu.Unlock()
+}
+
+// Run refreshes the tags every RefreshInterval until ctx is done
+func (s *DynamicTagSource) Run(ctx context.Context) {
+	if s.config.RefreshInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.config.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		s.refresh(ctx)
+	}
+}
+
+// Apply returns the sample with the dynamic tags added to a copy of its tags
+func (s *DynamicTagSource) Apply(sample daylight.Sample) daylight.Sample {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.tags) == 0 {
+		return sample
+	}
+	tags := make(map[string]string, len(sample.Tags)+len(s.tags))
+	for key, value := range s.tags {
+		tags[key] = value
+	}
+	for key, value := range sample.Tags {
+		tags[key] = value
+	}
+	sample.Tags = tags
+	return sample
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"io"
+	"os"
+	"time"
+)
+
+// RunEphemeris implements the ephemeris subcommand
+func RunEphemeris(cfg *config.Configuration, args []string) error {
+	flags := flag.NewFlagSet("ephemeris", flag.ExitOnError)
+	from := flags.String("from", fmt.Sprintf("%d-01-01", time.Now().Year()), "first date of the ephemeris")
+	years := flags.Int("years", 1, "number of years to generate")
+	output := flags.String("output", "-", "path to write the ephemeris to, - for stdout")
+	flags.Parse(args)
+
+	start, err := time.Parse("2006-01-02", *from)
+	if err != nil {
+		return fmt.Errorf("invalid -from date %s, %s", *from, err)
+	}
+	if *years < 1 {
+		return fmt.Errorf("-years must be at least 1")
+	}
+	days := int(start.AddDate(*years, 0, 0).Sub(start).Hours() / 24)
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("unable to create ephemeris file %s, %s", *output, err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	return daylight.WriteEphemeris(w, cfg.Latitude, cfg.Longitude, cfg.Altitude, start, days)
+}
+
+// RunLookup implements the lookup subcommand, a minimal reader that needs
+// only an ephemeris file
+func RunLookup(_ *config.Configuration, args []string) error {
+	flags := flag.NewFlagSet("lookup", flag.ExitOnError)
+	file := flags.String("file", "ephemeris.csv", "path to the ephemeris file")
+	at := flags.String("at", "", "RFC3339 time to look up; defaults to now")
+	offset := flags.Int("offset", 0, "minutes to offset daylight by, as with timeOffset")
+	flags.Parse(args)
+
+	t := time.Now()
+	if *at != "" {
+		var err error
+		t, err = time.Parse(time.RFC3339, *at)
+		if err != nil {
+			return fmt.Errorf("invalid -at time %s, %s", *at, err)
+		}
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		return fmt.Errorf("unable to open ephemeris file %s, %s", *file, err)
+	}
+	defer f.Close()
+
+	var latitude, longitude, altitude float64
+	var located bool
+	var events *daylight.SunEvents
+	date := t.Format("2006-01-02")
+	err = daylight.ScanEphemeris(f, func(comment string) {
+		n, _ := fmt.Sscanf(comment, "daylight-timeseries ephemeris latitude=%f longitude=%f altitude=%f", &latitude, &longitude, &altitude)
+		located = n >= 2
+	}, func(d string, e daylight.SunEvents) bool {
+		if d == date {
+			events = &e
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if events == nil {
+		return fmt.Errorf("date %s not in ephemeris", date)
+	}
+	// On days without sunrise and sunset the location of the file tells
+	// polar day from polar night
+	above := located && daylight.SunUp(daylight.CalculateSolarPosition(latitude, longitude, t).Elevation, altitude)
+	daylight, daylightOffset := daylight.Daylight(events.Sunrise, events.Sunset, above, t, time.Duration(*offset)*time.Minute)
+	fmt.Printf("daylight=%t daylight_offset=%t sunrise=%s sunset=%s\n",
+		daylight, daylightOffset, lookupTime(events.Sunrise, t.Location()), lookupTime(events.Sunset, t.Location()))
+	return nil
+}
+
+func lookupTime(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.In(loc).Format(time.RFC3339)
+}
@@ -0,0 +1,205 @@
+//go:build !slim || grpc
+
+package main
+
+import (
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylightpb"
+	"github.com/iwvelando/daylight-timeseries/pkg/output"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// GRPCBuiltIn reports whether the gRPC server is built in
+const GRPCBuiltIn = true
+
+// GRPCServer is the embedded gRPC server pushing daylight events to
+// subscribers of the DaylightService
+type GRPCServer struct {
+	daylightpb.UnimplementedDaylightServiceServer
+	server *grpc.Server
+	config config.Configuration
+}
+
+func NewGRPCServer(cfg config.Configuration) (*GRPCServer, error) {
+	var options []grpc.ServerOption
+	if cfg.GRPC.CertFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(cfg.GRPC.CertFile, cfg.GRPC.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load gRPC certificate, %s", err)
+		}
+		options = append(options, grpc.Creds(creds))
+	}
+	if cfg.GRPC.Auth.Enabled() {
+		options = append(options, grpc.StreamInterceptor(grpcAuthInterceptor(cfg.GRPC.Auth)))
+	}
+	s := &GRPCServer{
+		server: grpc.NewServer(options...),
+		config: cfg,
+	}
+	daylightpb.RegisterDaylightServiceServer(s.server, s)
+	return s, nil
+}
+
+// grpcAuthInterceptor only lets streams with valid credentials in their
+// authorization metadata through, checked as HTTP requests would be
+func grpcAuthInterceptor(auth config.HTTPAuth) grpc.StreamServerInterceptor {
+	authenticate := auth.Authenticator()
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		r, err := http.NewRequestWithContext(stream.Context(), http.MethodPost, info.FullMethod, nil)
+		if err != nil {
+			return status.Error(codes.Internal, "unable to authenticate request")
+		}
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		for _, value := range md.Get("authorization") {
+			r.Header.Add("Authorization", value)
+		}
+		ok, err := authenticate(r)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"op":    "GRPCServer.authenticate",
+				"error": err,
+			}).Error("failed to authenticate request")
+			return status.Error(codes.Unavailable, "unable to authenticate request")
+		}
+		if !ok {
+			return status.Error(codes.Unauthenticated, "unauthorized")
+		}
+		return handler(srv, stream)
+	}
+}
+
+// Start listens on the configured address, network and interface and serves
+// subscribers in the background
+func (s *GRPCServer) Start() error {
+	listener, err := output.Listen(s.config.GRPC.Network, s.config.GRPC.Interface, s.config.GRPC.Address)
+	if err != nil {
+		return err
+	}
+	go func() {
+		err := s.server.Serve(listener)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"op":    "GRPCServer.Start",
+				"error": err,
+			}).Error("gRPC server stopped")
+		}
+	}()
+	return nil
+}
+
+// Close stops the server, ending the streams of all subscribers, which would
+// otherwise never finish
+func (s *GRPCServer) Close() {
+	s.server.Stop()
+}
+
+// Subscribe sends the current state, then the transitions selected by the
+// request as they happen and a notice ahead of each selected sun event
+func (s *GRPCServer) Subscribe(req *daylightpb.SubscribeRequest, stream daylightpb.DaylightService_SubscribeServer) error {
+	ctx := stream.Context()
+	notice := s.config.GRPC.Notice
+	if req.Notice != nil {
+		if req.Notice.CheckValid() != nil || req.Notice.AsDuration() < 0 {
+			return status.Error(codes.InvalidArgument, "notice must be a duration that is not negative")
+		}
+		notice = req.Notice.AsDuration()
+	}
+	// Notices only follow sun events; selecting nothing but other fields
+	// leaves them out
+	var sunEvents []string
+	for _, event := range req.Events {
+		if slices.Contains(daylight.CountdownEvents, event) {
+			sunEvents = append(sunEvents, event)
+		}
+	}
+	notices := len(req.Events) == 0 || len(sunEvents) > 0
+
+	at := daylight.CalculateDaylightAt(s.config.DaylightOptions(), time.Now())
+	err := stream.Send(&daylightpb.DaylightEvent{Event: &daylightpb.DaylightEvent_State{State: stateMessage(at)}})
+	if err != nil {
+		return err
+	}
+	transitions := daylight.Subscribe(ctx, s.config.DaylightOptions(), req.Events...)
+
+	// upcoming fires notice before the event named next, or a day later when
+	// none happens soon, to look again
+	var next string
+	var nextTime time.Time
+	var upcoming <-chan time.Time
+	schedule := func(after time.Time) {
+		if !notices {
+			return
+		}
+		next, nextTime = daylight.NextEvent(s.config.DaylightOptions(), after, sunEvents...)
+		if next == "" {
+			upcoming = time.After(24 * time.Hour)
+			return
+		}
+		upcoming = time.After(time.Until(nextTime.Add(-notice)))
+	}
+	schedule(time.Now())
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case transition, ok := <-transitions:
+			if !ok {
+				return nil
+			}
+			err := stream.Send(&daylightpb.DaylightEvent{Event: &daylightpb.DaylightEvent_Transition{Transition: &daylightpb.Transition{
+				Time:  timestamppb.New(transition.Time),
+				Event: transition.Event,
+				Field: transition.Field,
+				Value: transition.Value,
+			}}})
+			if err != nil {
+				return err
+			}
+		case <-upcoming:
+			if next == "" {
+				schedule(time.Now())
+				continue
+			}
+			err := stream.Send(&daylightpb.DaylightEvent{Event: &daylightpb.DaylightEvent_Upcoming{Upcoming: &daylightpb.Upcoming{
+				Event: next,
+				Time:  timestamppb.New(nextTime),
+				In:    durationpb.New(time.Until(nextTime).Round(time.Second)),
+			}}})
+			if err != nil {
+				return err
+			}
+			schedule(nextTime)
+		}
+	}
+}
+
+// stateMessage converts a daylight state to its protocol buffer message
+func stateMessage(at daylight.DaylightAt) *daylightpb.State {
+	state := &daylightpb.State{
+		Time:           timestamppb.New(at.Time),
+		Daylight:       at.Daylight,
+		DaylightOffset: at.DaylightOffset,
+		Phase:          at.Phase,
+		Elevation:      at.Elevation,
+	}
+	if at.Sunrise != nil {
+		state.Sunrise = timestamppb.New(*at.Sunrise)
+	}
+	if at.Sunset != nil {
+		state.Sunset = timestamppb.New(*at.Sunset)
+	}
+	return state
+}
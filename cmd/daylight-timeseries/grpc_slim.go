@@ -0,0 +1,26 @@
+//go:build slim && !grpc
+
+package main
+
+import (
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+)
+
+// GRPCBuiltIn reports whether the gRPC server is built in
+const GRPCBuiltIn = false
+
+// GRPCServer stands in for the gRPC server in builds without it
+type GRPCServer struct{}
+
+// NewGRPCServer fails, the gRPC server is not built in
+func NewGRPCServer(cfg config.Configuration) (*GRPCServer, error) {
+	return nil, errGRPCNotBuiltIn
+}
+
+// Start fails, the gRPC server is not built in
+func (s *GRPCServer) Start() error {
+	return errGRPCNotBuiltIn
+}
+
+// Close does nothing
+func (s *GRPCServer) Close() {}
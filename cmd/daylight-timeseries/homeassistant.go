@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// WatchHomeAssistant polls the zone every RefreshInterval until ctx is done and
+// sends its location on updates whenever it moves away from current
+func WatchHomeAssistant(ctx context.Context, cfg config.HomeAssistant, current config.Location, updates chan<- config.Location) {
+	if cfg.RefreshInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(cfg.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		location, err := cfg.Location(ctx)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"op":    "WatchHomeAssistant",
+				"error": err,
+			}).Error("failed to refresh location from Home Assistant")
+			continue
+		}
+		if location == current {
+			continue
+		}
+		current = location
+		select {
+		case updates <- location:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
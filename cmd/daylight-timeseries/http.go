@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/output"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"time"
+)
+
+// HTTPServer is the embedded HTTP API; endpoints are registered on Mux before
+// Start is called
+type HTTPServer struct {
+	Mux    *http.ServeMux
+	server *http.Server
+	config config.HTTP
+	cancel context.CancelFunc
+}
+
+func NewHTTPServer(cfg config.HTTP) *HTTPServer {
+	mux := http.NewServeMux()
+	// Long-lived requests such as streams watch the base context so that they
+	// end when the server is closed
+	ctx, cancel := context.WithCancel(context.Background())
+	return &HTTPServer{
+		Mux: mux,
+		server: &http.Server{
+			Addr:              cfg.Address,
+			Handler:           RequestIDs(mux),
+			ReadHeaderTimeout: 10 * time.Second,
+			BaseContext: func(net.Listener) context.Context {
+				return ctx
+			},
+		},
+		config: cfg,
+		cancel: cancel,
+	}
+}
+
+// Start listens on the configured address, network and interface and serves
+// requests in the background
+func (s *HTTPServer) Start() error {
+	listener, err := output.Listen(s.config.Network, s.config.Interface, s.server.Addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		err := s.server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.WithFields(log.Fields{
+				"op":    "HTTPServer.Start",
+				"error": err,
+			}).Error("HTTP server stopped")
+		}
+	}()
+	return nil
+}
+
+// Close gracefully stops the server, waiting briefly for requests to finish
+func (s *HTTPServer) Close() error {
+	s.cancel()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.server.Shutdown(ctx)
+}
+
+// RequestIDs gives every HTTP request a request ID, taken from its
+// X-Request-ID header when the client or a proxy set one, and echoes it in
+// the response
+func RequestIDs(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" || len(id) > 128 {
+			id = config.NewRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+		handler.ServeHTTP(w, r.WithContext(config.WithRequestID(r.Context(), id)))
+	})
+}
+
+// MetricsHandler serves the expvar metrics as JSON like expvar.Handler, less
+// cmdline, whose flags may carry credentials, and memstats
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprint(w, "{\n")
+		first := true
+		expvar.Do(func(kv expvar.KeyValue) {
+			if kv.Key == "cmdline" || kv.Key == "memstats" {
+				return
+			}
+			if !first {
+				fmt.Fprint(w, ",\n")
+			}
+			first = false
+			fmt.Fprintf(w, "%q: %s", kv.Key, kv.Value)
+		})
+		fmt.Fprint(w, "\n}\n")
+	})
+}
@@ -0,0 +1,23 @@
+//go:build slim && !influxdb
+
+package main
+
+import (
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/output"
+)
+
+// The commands writing to or querying InfluxDB fail in builds without the
+// influxdb output
+
+func RunBackfill(cfg *config.Configuration, args []string) error {
+	return output.NotBuiltIn("influxdb")
+}
+
+func RunMigrate(cfg *config.Configuration, args []string) error {
+	return output.NotBuiltIn("influxdb")
+}
+
+func RunBootstrap(cfg *config.Configuration, args []string) error {
+	return output.NotBuiltIn("influxdb")
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	log "github.com/sirupsen/logrus"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// minNoonReadings is how many readings on either side of solar noon an
+// estimate needs
+const minNoonReadings = 10
+
+// Reading is a brightness read from the light sensor at a time of the local
+// clock
+type Reading struct {
+	Time  time.Time
+	Value float64
+}
+
+// NoonPeak returns how far the brightness peak of readings lies from noon,
+// fitting a parabola to those within window of it. It fails when there are
+// too few readings on either side or they do not peak within the window,
+// e.g. on overcast days.
+func NoonPeak(readings []Reading, noon time.Time, window time.Duration) (time.Duration, bool) {
+	// Least squares fit of value = a + b*x + c*x^2 with x in hours from noon
+	var n, sx, sx2, sx3, sx4, sy, sxy, sx2y float64
+	before, after := 0, 0
+	for _, r := range readings {
+		offset := r.Time.Sub(noon)
+		if offset < -window || offset > window {
+			continue
+		}
+		if offset < 0 {
+			before++
+		} else {
+			after++
+		}
+		x := offset.Hours()
+		n++
+		sx += x
+		sx2 += x * x
+		sx3 += x * x * x
+		sx4 += x * x * x * x
+		sy += r.Value
+		sxy += x * r.Value
+		sx2y += x * x * r.Value
+	}
+	if before < minNoonReadings || after < minNoonReadings {
+		return 0, false
+	}
+
+	// Solve the normal equations by Cramer's rule
+	det := func(m [3][3]float64) float64 {
+		return m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+			m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+			m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+	}
+	m := [3][3]float64{{n, sx, sx2}, {sx, sx2, sx3}, {sx2, sx3, sx4}}
+	d := det(m)
+	if math.Abs(d) < 1e-12 {
+		return 0, false
+	}
+	mb := [3][3]float64{{n, sy, sx2}, {sx, sxy, sx3}, {sx2, sx2y, sx4}}
+	mc := [3][3]float64{{n, sx, sy}, {sx, sx2, sxy}, {sx2, sx3, sx2y}}
+	b, c := det(mb)/d, det(mc)/d
+	if c >= 0 {
+		return 0, false
+	}
+	peak := time.Duration(-b / (2 * c) * float64(time.Hour))
+	if peak < -window || peak > window {
+		return 0, false
+	}
+	return peak, true
+}
+
+// ClockDrift estimates the offset of the local clock from the light sensor:
+// once the window around a day's solar noon has passed, the peak of that
+// window's readings gives the day's estimate, and the offset reported is
+// the median of the estimates of the last Days days. Positive offsets mean
+// the clock is ahead.
+type ClockDrift struct {
+	config    config.LightSensor
+	longitude float64
+
+	mu       sync.Mutex
+	readings []Reading
+	// noon is the solar noon whose window is being collected
+	noon      time.Time
+	estimates []time.Duration
+}
+
+func NewClockDrift(cfg config.LightSensor, longitude float64) *ClockDrift {
+	return &ClockDrift{config: cfg.WithDefaults(), longitude: longitude}
+}
+
+// nextNoon returns the solar noon whose window ends after t
+func (d *ClockDrift) nextNoon(t time.Time) time.Time {
+	date := daylight.SolarDate(t, d.longitude)
+	noon := daylight.SolarNoon(d.longitude, date.Year(), date.Month(), date.Day())
+	if t.After(noon.Add(d.config.Window)) {
+		date = date.AddDate(0, 0, 1)
+		noon = daylight.SolarNoon(d.longitude, date.Year(), date.Month(), date.Day())
+	}
+	return noon
+}
+
+// Add records a reading, estimating the offset of a day once its window has
+// passed
+func (d *ClockDrift) Add(reading Reading) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.noon.IsZero() {
+		d.noon = d.nextNoon(reading.Time)
+	}
+	if reading.Time.After(d.noon.Add(d.config.Window)) {
+		peak, ok := NoonPeak(d.readings, d.noon, d.config.Window)
+		if ok {
+			d.estimates = append(d.estimates, peak)
+			if len(d.estimates) > d.config.Days {
+				d.estimates = d.estimates[len(d.estimates)-d.config.Days:]
+			}
+		}
+		log.WithFields(log.Fields{
+			"op":        "ClockDrift.Add",
+			"noon":      d.noon.Format(time.RFC3339),
+			"readings":  len(d.readings),
+			"peak":      peak.String(),
+			"estimated": ok,
+		}).Debug("estimated the clock offset from solar noon")
+		d.readings = nil
+		d.noon = d.nextNoon(reading.Time)
+	}
+	if reading.Time.After(d.noon.Add(-d.config.Window)) {
+		d.readings = append(d.readings, reading)
+	}
+}
+
+// Offset returns the median of the daily estimates, false before the first
+func (d *ClockDrift) Offset() (time.Duration, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.estimates) == 0 {
+		return 0, false
+	}
+	sorted := append([]time.Duration(nil), d.estimates...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2, true
+	}
+	return sorted[middle], true
+}
+
+// Run reads the sensor every Interval until ctx is done; failed reads are
+// logged and skipped
+func (d *ClockDrift) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+	for {
+		value, err := d.config.Read(ctx)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"op":    "ClockDrift.Run",
+				"error": err,
+			}).Warn("failed to read the light sensor")
+		} else {
+			d.Add(Reading{Time: time.Now(), Value: value})
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
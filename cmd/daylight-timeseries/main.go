@@ -0,0 +1,438 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"github.com/iwvelando/daylight-timeseries/pkg/output"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// ShutdownTimeout is how long the poll loop may take to finish the sample at
+// hand on SIGTERM or SIGINT before the outputs are closed anyway
+const ShutdownTimeout = 10 * time.Second
+
+// ExitInterrupted is the exit code when a second signal cuts the shutdown
+// short
+const ExitInterrupted = 130
+
+func main() {
+
+	// Load the config file based on path provided via CLI or the default
+	configLocation := flag.String("config", "config.yaml", "path to configuration file, or an etcd3:// or consul:// key holding it")
+	flag.Var(&config.ConfigOverrides, "set", "override a configuration key, e.g. -set influxDB.bucket=test; may be repeated")
+	roleFlag := flag.String("role", "", "components to run, collector or server, overriding the role setting; both by default")
+	flag.StringVar(&config.LatitudeFlag, "lat", "", "latitude, overriding the configured one; with -lon the configuration file is optional")
+	flag.StringVar(&config.LongitudeFlag, "lon", "", "longitude, overriding the configured one; with -lat the configuration file is optional")
+	flag.Usage = Usage
+	flag.Parse()
+	daylight.Log = daylightLogger{}
+
+	// Without a configuration file there is nothing to write to, so just
+	// print today's times
+	if flag.NArg() == 0 && config.ConfigMissing(*configLocation) {
+		if !config.CoordinateFlagsSet() {
+			log.WithFields(log.Fields{
+				"op":     "main",
+				"config": *configLocation,
+			}).Fatal("configuration file not found; copy config.yaml.example to get started, or try daylight-timeseries -lat 40 -lon -105 print")
+		}
+		RunCommand(*configLocation, "print", nil)
+		return
+	}
+
+	// Run a one-shot subcommand if one was given, otherwise poll forever
+	if flag.NArg() > 0 {
+		RunCommand(*configLocation, flag.Arg(0), flag.Args()[1:])
+		return
+	}
+
+	cfg, err := loadConfiguration(*configLocation)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"op":    "main.LoadConfiguration",
+			"error": err,
+		}).Fatal("failed to load configuration")
+	}
+	if *roleFlag != "" {
+		cfg.Role = config.Role(*roleFlag)
+		err = cfg.Role.Validate()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"op":    "main",
+				"error": err,
+			}).Fatal("invalid -role flag")
+		}
+	}
+	role := cfg.Role
+
+	for _, name := range cfg.UnknownFields() {
+		log.WithFields(log.Fields{
+			"op":    "main",
+			"field": name,
+		}).Warn("ignoring unknown field in fields configuration")
+	}
+
+	latitude, longitude := cfg.PublicCoordinates()
+	log.WithFields(log.Fields{
+		"op":        "main",
+		"latitude":  latitude,
+		"longitude": longitude,
+		"role":      role,
+	}).Info("starting daylight polling")
+
+	// ctx is canceled on SIGTERM or SIGINT, stopping the startup backfill,
+	// the poll loop and the background workers before the outputs are
+	// flushed
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	if cfg.Geocode.Enabled {
+		cfg.ResolvePlace(ctx)
+	}
+
+	tracker := output.NewStatusTracker(cfg.Status.File, time.Now(), output.StatusMaxAge(*cfg))
+	err = tracker.Save()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"op":    "main",
+			"error": err,
+		}).Error("failed to write status file")
+	}
+
+	// The collector writes samples; without it the server only computes them
+	// for its API and notifications
+	var collector *Collector
+	if role.Collects() {
+		collector, err = StartCollector(cfg, tracker)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"op":    "main",
+				"error": err,
+			}).Fatal("failed to start collector")
+		}
+		if cfg.BackfillToday {
+			BackfillToday(ctx, *cfg, collector, time.Now())
+		}
+	}
+
+	var broadcaster *Broadcaster
+	var recent *RecentSamples
+	var ruleEngine *RuleEngine
+	var httpServer *HTTPServer
+	var grpcServer *GRPCServer
+	if role.Serves() {
+		if cfg.HTTP.Address != "" {
+			broadcaster = NewBroadcaster()
+			recent = NewRecentSamples(cfg.HTTP.Recent)
+			httpServer = NewHTTPServer(cfg.HTTP)
+			health, data := cfg.HTTP.Health, cfg.HTTP.Data
+			// Health reflects writes, so only a collector reports it
+			if role.Collects() {
+				httpServer.Mux.Handle("GET /v1/health", health.Protect(HealthHandler(*cfg, tracker)))
+			}
+			// The command line is left out of both, as it may carry
+			// credentials
+			httpServer.Mux.Handle("GET /v1/metrics", health.Protect(MetricsHandler()))
+			if cfg.HTTP.Pprof {
+				httpServer.Mux.Handle("GET /debug/pprof/", data.Protect(http.HandlerFunc(pprof.Index)))
+				httpServer.Mux.Handle("GET /debug/pprof/profile", data.Protect(http.HandlerFunc(pprof.Profile)))
+				httpServer.Mux.Handle("GET /debug/pprof/symbol", data.Protect(http.HandlerFunc(pprof.Symbol)))
+				httpServer.Mux.Handle("GET /debug/pprof/trace", data.Protect(http.HandlerFunc(pprof.Trace)))
+			}
+			httpServer.Mux.Handle("GET /v1/stream", data.Protect(broadcaster))
+			httpServer.Mux.Handle("GET /v1/recent", data.Protect(recent))
+			httpServer.Mux.Handle("GET /v1/schedule", data.Protect(ScheduleHandler(*cfg)))
+			httpServer.Mux.Handle("GET /v1/display", data.Protect(DisplayHandler(*cfg)))
+			httpServer.Mux.Handle("GET /v1/colortemp", data.Protect(ColorTemperatureHandler(*cfg)))
+			httpServer.Mux.Handle("GET /v1/daylight", data.Protect(DaylightAtHandler(*cfg)))
+			httpServer.Mux.Handle("GET /v1/now", data.Protect(NowHandler(*cfg)))
+			err = httpServer.Start()
+			if err != nil {
+				log.WithFields(log.Fields{
+					"op":    "main",
+					"error": err,
+				}).Fatal("failed to start HTTP server")
+			}
+		}
+
+		if cfg.GRPC.Address != "" {
+			grpcServer, err = NewGRPCServer(*cfg)
+			if err == nil {
+				err = grpcServer.Start()
+			}
+			if err != nil {
+				log.WithFields(log.Fields{
+					"op":    "main",
+					"error": err,
+				}).Fatal("failed to start gRPC server")
+			}
+		}
+
+		if cfg.Display.Output != "" && cfg.Display.Interval != 0 {
+			go RunDisplayRenderer(ctx, *cfg)
+		}
+
+		if cfg.Digest.Enabled() {
+			digestTracker := tracker
+			if !role.Collects() {
+				digestTracker = nil
+			}
+			go RunDigest(ctx, *cfg, digestTracker)
+		}
+
+		if len(cfg.ParsedRules()) > 0 {
+			ruleEngine = NewRuleEngine(*cfg)
+			if cfg.LowPower {
+				log.WithFields(log.Fields{
+					"op": "main",
+				}).Warn("rules are only evaluated when the low power poll loop wakes")
+			}
+		}
+	}
+
+	poller := daylight.NewPoller(cfg.DaylightOptions(), time.Now())
+
+	// SIGHUP, or a change of the file with watchConfig, reloads the
+	// configuration
+	reloads := make(chan struct{}, 1)
+	hangupCh := make(chan os.Signal, 1)
+	signal.Notify(hangupCh, syscall.SIGHUP)
+	go func() {
+		for range hangupCh {
+			select {
+			case reloads <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	if cfg.WatchConfig {
+		go WatchConfiguration(ctx, *configLocation, reloads)
+	}
+	// reload applies the configuration file again to the poll loop, keeping
+	// the running one when the file is invalid
+	reload := func() bool {
+		reloaded, err := ReloadConfiguration(*configLocation, role)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"op":    "main",
+				"error": err,
+			}).Error("failed to reload configuration, keeping the running one")
+			return false
+		}
+		for _, name := range RestartSettings(cfg, reloaded) {
+			log.WithFields(log.Fields{
+				"op":      "main",
+				"setting": name,
+			}).Warn("changes to this setting need a restart")
+		}
+		if reloaded.Geocode.Enabled {
+			reloaded.ResolvePlace(ctx)
+		}
+		cfg = reloaded
+		poller.Reconfigure(cfg.DaylightOptions())
+		if collector != nil {
+			collector.Reload(cfg, tracker)
+		}
+		ruleEngine = nil
+		if role.Serves() && len(cfg.ParsedRules()) > 0 {
+			ruleEngine = NewRuleEngine(*cfg)
+		}
+		latitude, longitude := cfg.PublicCoordinates()
+		log.WithFields(log.Fields{
+			"op":        "main",
+			"latitude":  latitude,
+			"longitude": longitude,
+		}).Info("reloaded configuration")
+		return true
+	}
+
+	locationCh := make(chan config.Location, 1)
+	if cfg.HomeAssistant.Enabled() {
+		go WatchHomeAssistant(ctx, cfg.HomeAssistant, config.Location{Latitude: cfg.Latitude, Longitude: cfg.Longitude}, locationCh)
+	}
+
+	// The loop is restarted when a sample panics; the poller keeps its state.
+	// It finishes the sample at hand when ctx is canceled and closes stopped.
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		output.Supervise(ctx, "poll", func(loop context.Context) {
+			// The first sample is taken straight away, the following ones on
+			// the wall clock boundaries of pollInterval
+			scheduled, aligned := time.Now().Round(0), false
+			for {
+
+				select {
+				case location := <-locationCh:
+					located := *cfg
+					located.Latitude, located.Longitude = location.Latitude, location.Longitude
+					if located.Geocode.Enabled {
+						located.ResolvePlace(loop)
+					}
+					poller.Reconfigure(located.DaylightOptions())
+					latitude, longitude := located.PublicCoordinates()
+					log.WithFields(log.Fields{
+						"op":        "main",
+						"latitude":  latitude,
+						"longitude": longitude,
+					}).Info("location changed in Home Assistant")
+				default:
+				}
+
+				ctx := config.WithRequestID(loop, config.NewRequestID())
+				// The grid is kept on the wall clock, which keeps advancing
+				// while the system is suspended unlike the monotonic clock, so
+				// samples missed during a suspend are handled as an overrun
+				times, next := cfg.OverrunPolicy.DueSamples(scheduled, time.Now().Round(0), cfg.PollInterval)
+				if !aligned {
+					next, aligned = daylight.NextBoundary(scheduled, cfg.PollInterval), true
+				}
+				if missed := int(next.Sub(scheduled)/cfg.PollInterval) - 1; missed > 0 {
+					config.Logger(ctx).WithFields(log.Fields{
+						"op":     "main",
+						"missed": missed,
+						"policy": cfg.OverrunPolicy,
+					}).Warn("poll loop overran its interval")
+				}
+
+				for i, t := range times {
+					// Queued samples are dropped on shutdown
+					if loop.Err() != nil {
+						return
+					}
+					// Every sample gets a request ID of its own; the first
+					// shares the one of the iteration
+					if i > 0 {
+						ctx = config.WithRequestID(loop, config.NewRequestID())
+					}
+					sample, transitions := poller.Poll(t)
+					if len(cfg.Hooks) > 0 {
+						sample = output.ApplyHooks(ctx, cfg.Hooks, sample)
+					}
+					if broadcaster != nil {
+						broadcaster.PublishSample(sample, transitions)
+						recent.Add(sample)
+					}
+					if ruleEngine != nil {
+						ruleEngine.Evaluate(ctx, sample)
+					}
+					if role.Serves() {
+						for _, transition := range transitions {
+							output.NotifyEvent(ctx, cfg.Notifiers, transition.Event, config.Notification{
+								Time:      transition.Time,
+								Rule:      transition.Event,
+								Message:   fmt.Sprintf("%s at %s", transition.Event, transition.Time.Format(time.RFC3339)),
+								RequestID: config.RequestID(ctx),
+								Data:      transition,
+							})
+						}
+					}
+					if collector != nil {
+						collector.Write(ctx, poller.Edges())
+						collector.Collect(ctx, sample, transitions, t)
+					}
+				}
+
+				scheduled = next
+				if cfg.LowPower {
+					now := time.Now()
+					change := poller.NextChange(now)
+					if collector != nil {
+						change = daylight.EarliestAfter(now, change, collector.NextChange(now))
+					}
+					if cfg.Heartbeat > 0 {
+						change = daylight.EarliestAfter(now, change, now.Add(cfg.Heartbeat))
+					}
+					scheduled = daylight.LowPowerWake(scheduled, cfg.PollInterval, change)
+					config.Logger(ctx).WithFields(log.Fields{
+						"op":   "main",
+						"wake": scheduled.Format(time.RFC3339),
+					}).Info("sleeping until the next possible change")
+				}
+				// Low-power mode sleeps through, without waking to check for
+				// a suspend
+				check := daylight.WallClockCheck
+				if cfg.LowPower {
+					check = 0
+				}
+				for !daylight.WaitUntil(loop, scheduled, reloads, check) {
+					if loop.Err() != nil {
+						return
+					}
+					// Sample straight away with the new configuration,
+					// starting the grid of a changed pollInterval over
+					if reload() {
+						scheduled, aligned = time.Now().Round(0), false
+						break
+					}
+				}
+
+			}
+		})
+	}()
+
+	<-ctx.Done()
+	stop()
+	log.WithFields(log.Fields{
+		"op": "main",
+	}).Info("caught signal, stopping")
+	// A second SIGTERM or SIGINT gives up on the flush
+	cancelCh := make(chan os.Signal, 1)
+	signal.Notify(cancelCh, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		sig := <-cancelCh
+		log.WithFields(log.Fields{
+			"op": "main",
+		}).Warn(fmt.Sprintf("caught signal %v again, exiting without flushing outputs", sig))
+		os.Exit(ExitInterrupted)
+	}()
+
+	// The exit code tells a supervisor whether every sample reached the
+	// outputs: 0 after a clean shutdown, 1 when the poll loop did not stop
+	// in time or an output failed to flush
+	code := 0
+	select {
+	case <-stopped:
+	case <-time.After(ShutdownTimeout):
+		log.WithFields(log.Fields{
+			"op":      "main",
+			"timeout": ShutdownTimeout,
+		}).Error("poll loop did not stop in time")
+		code = 1
+	}
+	if collector != nil {
+		log.WithFields(log.Fields{
+			"op": "main",
+		}).Info("flushing outputs")
+		if collector.Close() != nil {
+			code = 1
+		}
+	}
+	// os.Exit skips deferred calls, so the servers are closed here
+	if httpServer != nil {
+		httpServer.Close()
+	}
+	if grpcServer != nil {
+		grpcServer.Close()
+	}
+	if code != 0 {
+		os.Exit(code)
+	}
+
+}
+
+// daylightLogger adapts logrus to the warnings of the daylight package
+type daylightLogger struct{}
+
+func (daylightLogger) Warn(op, msg string, fields map[string]interface{}) {
+	log.WithFields(log.Fields(fields)).WithField("op", op).Warn(msg)
+}
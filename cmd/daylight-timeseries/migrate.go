@@ -0,0 +1,339 @@
+//go:build !slim || influxdb
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	influx "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"github.com/iwvelando/daylight-timeseries/pkg/output"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Migration describes how to rewrite historical points
+type Migration struct {
+	Rename        map[string]string
+	BoolToInt     map[string]bool
+	SchemaVersion string
+}
+
+// Apply rewrites the fields and schema_version tag of a point in place
+func (m Migration) Apply(tags map[string]string, fields map[string]interface{}) {
+	for name, value := range fields {
+		if b, ok := value.(bool); ok && m.BoolToInt[name] {
+			if b {
+				fields[name] = int64(1)
+			} else {
+				fields[name] = int64(0)
+			}
+		}
+	}
+	for from, to := range m.Rename {
+		if value, ok := fields[from]; ok {
+			delete(fields, from)
+			fields[to] = value
+		}
+	}
+	tags["schema_version"] = m.SchemaVersion
+}
+
+type migrationPoint struct {
+	time   time.Time
+	tags   map[string]string
+	fields map[string]interface{}
+}
+
+// matchesSchemaVersion reports whether a point with tags is selected by a source
+// schema_version; "none" selects untagged points and "" selects all
+func matchesSchemaVersion(tags map[string]string, sourceVersion string) bool {
+	version, ok := tags["schema_version"]
+	switch sourceVersion {
+	case "":
+		return true
+	case "none":
+		return !ok
+	default:
+		return version == sourceVersion
+	}
+}
+
+// ReadPoints queries the points of a measurement in [start, stop) and
+// reassembles fields that Flux returns as separate rows
+func ReadPoints(ctx context.Context, client influx.Client, cfg *config.Configuration, bucket, measurement string, start, stop time.Time) ([]*migrationPoint, error) {
+	query := fmt.Sprintf(`from(bucket: %s)
+  |> range(start: %s, stop: %s)
+  |> filter(fn: (r) => r._measurement == %s)`,
+		output.FluxString(bucket), start.UTC().Format(time.RFC3339Nano), stop.UTC().Format(time.RFC3339Nano), output.FluxString(measurement))
+	result, err := client.QueryAPI(cfg.InfluxDB.Organization).Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query points, %s", err)
+	}
+	defer result.Close()
+
+	points := map[string]*migrationPoint{}
+	var tagColumns []string
+	for result.Next() {
+		if result.TableChanged() {
+			tagColumns = tagColumns[:0]
+			for _, column := range result.TableMetadata().Columns() {
+				name := column.Name()
+				if column.IsGroup() && !strings.HasPrefix(name, "_") && name != "result" && name != "table" {
+					tagColumns = append(tagColumns, name)
+				}
+			}
+		}
+		record := result.Record()
+		tags := map[string]string{}
+		key := []string{record.Time().Format(time.RFC3339Nano)}
+		for _, name := range tagColumns {
+			if value, ok := record.ValueByKey(name).(string); ok {
+				tags[name] = value
+				key = append(key, name+"="+value)
+			}
+		}
+		sort.Strings(key[1:])
+		id := strings.Join(key, ",")
+
+		point, ok := points[id]
+		if !ok {
+			point = &migrationPoint{time: record.Time(), tags: tags, fields: map[string]interface{}{}}
+			points[id] = point
+		}
+		point.fields[record.Field()] = record.Value()
+	}
+	if result.Err() != nil {
+		return nil, fmt.Errorf("unable to read query result, %s", result.Err())
+	}
+
+	sorted := make([]*migrationPoint, 0, len(points))
+	for _, point := range points {
+		sorted = append(sorted, point)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].time.Before(sorted[j].time) })
+	return sorted, nil
+}
+
+// parseMapping parses comma separated old=new pairs
+func parseMapping(value string) (map[string]string, error) {
+	mapping := map[string]string{}
+	if value == "" {
+		return mapping, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		from, to, ok := strings.Cut(pair, "=")
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("expected old=new, got %q", pair)
+		}
+		mapping[from] = to
+	}
+	return mapping, nil
+}
+
+// RunMigrate implements the migrate subcommand
+func RunMigrate(cfg *config.Configuration, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	from := flags.String("from", "", "RFC3339 start of the time range to migrate")
+	to := flags.String("to", "", "RFC3339 end of the time range to migrate; defaults to now")
+	rename := flags.String("rename", "", "comma separated old=new field renames")
+	boolToInt := flags.String("bool-to-int", "", "comma separated boolean fields to rewrite as 0/1 integers")
+	sourceVersion := flags.String("source-version", "none", "schema_version of the points to migrate; none selects untagged points, empty selects all")
+	targetVersion := flags.String("schema-version", output.SchemaVersion, "schema_version to tag rewritten points with")
+	target := flags.String("measurement", daylight.Measurement, "measurement to write rewritten points to")
+	deleteSource := flags.Bool("delete", false, "delete the source points of each window before writing (InfluxDB v2 only)")
+	window := flags.Duration("window", 24*time.Hour, "size of the time windows migrated at once")
+	dryRun := flags.Bool("dry-run", false, "only report how many points would be rewritten")
+	flags.Parse(args)
+
+	start, err := time.Parse(time.RFC3339, *from)
+	if err != nil {
+		return fmt.Errorf("invalid -from %q, %s", *from, err)
+	}
+	stop := time.Now()
+	if *to != "" {
+		stop, err = time.Parse(time.RFC3339, *to)
+		if err != nil {
+			return fmt.Errorf("invalid -to %q, %s", *to, err)
+		}
+	}
+	renames, err := parseMapping(*rename)
+	if err != nil {
+		return fmt.Errorf("invalid -rename, %s", err)
+	}
+	migration := Migration{Rename: renames, BoolToInt: map[string]bool{}, SchemaVersion: *targetVersion}
+	for _, name := range strings.Split(*boolToInt, ",") {
+		if name != "" {
+			migration.BoolToInt[name] = true
+		}
+	}
+	if *deleteSource && cfg.InfluxDB.Bucket == "" {
+		return fmt.Errorf("-delete requires an InfluxDB v2 bucket")
+	}
+
+	bucket, err := config.InfluxWriteDestination(cfg)
+	if err != nil {
+		return err
+	}
+	client := output.NewInfluxClient(cfg, nil, "")
+	defer client.Close()
+	writeAPI := client.WriteAPIBlocking(cfg.InfluxDB.Organization, bucket)
+
+	ctx := context.Background()
+	total := 0
+	for windowStart := start; windowStart.Before(stop); windowStart = windowStart.Add(*window) {
+		windowStop := windowStart.Add(*window)
+		if windowStop.After(stop) {
+			windowStop = stop
+		}
+
+		if *deleteSource && !*dryRun {
+			resumed, err := resumeWindow(ctx, client, writeAPI, cfg, bucket, *target, windowStart, windowStop)
+			if err != nil {
+				return err
+			}
+			if resumed {
+				fmt.Printf("%s - %s: resumed an interrupted migration\n", windowStart.Format(time.RFC3339), windowStop.Format(time.RFC3339))
+				continue
+			}
+		}
+		points, err := ReadPoints(ctx, client, cfg, bucket, daylight.Measurement, windowStart, windowStop)
+		if err != nil {
+			return err
+		}
+		selected := 0
+		for _, point := range points {
+			if matchesSchemaVersion(point.tags, *sourceVersion) {
+				selected++
+			}
+		}
+		total += selected
+		fmt.Printf("%s - %s: %d points\n", windowStart.Format(time.RFC3339), windowStop.Format(time.RFC3339), selected)
+		if *dryRun || selected == 0 {
+			continue
+		}
+
+		var migrated, kept []*migrationPoint
+		for _, point := range points {
+			if matchesSchemaVersion(point.tags, *sourceVersion) {
+				migration.Apply(point.tags, point.fields)
+				migrated = append(migrated, point)
+			} else {
+				kept = append(kept, point)
+			}
+		}
+		if !*deleteSource {
+			err = writePoints(ctx, writeAPI, *target, migrated)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		err = replaceWindow(ctx, client, writeAPI, cfg, *target, migrated, kept, windowStart, windowStop)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("migrated %d points\n", total)
+	return nil
+}
+
+// stagingMeasurement holds the rewritten points of a window bound for
+// measurement while its source points are deleted, so that an interrupted
+// migration can be resumed without losing the window
+func stagingMeasurement(measurement string) string {
+	return measurement + "_migration"
+}
+
+// keptStagingMeasurement holds the points of a window written back unchanged,
+// apart from the migrated points, whose field types they may conflict with
+// when the migration rewrites the measurement in place
+var keptStagingMeasurement = daylight.Measurement + "_migration_kept"
+
+// writePoints writes points to measurement
+func writePoints(ctx context.Context, writeAPI api.WriteAPIBlocking, measurement string, points []*migrationPoint) error {
+	if len(points) == 0 {
+		return nil
+	}
+	rewritten := make([]*write.Point, 0, len(points))
+	for _, point := range points {
+		rewritten = append(rewritten, influx.NewPoint(measurement, point.tags, point.fields, point.time))
+	}
+	err := writeAPI.WritePoint(ctx, rewritten...)
+	if err != nil {
+		return fmt.Errorf("unable to write migrated points to %s, %s", measurement, err)
+	}
+	return nil
+}
+
+// deleteWindow deletes the points of measurement in [start, stop), the same
+// range ReadPoints reads; the delete API includes its stop
+func deleteWindow(ctx context.Context, client influx.Client, cfg *config.Configuration, measurement string, start, stop time.Time) error {
+	predicate := fmt.Sprintf("_measurement=%s", output.FluxString(measurement))
+	err := client.DeleteAPI().DeleteWithName(ctx, cfg.InfluxDB.Organization, cfg.InfluxDB.Bucket, start, stop.Add(-time.Nanosecond), predicate)
+	if err != nil {
+		return fmt.Errorf("unable to delete points of %s, %s", measurement, err)
+	}
+	return nil
+}
+
+// replaceWindow replaces the source points of a window with the migrated
+// points, written to target, and the kept points, written back unchanged.
+// Both are staged first, since InfluxDB rejects the converted field types
+// until the source points are deleted.
+func replaceWindow(ctx context.Context, client influx.Client, writeAPI api.WriteAPIBlocking, cfg *config.Configuration, target string, migrated, kept []*migrationPoint, start, stop time.Time) error {
+	err := writePoints(ctx, writeAPI, stagingMeasurement(target), migrated)
+	if err != nil {
+		return err
+	}
+	err = writePoints(ctx, writeAPI, keptStagingMeasurement, kept)
+	if err != nil {
+		return err
+	}
+	return finishWindow(ctx, client, writeAPI, cfg, target, migrated, kept, start, stop)
+}
+
+// finishWindow deletes the source points of a window, writes the staged
+// points to their measurements and deletes them from staging
+func finishWindow(ctx context.Context, client influx.Client, writeAPI api.WriteAPIBlocking, cfg *config.Configuration, target string, migrated, kept []*migrationPoint, start, stop time.Time) error {
+	err := deleteWindow(ctx, client, cfg, daylight.Measurement, start, stop)
+	if err != nil {
+		return err
+	}
+	err = writePoints(ctx, writeAPI, target, migrated)
+	if err != nil {
+		return err
+	}
+	err = writePoints(ctx, writeAPI, daylight.Measurement, kept)
+	if err != nil {
+		return err
+	}
+	err = deleteWindow(ctx, client, cfg, stagingMeasurement(target), start, stop)
+	if err != nil {
+		return err
+	}
+	return deleteWindow(ctx, client, cfg, keptStagingMeasurement, start, stop)
+}
+
+// resumeWindow finishes the migration of a window left staged by an
+// interrupted run, reporting whether there was one
+func resumeWindow(ctx context.Context, client influx.Client, writeAPI api.WriteAPIBlocking, cfg *config.Configuration, bucket, target string, start, stop time.Time) (bool, error) {
+	migrated, err := ReadPoints(ctx, client, cfg, bucket, stagingMeasurement(target), start, stop)
+	if err != nil {
+		return false, err
+	}
+	kept, err := ReadPoints(ctx, client, cfg, bucket, keptStagingMeasurement, start, stop)
+	if err != nil {
+		return false, err
+	}
+	if len(migrated) == 0 && len(kept) == 0 {
+		return false, nil
+	}
+	return true, finishWindow(ctx, client, writeAPI, cfg, target, migrated, kept, start, stop)
+}
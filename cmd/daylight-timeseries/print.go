@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"io"
+	"os"
+	"time"
+)
+
+// DayTimes are the sun events of one day at a location
+type DayTimes struct {
+	Date      string               `json:"date"`
+	Latitude  float64              `json:"latitude"`
+	Longitude float64              `json:"longitude"`
+	Events    map[string]time.Time `json:"events"`
+	DayLength float64              `json:"day_length_seconds"`
+	// Now is the daylight state at the time of printing, for today only
+	Now *daylight.DaylightAt `json:"now,omitempty"`
+}
+
+// CalculateDayTimes returns the sun events of the solar date of t, in the location of
+// t; events that do not happen that day are left out
+func CalculateDayTimes(cfg config.Configuration, t time.Time) DayTimes {
+	day := daylight.SolarDate(t, cfg.Longitude)
+	events := daylight.DayEvents(cfg.DaylightOptions(), day.Year(), day.Month(), day.Day())
+	latitude, longitude := cfg.PublicCoordinates()
+	times := DayTimes{
+		Date:      day.Format("2006-01-02"),
+		Latitude:  latitude,
+		Longitude: longitude,
+		Events:    map[string]time.Time{},
+		DayLength: daylight.DayLength(cfg.Latitude, cfg.Longitude, events, day.Year(), day.Month(), day.Day()).Seconds(),
+	}
+	for _, name := range daylight.CountdownEvents {
+		if event := events.Event(name); !event.IsZero() {
+			times.Events[name] = event.In(t.Location())
+		}
+	}
+	return times
+}
+
+// WriteDayTimes writes the sun events of a day as text or JSON
+func WriteDayTimes(w io.Writer, times DayTimes, format string) error {
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(times)
+	}
+	fmt.Fprintf(w, "%s at %.4f, %.4f\n", times.Date, times.Latitude, times.Longitude)
+	for _, name := range daylight.CountdownEvents {
+		value := "-"
+		if event, ok := times.Events[name]; ok {
+			value = event.Format("15:04:05 MST")
+		}
+		fmt.Fprintf(w, "  %-18s %s\n", name, value)
+	}
+	fmt.Fprintf(w, "  %-18s %s\n", "day_length", (time.Duration(times.DayLength) * time.Second).Round(time.Second))
+	if times.Now != nil {
+		_, err := fmt.Fprintf(w, "  %-18s daylight=%t phase=%s elevation=%.2f\n", "now", times.Now.Daylight, times.Now.Phase, times.Now.Elevation)
+		return err
+	}
+	return nil
+}
+
+// RunPrint implements the print subcommand
+func RunPrint(cfg *config.Configuration, args []string) error {
+	flags := flag.NewFlagSet("print", flag.ExitOnError)
+	date := flags.String("date", "", "date to print as YYYY-MM-DD; defaults to today")
+	timezone := flags.String("timezone", "", "IANA time zone to print times in; defaults to the system one")
+	format := flags.String("format", "text", "output format, text or json")
+	flags.Parse(args)
+
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("-format must be text or json")
+	}
+	location := time.Local
+	if *timezone != "" {
+		var err error
+		location, err = time.LoadLocation(*timezone)
+		if err != nil {
+			return fmt.Errorf("invalid -timezone %s, %s", *timezone, err)
+		}
+	}
+
+	now := time.Now().In(location)
+	t := now
+	if *date != "" {
+		day, err := time.Parse("2006-01-02", *date)
+		if err != nil {
+			return fmt.Errorf("invalid -date %s, %s", *date, err)
+		}
+		t = solarDateTime(day, cfg.Longitude).In(location)
+	}
+	return WriteDayTimes(os.Stdout, dayTimesWithNow(*cfg, t, now), *format)
+}
+
+// solarDateTime returns local mean solar noon of a date at longitude, which
+// falls on that solar date
+func solarDateTime(day time.Time, longitude float64) time.Time {
+	return day.Add(12*time.Hour - time.Duration(longitude/15*float64(time.Hour)))
+}
+
+// dayTimesWithNow returns the sun events of the solar date of t, with the
+// daylight state at now when that date is today
+func dayTimesWithNow(cfg config.Configuration, t, now time.Time) DayTimes {
+	times := CalculateDayTimes(cfg, t)
+	if times.Date == daylight.SolarDate(now, cfg.Longitude).Format("2006-01-02") {
+		at := daylight.CalculateDaylightAt(cfg.DaylightOptions(), now)
+		times.Now = &at
+	}
+	return times
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RecentSamples is a ring buffer of the latest samples, served as JSON so
+// that lightweight consumers get a short history without a database
+type RecentSamples struct {
+	mu      sync.Mutex
+	samples []daylight.Sample
+	next    int
+	full    bool
+}
+
+func NewRecentSamples(size int) *RecentSamples {
+	return &RecentSamples{samples: make([]daylight.Sample, max(size, 0))}
+}
+
+// Add keeps a sample, replacing the oldest one once the buffer is full
+func (r *RecentSamples) Add(sample daylight.Sample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.samples) == 0 {
+		return
+	}
+	r.samples[r.next] = sample
+	r.next = (r.next + 1) % len(r.samples)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Samples returns the kept samples, oldest first
+func (r *RecentSamples) Samples() []daylight.Sample {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]daylight.Sample{}, r.samples[:r.next]...)
+	}
+	return append(append([]daylight.Sample{}, r.samples[r.next:]...), r.samples[:r.next]...)
+}
+
+// ServeHTTP answers GET /v1/recent with the kept samples, oldest first,
+// optionally only those after since (RFC3339) and at most the latest limit
+func (r *RecentSamples) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	samples := r.Samples()
+	if value := req.URL.Query().Get("since"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			http.Error(w, "since must be an RFC3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		i := 0
+		for i < len(samples) && !samples[i].Time.After(since) {
+			i++
+		}
+		samples = samples[i:]
+	}
+	if value := req.URL.Query().Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			http.Error(w, "limit must be a non-negative number", http.StatusBadRequest)
+			return
+		}
+		if limit < len(samples) {
+			samples = samples[len(samples)-limit:]
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(samples)
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"github.com/iwvelando/daylight-timeseries/pkg/output"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"path/filepath"
+	"reflect"
+	"runtime/debug"
+	"time"
+)
+
+// goMemoryLimit is the memory limit the runtime started with, from
+// GOMEMLIMIT, which applies while memory.limit is not set
+var goMemoryLimit = debug.SetMemoryLimit(-1)
+
+// errGRPCNotBuiltIn is the error of a gRPC server left out of a slim build
+var errGRPCNotBuiltIn = fmt.Errorf("the gRPC server is not built into this binary, rebuild it without -tags slim or with -tags slim,grpc")
+
+// loadConfiguration loads the configuration file, then checks its outputs
+// and gRPC server against those built into this binary, loads its ephemeris file and
+// applies its memory limits
+func loadConfiguration(configPath string) (*config.Configuration, error) {
+	cfg, err := config.LoadConfiguration(configPath)
+	if err != nil {
+		return nil, err
+	}
+	err = output.ValidateOutputTypes(*cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.GRPC.Address != "" && !GRPCBuiltIn {
+		return nil, fmt.Errorf("grpc.address is set, but %s", errGRPCNotBuiltIn)
+	}
+	err = daylight.UseEphemeris(cfg.DaylightOptions())
+	if err != nil {
+		return nil, err
+	}
+	output.LimitQueues(cfg.Memory)
+	if cfg.Memory.Limit > 0 {
+		debug.SetMemoryLimit(cfg.Memory.Limit)
+	} else {
+		debug.SetMemoryLimit(goMemoryLimit)
+	}
+	return cfg, nil
+}
+
+// ReloadConfiguration loads the configuration file again, with the same
+// overrides and flags, for a running instance. Viper is reset first, since
+// values set while loading the previous configuration would otherwise take
+// precedence over the file.
+func ReloadConfiguration(configPath string, role config.Role) (*config.Configuration, error) {
+	viper.Reset()
+	cfg, err := loadConfiguration(configPath)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Role = role
+	return cfg, nil
+}
+
+// RestartSettings lists the settings a reload does not apply, since they are
+// only read at startup, that differ between old and new
+func RestartSettings(old, new *config.Configuration) []string {
+	var changed []string
+	for _, setting := range []struct {
+		name     string
+		old, new interface{}
+	}{
+		{"http", old.HTTP, new.HTTP},
+		{"grpc", old.GRPC, new.GRPC},
+		{"status", old.Status, new.Status},
+		{"display", old.Display, new.Display},
+		{"digest", old.Digest, new.Digest},
+		{"homeAssistant", old.HomeAssistant, new.HomeAssistant},
+		{"watchConfig", old.WatchConfig, new.WatchConfig},
+	} {
+		if !reflect.DeepEqual(setting.old, setting.new) {
+			changed = append(changed, setting.name)
+		}
+	}
+	return changed
+}
+
+// Reload applies a new configuration to the collector. Outputs opened with
+// the same settings keep running with their queues and connections; changed
+// and removed ones are flushed and closed before the changed and added ones
+// are opened, so nothing queued is lost. An output that fails to open is
+// logged and left out until the next reload.
+func (c *Collector) Reload(cfg *config.Configuration, tracker *output.StatusTracker) {
+	wanted := cfg.OutputConfigs()
+	settings := make(map[string][]interface{}, len(wanted))
+	for _, output := range wanted {
+		settings[output.Name] = cfg.OutputSettings(output)
+	}
+
+	kept := map[string]output.Output{}
+	for _, output := range c.currentOutputs() {
+		name := output.Name()
+		if reflect.DeepEqual(c.settings[name], settings[name]) {
+			kept[name] = output
+			continue
+		}
+		err := output.Close()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"op":     "Collector.Reload",
+				"output": name,
+				"error":  err,
+			}).Error("failed to close output")
+		}
+	}
+
+	var outputs []output.Output
+	for _, outputConfig := range wanted {
+		if output, ok := kept[outputConfig.Name]; ok {
+			outputs = append(outputs, output)
+			continue
+		}
+		output, err := output.OpenOutput(cfg, outputConfig, tracker)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"op":     "Collector.Reload",
+				"output": outputConfig.Name,
+				"error":  err,
+			}).Error("failed to open output, leaving it out until the next reload")
+			delete(settings, outputConfig.Name)
+			continue
+		}
+		log.WithFields(log.Fields{
+			"op":     "Collector.Reload",
+			"output": outputConfig.Name,
+		}).Info("opened output")
+		outputs = append(outputs, output)
+	}
+
+	c.mu.Lock()
+	c.outputs, c.settings = outputs, settings
+	c.mu.Unlock()
+	c.configure(cfg)
+}
+
+// WatchConfiguration signals reloads whenever the configuration file is
+// written or replaced, until ctx is done. The directory is watched rather
+// than the file, since editors and configuration management often replace
+// the file by renaming a new one over it; bursts of events are coalesced.
+// A remote source is watched through etcd or Consul instead.
+func WatchConfiguration(ctx context.Context, configPath string, reloads chan<- struct{}) {
+	if remote, ok := config.ParseRemoteSource(configPath); ok {
+		watchRemoteConfiguration(ctx, remote, reloads)
+		return
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"op":    "WatchConfiguration",
+			"error": err,
+		}).Error("failed to watch the configuration file")
+		return
+	}
+	defer watcher.Close()
+	path := filepath.Clean(configPath)
+	err = watcher.Add(filepath.Dir(path))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"op":    "WatchConfiguration",
+			"error": err,
+		}).Error("failed to watch the configuration file")
+		return
+	}
+
+	var settle <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) == path && event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename) {
+				settle = time.After(500 * time.Millisecond)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.WithFields(log.Fields{
+				"op":    "WatchConfiguration",
+				"error": err,
+			}).Error("error watching the configuration file")
+		case <-settle:
+			settle = nil
+			select {
+			case reloads <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// watchRemoteConfiguration signals reloads whenever the key of a remote
+// source changes, until ctx is done, watching again after a failure
+func watchRemoteConfiguration(ctx context.Context, remote config.RemoteSource, reloads chan<- struct{}) {
+	for {
+		err := remote.Watch(ctx, func() {
+			select {
+			case reloads <- struct{}{}:
+			default:
+			}
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		log.WithFields(log.Fields{
+			"op":     "WatchConfiguration",
+			"remote": remote.String(),
+			"error":  err,
+		}).Error("error watching the remote configuration, retrying")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
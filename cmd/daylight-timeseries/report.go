@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// ReportEntry holds the reference and computed sunrise/sunset for one day
+type ReportEntry struct {
+	Date             time.Time
+	ReferenceSunrise time.Time
+	ReferenceSunset  time.Time
+	Sunrise          time.Time
+	Sunset           time.Time
+}
+
+// LoadReference reads a CSV of date,sunrise,sunset rows. Dates use the
+// 2006-01-02 layout; times may be HH:MM, HH:MM:SS or RFC3339. Bare times are
+// interpreted in loc. A header row and blank lines are ignored.
+func LoadReference(r io.Reader, loc *time.Location) ([]ReportEntry, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var entries []ReportEntry
+	line := 0
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read reference data, %s", err)
+		}
+		line++
+		if len(record) < 3 {
+			return nil, fmt.Errorf("reference line %d: expected date,sunrise,sunset", line)
+		}
+
+		date, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(record[0]), loc)
+		if err != nil {
+			if line == 1 {
+				// Header row
+				continue
+			}
+			return nil, fmt.Errorf("reference line %d: invalid date %q, %s", line, record[0], err)
+		}
+		entry := ReportEntry{Date: date}
+		entry.ReferenceSunrise, err = parseReferenceTime(date, record[1], loc)
+		if err != nil {
+			return nil, fmt.Errorf("reference line %d: invalid sunrise %q, %s", line, record[1], err)
+		}
+		entry.ReferenceSunset, err = parseReferenceTime(date, record[2], loc)
+		if err != nil {
+			return nil, fmt.Errorf("reference line %d: invalid sunset %q, %s", line, record[2], err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func parseReferenceTime(date time.Time, value string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "-" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("expected HH:MM, HH:MM:SS or RFC3339")
+}
+
+// CompareReference fills in the computed sunrise/sunset for each entry
+func CompareReference(cfg config.Configuration, entries []ReportEntry) {
+	for i := range entries {
+		d := entries[i].Date
+		entries[i].Sunrise, entries[i].Sunset = daylight.SunriseSunset(cfg.DaylightOptions(),
+			d.Year(),
+			d.Month(),
+			d.Day(),
+		)
+	}
+}
+
+// WriteReport prints per-day deltas (computed minus reference) and a summary
+func WriteReport(w io.Writer, entries []ReportEntry, loc *time.Location) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "date\tsunrise\treference\tdelta\tsunset\treference\tdelta")
+
+	var count int
+	var sum, worst float64
+	for _, e := range entries {
+		riseDelta, riseOk := reportDelta(e.Sunrise, e.ReferenceSunrise)
+		setDelta, setOk := reportDelta(e.Sunset, e.ReferenceSunset)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.Date.Format("2006-01-02"),
+			reportClock(e.Sunrise, loc),
+			reportClock(e.ReferenceSunrise, loc),
+			reportDeltaString(riseDelta, riseOk),
+			reportClock(e.Sunset, loc),
+			reportClock(e.ReferenceSunset, loc),
+			reportDeltaString(setDelta, setOk),
+		)
+		for _, delta := range []struct {
+			value float64
+			ok    bool
+		}{{riseDelta, riseOk}, {setDelta, setOk}} {
+			if !delta.ok {
+				continue
+			}
+			count++
+			sum += math.Abs(delta.value)
+			worst = math.Max(worst, math.Abs(delta.value))
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if count == 0 {
+		_, err := fmt.Fprintln(w, "\nno comparable events")
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d events compared, mean absolute delta %.1fs, max absolute delta %.0fs\n",
+		count, sum/float64(count), worst)
+	return err
+}
+
+func reportDelta(computed, reference time.Time) (float64, bool) {
+	if computed.IsZero() || reference.IsZero() {
+		return 0, false
+	}
+	return computed.Sub(reference).Seconds(), true
+}
+
+func reportDeltaString(delta float64, ok bool) string {
+	if !ok {
+		return "n/a"
+	}
+	return fmt.Sprintf("%+.0fs", delta)
+}
+
+func reportClock(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.In(loc).Format("15:04:05")
+}
+
+// RunReport implements the report subcommand
+func RunReport(cfg *config.Configuration, args []string) error {
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	referencePath := flags.String("reference", "", "path to a reference CSV of date,sunrise,sunset rows")
+	timezone := flags.String("timezone", "Local", "time zone of the reference times and report output")
+	flags.Parse(args)
+
+	if *referencePath == "" {
+		return fmt.Errorf("-reference is required")
+	}
+	loc, err := time.LoadLocation(*timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone %s, %s", *timezone, err)
+	}
+
+	f, err := os.Open(*referencePath)
+	if err != nil {
+		return fmt.Errorf("unable to open reference file %s, %s", *referencePath, err)
+	}
+	defer f.Close()
+
+	entries, err := LoadReference(f, loc)
+	if err != nil {
+		return err
+	}
+	CompareReference(*cfg, entries)
+
+	return WriteReport(os.Stdout, entries, loc)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"github.com/iwvelando/daylight-timeseries/pkg/output"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// RuleEngine evaluates the rules against each sample written
+type RuleEngine struct {
+	Config config.Configuration
+	rules  []config.Rule
+	active []bool
+	last   time.Time
+	date   string
+	days   []daylight.SunEvents
+}
+
+func NewRuleEngine(cfg config.Configuration) *RuleEngine {
+	rules := cfg.ParsedRules()
+	return &RuleEngine{
+		Config: cfg,
+		rules:  rules,
+		active: make([]bool, len(rules)),
+	}
+}
+
+// Evaluate checks the rules against a sample and notifies for those that
+// fire. Condition rules fire when their condition becomes true, including on
+// the first sample; event rules fire when their moment falls between the
+// previous sample and this one.
+func (e *RuleEngine) Evaluate(ctx context.Context, sample daylight.Sample) {
+	now := sample.Time
+	day := daylight.SolarDate(now, e.Config.Longitude)
+	if date := day.Format("2006-01-02"); date != e.date {
+		e.date = date
+		e.days = nil
+		for i := -1; i <= 1; i++ {
+			d := day.AddDate(0, 0, i)
+			e.days = append(e.days, daylight.DayEvents(e.Config.DaylightOptions(), d.Year(), d.Month(), d.Day()))
+		}
+	}
+
+	for i, rule := range e.rules {
+		if field := rule.Field(); field != "" {
+			holds := rule.Holds(e.value(field, sample, day))
+			if holds && !e.active[i] {
+				e.fire(ctx, rule, now, rule.When)
+			}
+			e.active[i] = holds
+			continue
+		}
+		if e.last.IsZero() || !now.After(e.last) {
+			continue
+		}
+		if next := daylight.NextCountdown(*rule.Event(), e.last, e.days); !next.IsZero() && !next.After(now) {
+			e.fire(ctx, rule, now, fmt.Sprintf("%s at %s", rule.At, next.Format(time.RFC3339)))
+		}
+	}
+	e.last = now
+}
+
+// value looks a field up in the sample, falling back to the computed day
+// length in seconds for day_length
+func (e *RuleEngine) value(field string, sample daylight.Sample, day time.Time) interface{} {
+	if value, ok := sample.Fields[field]; ok {
+		return value
+	}
+	if field == "day_length" {
+		events := e.days[1]
+		return daylight.DayLength(e.Config.Latitude, e.Config.Longitude, events, day.Year(), day.Month(), day.Day()).Seconds()
+	}
+	return nil
+}
+
+func (e *RuleEngine) fire(ctx context.Context, rule config.Rule, now time.Time, reason string) {
+	message := rule.Message
+	if message == "" {
+		message = fmt.Sprintf("rule %s: %s", rule.Name, reason)
+	}
+	config.Logger(ctx).WithFields(log.Fields{
+		"op":   "RuleEngine.Evaluate",
+		"rule": rule.Name,
+	}).Info(message)
+	output.Notify(ctx, e.Config.Notifiers, rule.Notify, config.Notification{
+		Time:      now,
+		Rule:      rule.Name,
+		Message:   message,
+		RequestID: config.RequestID(ctx),
+	})
+}
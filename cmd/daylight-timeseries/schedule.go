@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// LightingPeriod is one night of a lighting schedule; On or Off are zero when
+// the sun does not cross the twilight elevation on that day
+type LightingPeriod struct {
+	Date string
+	On   time.Time
+	Off  time.Time
+}
+
+func (p LightingPeriod) MarshalJSON() ([]byte, error) {
+	format := func(t time.Time) *string {
+		if t.IsZero() {
+			return nil
+		}
+		s := t.Format(time.RFC3339)
+		return &s
+	}
+	return json.Marshal(struct {
+		Date string  `json:"date"`
+		On   *string `json:"on"`
+		Off  *string `json:"off"`
+	}{p.Date, format(p.On), format(p.Off)})
+}
+
+// twilightEvents picks the evening and morning events a schedule is based on
+func twilightEvents(events daylight.SunEvents, twilight string) (time.Time, time.Time, error) {
+	switch twilight {
+	case "sunset":
+		return events.Sunset, events.Sunrise, nil
+	case "", "civil":
+		return events.CivilDusk, events.CivilDawn, nil
+	case "nautical":
+		return events.NauticalDusk, events.NauticalDawn, nil
+	case "astronomical":
+		return events.AstronomicalDusk, events.AstronomicalDawn, nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("unknown twilight %q, expected sunset, civil, nautical or astronomical", twilight)
+}
+
+// clampClock moves t to the given HH:MM on the same local day if t is on the
+// wrong side of it
+func clampClock(t time.Time, clock string, earliest bool) (time.Time, error) {
+	if clock == "" || t.IsZero() {
+		return t, nil
+	}
+	c, err := time.Parse("15:04", clock)
+	if err != nil {
+		return t, fmt.Errorf("invalid clock time %q, expected HH:MM", clock)
+	}
+	bound := time.Date(t.Year(), t.Month(), t.Day(), c.Hour(), c.Minute(), 0, 0, t.Location())
+	if (earliest && t.Before(bound)) || (!earliest && t.After(bound)) {
+		return bound, nil
+	}
+	return t, nil
+}
+
+// CalculateLightingSchedule returns the lighting periods for days nights
+// starting at from: lights switch on at the evening twilight of each date plus
+// OnOffset minutes and off at the following morning twilight plus OffOffset
+// minutes, optionally bounded by EarliestOn and LatestOff
+func CalculateLightingSchedule(cfg config.Configuration, from time.Time, days int) ([]LightingPeriod, error) {
+	schedule := cfg.LightingSchedule
+	loc := time.Local
+	if schedule.Timezone != "" {
+		var err error
+		loc, err = time.LoadLocation(schedule.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid lighting schedule timezone %s, %s", schedule.Timezone, err)
+		}
+	}
+
+	periods := make([]LightingPeriod, 0, days)
+	for i := 0; i < days; i++ {
+		d := from.AddDate(0, 0, i)
+		next := d.AddDate(0, 0, 1)
+		evening, _, err := twilightEvents(daylight.DayEvents(cfg.DaylightOptions(), d.Year(), d.Month(), d.Day()), schedule.Twilight)
+		if err != nil {
+			return nil, err
+		}
+		_, morning, _ := twilightEvents(daylight.DayEvents(cfg.DaylightOptions(), next.Year(), next.Month(), next.Day()), schedule.Twilight)
+
+		period := LightingPeriod{Date: d.Format("2006-01-02")}
+		if !evening.IsZero() {
+			period.On = evening.Add(time.Duration(schedule.OnOffset) * time.Minute).In(loc)
+		}
+		if !morning.IsZero() {
+			period.Off = morning.Add(time.Duration(schedule.OffOffset) * time.Minute).In(loc)
+		}
+		period.On, err = clampClock(period.On, schedule.EarliestOn, true)
+		if err != nil {
+			return nil, err
+		}
+		period.Off, err = clampClock(period.Off, schedule.LatestOff, false)
+		if err != nil {
+			return nil, err
+		}
+		periods = append(periods, period)
+	}
+	return periods, nil
+}
+
+// WriteLightingSchedule encodes a schedule as csv or json
+func WriteLightingSchedule(w io.Writer, periods []LightingPeriod, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(periods)
+	case "csv":
+		out := csv.NewWriter(w)
+		out.Write([]string{"date", "on", "off"})
+		for _, p := range periods {
+			row := []string{p.Date, "", ""}
+			if !p.On.IsZero() {
+				row[1] = p.On.Format(time.RFC3339)
+			}
+			if !p.Off.IsZero() {
+				row[2] = p.Off.Format(time.RFC3339)
+			}
+			out.Write(row)
+		}
+		out.Flush()
+		return out.Error()
+	}
+	return fmt.Errorf("unknown format %q, expected csv or json", format)
+}
+
+// ScheduleHandler serves the lighting schedule starting today; the days and
+// format query parameters default to 7 and json
+func ScheduleHandler(cfg config.Configuration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		days := 7
+		if value := r.URL.Query().Get("days"); value != "" {
+			var err error
+			days, err = strconv.Atoi(value)
+			if err != nil || days < 1 || days > 366 {
+				http.Error(w, "days must be between 1 and 366", http.StatusBadRequest)
+				return
+			}
+		}
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "json"
+		}
+		if format != "json" && format != "csv" {
+			http.Error(w, "format must be csv or json", http.StatusBadRequest)
+			return
+		}
+
+		periods, err := CalculateLightingSchedule(cfg, time.Now(), days)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv")
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		WriteLightingSchedule(w, periods, format)
+	})
+}
+
+// RunSchedule implements the schedule subcommand
+func RunSchedule(cfg *config.Configuration, args []string) error {
+	flags := flag.NewFlagSet("schedule", flag.ExitOnError)
+	from := flags.String("from", time.Now().Format("2006-01-02"), "first date of the schedule")
+	days := flags.Int("days", 7, "number of nights to schedule")
+	format := flags.String("format", "csv", "output format, csv or json")
+	output := flags.String("output", "-", "path to write the schedule to, - for stdout")
+	flags.Parse(args)
+
+	start, err := time.ParseInLocation("2006-01-02", *from, time.Local)
+	if err != nil {
+		return fmt.Errorf("invalid -from date %s, %s", *from, err)
+	}
+	if *days < 1 {
+		return fmt.Errorf("-days must be at least 1")
+	}
+
+	periods, err := CalculateLightingSchedule(*cfg, start, *days)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("unable to create schedule file %s, %s", *output, err)
+		}
+		defer f.Close()
+		w = f
+	}
+	return WriteLightingSchedule(w, periods, *format)
+}
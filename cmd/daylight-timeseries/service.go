@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ServiceLabel is the launchd label of the service, its name with the reverse
+// domain launchd expects
+const ServiceLabel = "com.github.iwvelando.daylight-timeseries"
+
+// Service describes the background service the install-service subcommand
+// registers: the binary run with the configuration file, as a system service
+// or, with User, one of the current user
+type Service struct {
+	Name       string
+	Executable string
+	Args       []string
+	WorkingDir string
+	User       bool
+}
+
+// ServiceArgs returns the arguments the service runs the binary with: the
+// absolute configuration path, or the remote source, and the -role, -set,
+// -lat and -lon given
+func ServiceArgs() ([]string, error) {
+	configPath := flag.Lookup("config").Value.String()
+	if _, ok := config.ParseRemoteSource(configPath); !ok {
+		var err error
+		configPath, err = filepath.Abs(configPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to resolve the configuration path, %s", err)
+		}
+	}
+	args := []string{"-config", configPath}
+	if role := flag.Lookup("role").Value.String(); role != "" {
+		args = append(args, "-role", role)
+	}
+	for _, override := range config.ConfigOverrides {
+		args = append(args, "-set", override)
+	}
+	if config.LatitudeFlag != "" {
+		args = append(args, "-lat", config.LatitudeFlag)
+	}
+	if config.LongitudeFlag != "" {
+		args = append(args, "-lon", config.LongitudeFlag)
+	}
+	return args, nil
+}
+
+// systemdQuote quotes an argument of ExecStart when it needs it
+func systemdQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\$%;") {
+		return arg
+	}
+	arg = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `$$`, `%`, `%%`).Replace(arg)
+	return `"` + arg + `"`
+}
+
+// SystemdUnit returns the systemd unit of the service; SIGHUP reloads the
+// configuration, so systemctl reload works
+func (s Service) SystemdUnit() string {
+	command := []string{systemdQuote(s.Executable)}
+	for _, arg := range s.Args {
+		command = append(command, systemdQuote(arg))
+	}
+	target := "multi-user.target"
+	if s.User {
+		target = "default.target"
+	}
+	return fmt.Sprintf(`[Unit]
+Description=Daylight time series collector
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+ExecStart=%s
+ExecReload=/bin/kill -HUP $MAINPID
+WorkingDirectory=%s
+Restart=on-failure
+RestartSec=10
+
+[Install]
+WantedBy=%s
+`, strings.Join(command, " "), s.WorkingDir, target)
+}
+
+// xmlEscape escapes text for a plist string
+func xmlEscape(text string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(text))
+	return buf.String()
+}
+
+// LaunchdPlist returns the launchd property list of the service, logging to
+// logPath
+func (s Service) LaunchdPlist(logPath string) string {
+	var args strings.Builder
+	for _, arg := range append([]string{s.Executable}, s.Args...) {
+		fmt.Fprintf(&args, "\t\t<string>%s</string>\n", xmlEscape(arg))
+	}
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+%s	</array>
+	<key>WorkingDirectory</key>
+	<string>%s</string>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<true/>
+	<key>StandardOutPath</key>
+	<string>%s</string>
+	<key>StandardErrorPath</key>
+	<string>%s</string>
+</dict>
+</plist>
+`, xmlEscape(s.Name), args.String(), xmlEscape(s.WorkingDir), xmlEscape(logPath), xmlEscape(logPath))
+}
+
+// servicePaths returns where the service definition and, for launchd, its
+// log go on this platform
+func (s Service) servicePaths() (definition, logPath string, err error) {
+	home, _ := os.UserHomeDir()
+	switch runtime.GOOS {
+	case "linux":
+		if s.User {
+			return filepath.Join(home, ".config", "systemd", "user", s.Name+".service"), "", nil
+		}
+		return filepath.Join("/etc/systemd/system", s.Name+".service"), "", nil
+	case "darwin":
+		if s.User {
+			return filepath.Join(home, "Library", "LaunchAgents", s.Name+".plist"),
+				filepath.Join(home, "Library", "Logs", "daylight-timeseries.log"), nil
+		}
+		return filepath.Join("/Library/LaunchDaemons", s.Name+".plist"), "/var/log/daylight-timeseries.log", nil
+	}
+	return "", "", fmt.Errorf("services are only supported with systemd on Linux and launchd on macOS, not on %s", runtime.GOOS)
+}
+
+// Definition returns the unit or property list of the service on this
+// platform and where it goes
+func (s Service) Definition() (path, content string, err error) {
+	path, logPath, err := s.servicePaths()
+	if err != nil {
+		return "", "", err
+	}
+	if runtime.GOOS == "darwin" {
+		return path, s.LaunchdPlist(logPath), nil
+	}
+	return path, s.SystemdUnit(), nil
+}
+
+// systemctl runs systemctl for the system or user service manager
+func (s Service) systemctl(args ...string) error {
+	if s.User {
+		args = append([]string{"--user"}, args...)
+	}
+	return runServiceManager("systemctl", args...)
+}
+
+func runServiceManager(name string, args ...string) error {
+	output, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("unable to run %s %s, %s: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Install writes the service definition, then enables and starts the service
+func (s Service) Install() (string, error) {
+	path, content, err := s.Definition()
+	if err != nil {
+		return "", err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err == nil {
+		err = os.WriteFile(path, []byte(content), 0644)
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to write %s, %s", path, err)
+	}
+	if runtime.GOOS == "darwin" {
+		return path, runServiceManager("launchctl", "load", "-w", path)
+	}
+	err = s.systemctl("daemon-reload")
+	if err != nil {
+		return path, err
+	}
+	return path, s.systemctl("enable", "--now", s.Name+".service")
+}
+
+// Uninstall stops and disables the service, then removes its definition
+func (s Service) Uninstall() (string, error) {
+	path, _, err := s.servicePaths()
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("the service %s is not installed, %s", s.Name, err)
+	}
+	if runtime.GOOS == "darwin" {
+		err = runServiceManager("launchctl", "unload", "-w", path)
+	} else {
+		err = s.systemctl("disable", "--now", s.Name+".service")
+	}
+	if err != nil {
+		return path, err
+	}
+	err = os.Remove(path)
+	if err != nil {
+		return path, fmt.Errorf("unable to remove %s, %s", path, err)
+	}
+	if runtime.GOOS == "linux" {
+		return path, s.systemctl("daemon-reload")
+	}
+	return path, nil
+}
+
+// serviceFlags parses the flags shared by install-service and
+// uninstall-service
+func serviceFlags(name string, args []string, install bool) (Service, bool) {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	defaultName := "daylight-timeseries"
+	if runtime.GOOS == "darwin" {
+		defaultName = ServiceLabel
+	}
+	service := Service{}
+	flags.StringVar(&service.Name, "name", defaultName, "name of the systemd unit or launchd label")
+	flags.BoolVar(&service.User, "user", false, "a service of the current user rather than a system service")
+	var dryRun *bool
+	if install {
+		dryRun = flags.Bool("dry-run", false, "print the service definition instead of installing it")
+	}
+	flags.Parse(args)
+	return service, dryRun != nil && *dryRun
+}
+
+// RunInstallService implements the install-service subcommand
+func RunInstallService(cfg *config.Configuration, args []string) error {
+	service, dryRun := serviceFlags("install-service", args, true)
+	executable, err := os.Executable()
+	if err == nil {
+		executable, err = filepath.EvalSymlinks(executable)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to locate the executable, %s", err)
+	}
+	service.Executable = executable
+	service.Args, err = ServiceArgs()
+	if err != nil {
+		return err
+	}
+	// A remote configuration has no directory of its own, so the service
+	// runs next to the executable
+	service.WorkingDir = filepath.Dir(executable)
+	if _, remote := config.ParseRemoteSource(service.Args[1]); !remote {
+		service.WorkingDir = filepath.Dir(service.Args[1])
+	}
+
+	if dryRun {
+		path, content, err := service.Definition()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("# %s\n%s", path, content)
+		return nil
+	}
+	path, err := service.Install()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("installed and started %s from %s\n", service.Name, path)
+	return nil
+}
+
+// RunUninstallService implements the uninstall-service subcommand
+func RunUninstallService(cfg *config.Configuration, args []string) error {
+	service, _ := serviceFlags("uninstall-service", args, false)
+	path, err := service.Uninstall()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("stopped %s and removed %s\n", service.Name, path)
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/output"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+)
+
+// HealthHandler serves the current status as JSON, with status 503 when the
+// instance is unhealthy
+func HealthHandler(cfg config.Configuration, tracker *output.StatusTracker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := tracker.Snapshot()
+		w.Header().Set("Content-Type", "application/json")
+		if err := status.Check(time.Now(), output.StatusMaxAge(cfg), outputNames(cfg)); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(status)
+	})
+}
+
+// RunStatus implements the status subcommand, exiting 0 when the running
+// instance has written recently and 1 otherwise
+func RunStatus(cfg *config.Configuration, args []string) error {
+	flags := flag.NewFlagSet("status", flag.ExitOnError)
+	statusFile := flags.String("file", cfg.Status.File, "path to the status file of the running instance")
+	quiet := flags.Bool("quiet", false, "only report through the exit code")
+	flags.Parse(args)
+
+	if *statusFile == "" {
+		return fmt.Errorf("no status file configured")
+	}
+
+	status, err := output.ReadStatus(*statusFile)
+	if err == nil {
+		err = status.Check(time.Now(), output.StatusMaxAge(*cfg), outputNames(*cfg))
+	}
+	if err != nil {
+		if !*quiet {
+			fmt.Printf("unhealthy: %s\n", err)
+			printOutputStatus(status)
+		}
+		os.Exit(1)
+	}
+	if !*quiet {
+		if last := status.LastSuccess(outputNames(*cfg)); last.IsZero() {
+			fmt.Printf("healthy: started %s, no write yet\n", status.Started.Format(time.RFC3339))
+		} else {
+			fmt.Printf("healthy: last write %s\n", last.Format(time.RFC3339))
+		}
+		printOutputStatus(status)
+	}
+	return nil
+}
+
+// outputNames returns the names of the enabled outputs, whose writes count
+// towards health
+func outputNames(cfg config.Configuration) []string {
+	var names []string
+	for _, output := range cfg.OutputConfigs() {
+		names = append(names, output.Name)
+	}
+	return names
+}
+
+// printOutputStatus prints the write counters of each output
+func printOutputStatus(status output.Status) {
+	for _, name := range slices.Sorted(maps.Keys(status.Outputs)) {
+		s := status.Outputs[name]
+		lastSuccess := "never"
+		if !s.LastSuccess.IsZero() {
+			lastSuccess = s.LastSuccess.Format(time.RFC3339)
+		}
+		fmt.Printf("  %s: %d points, %d bytes, %d failures, %d retries, last success %s\n", name, s.Points, s.Bytes, s.Failures, s.Retries, lastSuccess)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// StreamMessage is a single server-sent event
+type StreamMessage struct {
+	Event string
+	Data  interface{}
+}
+
+// Broadcaster fans samples and transitions out to connected stream clients
+type Broadcaster struct {
+	mu          sync.Mutex
+	subscribers map[chan StreamMessage]struct{}
+	last        *StreamMessage
+}
+
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{
+		subscribers: map[chan StreamMessage]struct{}{},
+	}
+}
+
+// Subscribe registers a new client; the returned function unregisters it
+func (b *Broadcaster) Subscribe() (<-chan StreamMessage, func()) {
+	ch := make(chan StreamMessage, 16)
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	if b.last != nil {
+		ch <- *b.last
+	}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subscribers, ch)
+		b.mu.Unlock()
+	}
+}
+
+// Publish sends a message to every client, dropping it for clients whose
+// buffer is full rather than stalling the poll loop
+func (b *Broadcaster) Publish(msg StreamMessage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if msg.Event == "sample" {
+		b.last = &msg
+	}
+	for ch := range b.subscribers {
+		select {
+		case ch <- msg:
+		default:
+			log.WithFields(log.Fields{
+				"op": "Broadcaster.Publish",
+			}).Warn("stream client too slow, dropping message")
+		}
+	}
+}
+
+// PublishSample sends a sample and the transitions since the previous one
+func (b *Broadcaster) PublishSample(sample daylight.Sample, transitions []daylight.Transition) {
+	b.Publish(StreamMessage{Event: "sample", Data: sample})
+	for _, transition := range transitions {
+		b.Publish(StreamMessage{Event: "transition", Data: transition})
+	}
+}
+
+// ServeHTTP streams messages to the client as server-sent events
+func (b *Broadcaster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	messages, unsubscribe := b.Subscribe()
+	defer unsubscribe()
+
+	keepalive := time.NewTicker(30 * time.Second)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		case msg := <-messages:
+			data, err := json.Marshal(msg.Data)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"op":    "Broadcaster.ServeHTTP",
+					"error": err,
+				}).Error("failed to encode stream message")
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.Event, data)
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// SweepLocation is a candidate site compared by the sweep subcommand
+type SweepLocation struct {
+	Name      string
+	Latitude  float64
+	Longitude float64
+}
+
+// SweepDay holds the sun events of one location on one day
+type SweepDay struct {
+	Location  SweepLocation
+	Date      time.Time
+	Sunrise   time.Time
+	Sunset    time.Time
+	DayLength time.Duration
+}
+
+// SweepSummary aggregates the days of one location
+type SweepSummary struct {
+	Location      SweepLocation
+	Days          int
+	MinDayLength  time.Duration
+	MaxDayLength  time.Duration
+	MeanDayLength time.Duration
+}
+
+// sweepLocations collects repeated -location name=latitude,longitude flags
+type sweepLocations []SweepLocation
+
+func (s *sweepLocations) String() string {
+	return fmt.Sprint(*s)
+}
+
+func (s *sweepLocations) Set(value string) error {
+	name, coordinates, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("expected name=latitude,longitude")
+	}
+	location, err := parseSweepLocation(name, strings.Split(coordinates, ","))
+	if err != nil {
+		return err
+	}
+	*s = append(*s, location)
+	return nil
+}
+
+func parseSweepLocation(name string, coordinates []string) (SweepLocation, error) {
+	if len(coordinates) != 2 {
+		return SweepLocation{}, fmt.Errorf("expected latitude,longitude for %s", name)
+	}
+	latitude, err := config.ParseCoordinate(coordinates[0], "latitude")
+	if err != nil {
+		return SweepLocation{}, fmt.Errorf("invalid latitude for %s, %s", name, err)
+	}
+	longitude, err := config.ParseCoordinate(coordinates[1], "longitude")
+	if err != nil {
+		return SweepLocation{}, fmt.Errorf("invalid longitude for %s, %s", name, err)
+	}
+	err = config.ValidateCoordinates(latitude, longitude)
+	if err != nil {
+		return SweepLocation{}, fmt.Errorf("invalid location %s, %s", name, err)
+	}
+	return SweepLocation{Name: strings.TrimSpace(name), Latitude: latitude, Longitude: longitude}, nil
+}
+
+// LoadSweepLocations reads a CSV of name,latitude,longitude rows, skipping a
+// header row
+func LoadSweepLocations(r io.Reader) ([]SweepLocation, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	var locations []SweepLocation
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read locations, %s", err)
+		}
+		if len(record) != 3 {
+			return nil, fmt.Errorf("locations line %d: expected name,latitude,longitude", line)
+		}
+		location, err := parseSweepLocation(record[0], record[1:])
+		if err != nil {
+			if line == 1 {
+				// Header row
+				continue
+			}
+			return nil, fmt.Errorf("locations line %d: %s", line, err)
+		}
+		locations = append(locations, location)
+	}
+	return locations, nil
+}
+
+// Sweep computes the sun events of every location for days days from from
+func Sweep(locations []SweepLocation, from time.Time, days int) []SweepDay {
+	var results []SweepDay
+	for _, location := range locations {
+		for i := 0; i < days; i++ {
+			d := from.AddDate(0, 0, i)
+			events := daylight.CalculateSunEvents(location.Latitude, location.Longitude, 0, d.Year(), d.Month(), d.Day())
+			results = append(results, SweepDay{
+				Location:  location,
+				Date:      d,
+				Sunrise:   events.Sunrise,
+				Sunset:    events.Sunset,
+				DayLength: daylight.DayLength(location.Latitude, location.Longitude, events, d.Year(), d.Month(), d.Day()),
+			})
+		}
+	}
+	return results
+}
+
+// SummarizeSweep aggregates sweep results per location, in input order
+func SummarizeSweep(results []SweepDay) []SweepSummary {
+	var summaries []SweepSummary
+	index := map[string]int{}
+	var totals []time.Duration
+	for _, day := range results {
+		i, ok := index[day.Location.Name]
+		if !ok {
+			i = len(summaries)
+			index[day.Location.Name] = i
+			summaries = append(summaries, SweepSummary{
+				Location:     day.Location,
+				MinDayLength: day.DayLength,
+				MaxDayLength: day.DayLength,
+			})
+			totals = append(totals, 0)
+		}
+		s := &summaries[i]
+		s.Days++
+		totals[i] += day.DayLength
+		if day.DayLength < s.MinDayLength {
+			s.MinDayLength = day.DayLength
+		}
+		if day.DayLength > s.MaxDayLength {
+			s.MaxDayLength = day.DayLength
+		}
+	}
+	for i := range summaries {
+		summaries[i].MeanDayLength = totals[i] / time.Duration(summaries[i].Days)
+	}
+	return summaries
+}
+
+func sweepClock(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(loc).Format(time.RFC3339)
+}
+
+func sweepHours(d time.Duration) string {
+	return strconv.FormatFloat(d.Hours(), 'f', 3, 64)
+}
+
+// WriteSweep writes per-day results, or per-location summaries, as an aligned
+// table or CSV
+func WriteSweep(w io.Writer, results []SweepDay, summary bool, format string, loc *time.Location) error {
+	var rows [][]string
+	if summary {
+		rows = append(rows, []string{"location", "latitude", "longitude", "days", "min_day_length_h", "mean_day_length_h", "max_day_length_h"})
+		for _, s := range SummarizeSweep(results) {
+			rows = append(rows, []string{
+				s.Location.Name,
+				fmt.Sprint(s.Location.Latitude),
+				fmt.Sprint(s.Location.Longitude),
+				strconv.Itoa(s.Days),
+				sweepHours(s.MinDayLength),
+				sweepHours(s.MeanDayLength),
+				sweepHours(s.MaxDayLength),
+			})
+		}
+	} else {
+		rows = append(rows, []string{"location", "date", "sunrise", "sunset", "day_length_h"})
+		for _, day := range results {
+			rows = append(rows, []string{
+				day.Location.Name,
+				day.Date.Format("2006-01-02"),
+				sweepClock(day.Sunrise, loc),
+				sweepClock(day.Sunset, loc),
+				sweepHours(day.DayLength),
+			})
+		}
+	}
+
+	switch format {
+	case "csv":
+		out := csv.NewWriter(w)
+		out.WriteAll(rows)
+		return out.Error()
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, row := range rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("unknown format %q, expected table or csv", format)
+}
+
+// RunSweep implements the sweep subcommand
+func RunSweep(_ *config.Configuration, args []string) error {
+	var locations sweepLocations
+	flags := flag.NewFlagSet("sweep", flag.ExitOnError)
+	flags.Var(&locations, "location", "candidate location as name=latitude,longitude; may be repeated")
+	locationsPath := flags.String("locations", "", "path to a CSV of name,latitude,longitude rows")
+	from := flags.String("from", time.Now().Format("2006-01-02"), "first date to compare")
+	to := flags.String("to", "", "last date to compare; defaults to -from")
+	summary := flags.Bool("summary", false, "print one row of day length statistics per location")
+	format := flags.String("format", "table", "output format, table or csv")
+	timezone := flags.String("timezone", "UTC", "time zone to print sunrise and sunset in")
+	flags.Parse(args)
+
+	if *locationsPath != "" {
+		f, err := os.Open(*locationsPath)
+		if err != nil {
+			return fmt.Errorf("unable to open locations file %s, %s", *locationsPath, err)
+		}
+		loaded, err := LoadSweepLocations(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		locations = append(locations, loaded...)
+	}
+	if len(locations) == 0 {
+		return fmt.Errorf("no locations given, use -location or -locations")
+	}
+
+	start, err := time.Parse("2006-01-02", *from)
+	if err != nil {
+		return fmt.Errorf("invalid -from date %s, %s", *from, err)
+	}
+	end := start
+	if *to != "" {
+		end, err = time.Parse("2006-01-02", *to)
+		if err != nil {
+			return fmt.Errorf("invalid -to date %s, %s", *to, err)
+		}
+	}
+	if end.Before(start) {
+		return fmt.Errorf("-to must not be before -from")
+	}
+	loc, err := time.LoadLocation(*timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone %s, %s", *timezone, err)
+	}
+
+	days := int(end.Sub(start).Hours()/24) + 1
+	return WriteSweep(os.Stdout, Sweep(locations, start, days), *summary, *format, loc)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ExpectedTransition is an event the replay must produce within a tolerance
+// of the given time
+type ExpectedTransition struct {
+	Event string
+	Time  time.Time
+}
+
+// LoadExpectedTransitions reads a CSV of event,time rows with RFC3339 times
+func LoadExpectedTransitions(r io.Reader) ([]ExpectedTransition, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	var expected []ExpectedTransition
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read expectations, %s", err)
+		}
+		if len(record) != 2 {
+			return nil, fmt.Errorf("expectations line %d: expected event,time", line)
+		}
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(record[1]))
+		if err != nil {
+			if line == 1 {
+				// Header row
+				continue
+			}
+			return nil, fmt.Errorf("expectations line %d: invalid time %q, %s", line, record[1], err)
+		}
+		expected = append(expected, ExpectedTransition{Event: strings.TrimSpace(record[0]), Time: t})
+	}
+	return expected, nil
+}
+
+// CheckTransitions matches each expectation to a distinct observed
+// transition of the same event within tolerance and describes any failures
+func CheckTransitions(observed []daylight.Transition, expected []ExpectedTransition, tolerance time.Duration) []string {
+	var failures []string
+	used := make([]bool, len(observed))
+	for _, e := range expected {
+		match := -1
+		for i, o := range observed {
+			if used[i] || o.Event != e.Event {
+				continue
+			}
+			delta := o.Time.Sub(e.Time)
+			if delta < 0 {
+				delta = -delta
+			}
+			if delta <= tolerance {
+				match = i
+				break
+			}
+		}
+		if match < 0 {
+			failures = append(failures, fmt.Sprintf("expected %s at %s, not observed within %s",
+				e.Event, e.Time.Format(time.RFC3339), tolerance))
+			continue
+		}
+		used[match] = true
+	}
+	return failures
+}
+
+// RunVerify implements the verify subcommand
+func RunVerify(cfg *config.Configuration, args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	from := flags.String("from", "", "RFC3339 time or 2006-01-02 date to start the replay at")
+	to := flags.String("to", "", "RFC3339 time or 2006-01-02 date to end the replay at")
+	interval := flags.Duration("interval", cfg.PollInterval, "simulated poll interval")
+	count := flags.Int("transitions", -1, "expected number of transitions; not checked when negative")
+	expectPath := flags.String("expect", "", "path to a CSV of event,time rows that must be observed")
+	tolerance := flags.Duration("tolerance", 0, "allowed distance between expected and observed transitions; defaults to the interval")
+	flags.Parse(args)
+
+	start, err := parseReplayTime(*from)
+	if err != nil {
+		return fmt.Errorf("invalid -from %q, %s", *from, err)
+	}
+	end, err := parseReplayTime(*to)
+	if err != nil {
+		return fmt.Errorf("invalid -to %q, %s", *to, err)
+	}
+	if !end.After(start) {
+		return fmt.Errorf("-to must be after -from")
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("-interval must be positive")
+	}
+	if *tolerance == 0 {
+		*tolerance = *interval
+	}
+
+	var expected []ExpectedTransition
+	if *expectPath != "" {
+		f, err := os.Open(*expectPath)
+		if err != nil {
+			return fmt.Errorf("unable to open expectations file %s, %s", *expectPath, err)
+		}
+		expected, err = LoadExpectedTransitions(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	observed := daylight.Replay(cfg.DaylightOptions(), start, end, *interval)
+	for _, t := range observed {
+		fmt.Printf("%s\t%s\n", t.Time.Format(time.RFC3339), t.Event)
+	}
+
+	failures := CheckTransitions(observed, expected, *tolerance)
+	if *count >= 0 && len(observed) != *count {
+		failures = append(failures, fmt.Sprintf("expected %d transitions, observed %d", *count, len(observed)))
+	}
+	if len(failures) > 0 {
+		for _, failure := range failures {
+			fmt.Printf("FAIL: %s\n", failure)
+		}
+		return fmt.Errorf("%d of the verification checks failed", len(failures))
+	}
+	fmt.Printf("OK: %d transitions observed\n", len(observed))
+	return nil
+}
+
+func parseReplayTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
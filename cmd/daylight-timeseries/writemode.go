@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"time"
+)
+
+// WriteFilter selects the samples of one series to write in transitions mode
+type WriteFilter struct {
+	Mode      config.WriteMode
+	Heartbeat time.Duration
+	written   time.Time
+}
+
+// Due reports whether a sample at t is written, given whether any of its
+// boolean fields changed since the previous sample. The first sample is
+// always written so the series starts with the current state.
+func (f *WriteFilter) Due(t time.Time, changed bool) bool {
+	if f.Mode != config.WriteTransitions || changed || f.written.IsZero() ||
+		(f.Heartbeat > 0 && t.Sub(f.written) >= f.Heartbeat) || t.Before(f.written) {
+		f.written = t
+		return true
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTPAuth protects a group of HTTP endpoints. A request is let through when
+// any configured method accepts it; a group without any method is open.
+type HTTPAuth struct {
+	Users  []HTTPUser
+	Tokens []string
+	OAuth2 OAuth2Introspection
+}
+
+// HTTPUser is a basic auth username and password
+type HTTPUser struct {
+	Username string
+	Password string
+}
+
+// OAuth2Introspection validates bearer tokens issued to clients through the
+// OAuth2 client credentials grant by asking the authorization server's token
+// introspection endpoint (RFC 7662)
+type OAuth2Introspection struct {
+	IntrospectionURL string
+	ClientID         string
+	ClientSecret     string
+	Scope            string
+	CacheTTL         time.Duration
+}
+
+// introspectionTimeout bounds a token introspection, which holds up the
+// request being authorized
+const introspectionTimeout = 10 * time.Second
+
+// introspectionClient queries introspection endpoints
+var introspectionClient = &http.Client{Timeout: introspectionTimeout}
+
+// tokenCache remembers active tokens until they need introspecting again
+type tokenCache struct {
+	mu      sync.Mutex
+	expires map[[sha256.Size]byte]time.Time
+}
+
+func (a HTTPAuth) Enabled() bool {
+	return len(a.Users) > 0 || len(a.Tokens) > 0 || a.OAuth2.IntrospectionURL != ""
+}
+
+// Authenticator returns a function reporting whether a request carries valid
+// credentials, for servers other than HTTP ones building a request from
+// their metadata; introspected tokens are cached across its calls
+func (a HTTPAuth) Authenticator() func(*http.Request) (bool, error) {
+	cache := &tokenCache{expires: map[[sha256.Size]byte]time.Time{}}
+	return func(r *http.Request) (bool, error) {
+		return a.authenticate(r, cache)
+	}
+}
+
+// Protect wraps handler so that only authenticated requests reach it
+func (a HTTPAuth) Protect(handler http.Handler) http.Handler {
+	if !a.Enabled() {
+		return handler
+	}
+	authenticate := a.Authenticator()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok, err := authenticate(r)
+		if err != nil {
+			Logger(r.Context()).WithFields(log.Fields{
+				"op":    "HTTPAuth.Protect",
+				"error": err,
+			}).Error("failed to authenticate request")
+			http.Error(w, "unable to authenticate request", http.StatusServiceUnavailable)
+			return
+		}
+		if !ok {
+			if len(a.Users) > 0 {
+				w.Header().Add("WWW-Authenticate", `Basic realm="daylight-timeseries"`)
+			}
+			if len(a.Tokens) > 0 || a.OAuth2.IntrospectionURL != "" {
+				w.Header().Add("WWW-Authenticate", `Bearer realm="daylight-timeseries"`)
+			}
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func (a HTTPAuth) authenticate(r *http.Request, cache *tokenCache) (bool, error) {
+	if username, password, ok := r.BasicAuth(); ok {
+		for _, user := range a.Users {
+			usernameOk := subtle.ConstantTimeCompare([]byte(username), []byte(user.Username)) == 1
+			passwordOk := subtle.ConstantTimeCompare([]byte(password), []byte(user.Password)) == 1
+			if usernameOk && passwordOk {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return false, nil
+	}
+	for _, t := range a.Tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+			return true, nil
+		}
+	}
+	if a.OAuth2.IntrospectionURL != "" {
+		return a.OAuth2.active(r, token, cache)
+	}
+	return false, nil
+}
+
+// active reports whether the authorization server considers token active and,
+// when Scope is set, granted that scope. Active tokens are cached for
+// CacheTTL (a minute by default) or until they expire.
+func (o OAuth2Introspection) active(r *http.Request, token string, cache *tokenCache) (bool, error) {
+	key := sha256.Sum256([]byte(token))
+	now := time.Now()
+	cache.mu.Lock()
+	expires, ok := cache.expires[key]
+	cache.mu.Unlock()
+	if ok && now.Before(expires) {
+		return true, nil
+	}
+
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, o.IntrospectionURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, fmt.Errorf("unable to create introspection request, %s", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(o.ClientID), url.QueryEscape(o.ClientSecret))
+	resp, err := introspectionClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("unable to introspect token, %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unable to introspect token, %s", resp.Status)
+	}
+
+	var result struct {
+		Active bool   `json:"active"`
+		Scope  string `json:"scope"`
+		Exp    int64  `json:"exp"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return false, fmt.Errorf("unable to decode introspection response, %s", err)
+	}
+	if !result.Active {
+		return false, nil
+	}
+	if o.Scope != "" && !slices.Contains(strings.Fields(result.Scope), o.Scope) {
+		return false, nil
+	}
+
+	ttl := o.CacheTTL
+	if ttl == 0 {
+		ttl = time.Minute
+	}
+	expires = now.Add(ttl)
+	if result.Exp != 0 && time.Unix(result.Exp, 0).Before(expires) {
+		expires = time.Unix(result.Exp, 0)
+	}
+	cache.mu.Lock()
+	for k, e := range cache.expires {
+		if !now.Before(e) {
+			delete(cache.expires, k)
+		}
+	}
+	cache.expires[key] = expires
+	cache.mu.Unlock()
+	return true, nil
+}
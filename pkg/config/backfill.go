@@ -0,0 +1,12 @@
+package config
+
+import (
+	"time"
+)
+
+// GapFill backfills the samples missed while the exporter was down, from the
+// last point found in InfluxDB at startup, going back at most MaxGap
+type GapFill struct {
+	Enabled bool
+	MaxGap  time.Duration
+}
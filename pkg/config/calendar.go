@@ -0,0 +1,297 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultHolidayURL is the Nager.Date public holiday API queried for
+// calendar.country
+const DefaultHolidayURL = "https://date.nager.at/api/v3/PublicHolidays"
+
+// Day types tagged on samples when the calendar is configured
+const (
+	DayTypeWorkday = "workday"
+	DayTypeWeekend = "weekend"
+	DayTypeHoliday = "holiday"
+)
+
+// Calendar tags samples with the day_type of their local date, workday,
+// weekend or holiday, and with WorkingHours adds the daylight within the
+// working hours of workdays, for occupancy against daylight analyses.
+// Holidays come from an iCalendar file or URL, from the public holidays of
+// Country (an ISO 3166-1 alpha-2 code such as US or DE), or both.
+type Calendar struct {
+	ICS          string
+	Country      string
+	URL          string
+	Weekend      []string
+	WorkingHours WakingHours
+	Timezone     string
+	Timeout      time.Duration
+
+	location *time.Location
+	weekend  map[time.Weekday]bool
+	holidays *Holidays
+}
+
+func (c Calendar) Enabled() bool {
+	return c.ICS != "" || c.Country != "" || c.WorkingHours.Enabled()
+}
+
+func (c Calendar) Validate() error {
+	if c.WorkingHours.Enabled() {
+		err := c.WorkingHours.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid calendar.workingHours, %s", err)
+		}
+	}
+	if c.Country != "" && len(c.Country) != 2 {
+		return fmt.Errorf("invalid calendar.country %q, expected a two letter ISO 3166-1 code", c.Country)
+	}
+	for _, day := range c.Weekend {
+		if _, ok := parseWeekday(day); !ok {
+			return fmt.Errorf("invalid calendar.weekend day %q, expected a weekday name such as saturday", day)
+		}
+	}
+	return nil
+}
+
+func parseWeekday(name string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(name, day.String()) || strings.EqualFold(name, day.String()[:3]) {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// load resolves the time zone and weekend days; holidays are fetched when
+// first needed
+func (c *Calendar) load() error {
+	c.location = time.Local
+	if c.Timezone != "" {
+		location, err := time.LoadLocation(c.Timezone)
+		if err != nil {
+			return fmt.Errorf("invalid calendar timezone %s, %s", c.Timezone, err)
+		}
+		c.location = location
+	}
+	weekend := c.Weekend
+	if len(weekend) == 0 {
+		weekend = []string{"saturday", "sunday"}
+	}
+	c.weekend = map[time.Weekday]bool{}
+	for _, name := range weekend {
+		day, _ := parseWeekday(name)
+		c.weekend[day] = true
+	}
+	c.holidays = NewHolidays(*c)
+	return nil
+}
+
+// DayType returns whether the local date of t is a holiday, a weekend day or
+// a workday; holidays win over weekends
+func (c Calendar) DayType(t time.Time) string {
+	local := t.In(c.location)
+	if c.holidays != nil && c.holidays.Contains(local.Format("2006-01-02")) {
+		return DayTypeHoliday
+	}
+	if c.weekend[local.Weekday()] {
+		return DayTypeWeekend
+	}
+	return DayTypeWorkday
+}
+
+// WorkingDaylight returns how much of the working hours of the local date of
+// t lie between sunrise and sunset, zero on weekends and holidays
+func (c Calendar) WorkingDaylight(sunriseTime, sunsetTime time.Time, polarDay bool, t time.Time) time.Duration {
+	if c.DayType(t) != DayTypeWorkday {
+		return 0
+	}
+	return WakingDaylight(c.WorkingHours, sunriseTime, sunsetTime, polarDay, t.In(c.location))
+}
+
+// Holidays holds the holiday dates of a calendar, loading the iCalendar once
+// and the public holidays of the country a year at a time. Loads run in the
+// background, the dates counting as no holidays until they complete, and a
+// failed load is logged and retried after HolidayRetry instead of on every
+// sample.
+type Holidays struct {
+	calendar Calendar
+	client   *http.Client
+
+	mu sync.Mutex
+	// dates are the public holidays of the loaded years, ics those of the
+	// iCalendar once loaded
+	dates   map[string]bool
+	years   map[int]bool
+	ics     *ICSHolidays
+	loading map[string]bool
+	failed  map[string]time.Time
+}
+
+// HolidayRetry is how long a failed holiday load waits before it is tried again
+const HolidayRetry = time.Hour
+
+func NewHolidays(calendar Calendar) *Holidays {
+	timeout := calendar.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	return &Holidays{
+		calendar: calendar,
+		client:   &http.Client{Timeout: timeout},
+		dates:    map[string]bool{},
+		years:    map[int]bool{},
+		loading:  map[string]bool{},
+		failed:   map[string]time.Time{},
+	}
+}
+
+// Contains reports whether a date, as 2006-01-02, is a holiday
+func (h *Holidays) Contains(date string) bool {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.calendar.ICS != "" && h.ics == nil {
+		h.fetch("ics", func() (func(), error) {
+			ics, err := h.loadICS()
+			return func() { h.ics = ics }, err
+		})
+	}
+	if h.calendar.Country != "" && !h.years[day.Year()] {
+		year := day.Year()
+		h.fetch(fmt.Sprintf("%s/%d", h.calendar.Country, year), func() (func(), error) {
+			dates, err := h.loadCountry(year)
+			return func() {
+				for d := range dates {
+					h.dates[d] = true
+				}
+				h.years[year] = true
+			}, err
+		})
+	}
+	if h.dates[date] {
+		return true
+	}
+	return h.ics != nil && h.ics.Contains(day)
+}
+
+// fetch starts loading a source in the background unless it is loading
+// already or failed recently. load runs without the lock so that samples
+// are not held up by a slow server; the function it returns stores the
+// holidays under the lock.
+func (h *Holidays) fetch(source string, load func() (func(), error)) {
+	if h.loading[source] || !h.due(source) {
+		return
+	}
+	h.loading[source] = true
+	go func() {
+		store, err := load()
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.loading, source)
+		if h.loaded(source, err) {
+			store()
+		}
+	}()
+}
+
+// due reports whether a source may be loaded, not having failed recently
+func (h *Holidays) due(source string) bool {
+	failed, ok := h.failed[source]
+	return !ok || time.Since(failed) >= HolidayRetry
+}
+
+// loaded logs a failed load and reports success
+func (h *Holidays) loaded(source string, err error) bool {
+	if err == nil {
+		delete(h.failed, source)
+		return true
+	}
+	h.failed[source] = time.Now()
+	log.WithFields(log.Fields{
+		"op":     "Holidays.fetch",
+		"source": source,
+		"error":  err,
+	}).Warn("failed to load holidays, retrying in an hour")
+	return false
+}
+
+func (h *Holidays) get(location string) (io.ReadCloser, error) {
+	u, err := url.Parse(location)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return os.Open(location)
+	}
+	req, err := http.NewRequest(http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "daylight-timeseries")
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s returned %s", location, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+func (h *Holidays) loadICS() (*ICSHolidays, error) {
+	body, err := h.get(h.calendar.ICS)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read holiday calendar %s, %s", h.calendar.ICS, err)
+	}
+	defer body.Close()
+	holidays, err := ParseICS(body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse holiday calendar %s, %s", h.calendar.ICS, err)
+	}
+	return holidays, nil
+}
+
+// publicHoliday is an entry of the Nager.Date API; Global is false for
+// holidays of some regions only
+type publicHoliday struct {
+	Date   string `json:"date"`
+	Global bool   `json:"global"`
+}
+
+func (h *Holidays) loadCountry(year int) (map[string]bool, error) {
+	base := h.calendar.URL
+	if base == "" {
+		base = DefaultHolidayURL
+	}
+	location := fmt.Sprintf("%s/%d/%s", strings.TrimSuffix(base, "/"), year, strings.ToUpper(h.calendar.Country))
+	body, err := h.get(location)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch the public holidays of %s, %s", h.calendar.Country, err)
+	}
+	defer body.Close()
+	var holidays []publicHoliday
+	err = json.NewDecoder(body).Decode(&holidays)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode the public holidays of %s, %s", h.calendar.Country, err)
+	}
+	dates := map[string]bool{}
+	for _, holiday := range holidays {
+		if holiday.Global {
+			dates[holiday.Date] = true
+		}
+	}
+	return dates, nil
+}
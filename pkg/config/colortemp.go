@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"math"
+)
+
+// ColorTemperature configures night-shift suggestions in the style of
+// redshift: Day kelvin while the sun is at or above DayElevation, Night
+// kelvin at or below NightElevation and a linear blend in between
+type ColorTemperature struct {
+	Day            int
+	Night          int
+	DayElevation   *float64
+	NightElevation *float64
+}
+
+// Enabled reports whether the color_temperature field should be emitted
+func (c ColorTemperature) Enabled() bool {
+	return c.Day != 0 || c.Night != 0
+}
+
+// withDefaults fills in redshift's defaults for unset values, night from
+// civil twilight on
+func (c ColorTemperature) withDefaults() ColorTemperature {
+	if c.Day == 0 {
+		c.Day = 6500
+	}
+	if c.Night == 0 {
+		c.Night = 3500
+	}
+	if c.DayElevation == nil {
+		elevation := 3.0
+		c.DayElevation = &elevation
+	}
+	if c.NightElevation == nil {
+		elevation := -6.0
+		c.NightElevation = &elevation
+	}
+	return c
+}
+
+func (c ColorTemperature) Validate() error {
+	c = c.withDefaults()
+	if c.Day < 1000 || c.Day > 25000 || c.Night < 1000 || c.Night > 25000 {
+		return fmt.Errorf("color temperatures must be between 1000K and 25000K")
+	}
+	if *c.DayElevation <= *c.NightElevation {
+		return fmt.Errorf("color temperature dayElevation must be above nightElevation")
+	}
+	return nil
+}
+
+// Kelvin returns the suggested color temperature for a solar elevation
+func (c ColorTemperature) Kelvin(elevation float64) int {
+	c = c.withDefaults()
+	if elevation >= *c.DayElevation {
+		return c.Day
+	}
+	if elevation <= *c.NightElevation {
+		return c.Night
+	}
+	progress := (elevation - *c.NightElevation) / (*c.DayElevation - *c.NightElevation)
+	return int(math.Round(float64(c.Night) + progress*float64(c.Day-c.Night)))
+}
@@ -0,0 +1,471 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"time"
+)
+
+// Config represents a YAML-formatted config file
+type Configuration struct {
+	Latitude             float64
+	Longitude            float64
+	Altitude             float64
+	LocationName         string
+	Locations            []NamedLocation
+	Role                 Role
+	PollInterval         time.Duration
+	OverrunPolicy        OverrunPolicy
+	WriteMode            WriteMode
+	Heartbeat            time.Duration
+	LowPower             bool
+	WatchConfig          bool
+	RecomputeInterval    time.Duration
+	TimeOffset           time.Duration
+	ExactTransitionTimes bool
+	TransitionPoints     bool
+	Aviation             bool
+	Fields               map[string]bool
+	TagCoordinates       bool
+	Privacy              Privacy
+	EphemerisFile        string
+	Status               StatusConfig
+	InfluxDB             InfluxDB
+	OPCUA                OPCUA
+	HTTP                 HTTP
+	GRPC                 GRPC
+	LightingSchedule     LightingSchedule
+	WakingHours          WakingHours
+	Display              Display
+	ColorTemperature     ColorTemperature
+	Exposure             Exposure
+	FIFO                 FIFO
+	Hooks                []Hook
+	LocalTime            LocalTime
+	Calendar             Calendar
+	HomeAssistant        HomeAssistant
+	DynamicTags          DynamicTags
+	LightSensor          LightSensor
+	Countdowns           map[string]string
+	Thresholds           map[string]string
+	GoldenHour           ElevationBand
+	BlueHour             ElevationBand
+	LocationGroups       []LocationGroup
+	Notifiers            map[string]Notifier
+	GapFill              GapFill
+	BackfillToday        bool
+	Spool                Spool
+	Memory               Memory
+	Rules                []Rule
+	Digest               Digest
+	CrossCheck           CrossCheck
+	Geocode              Geocode
+	Outputs              []OutputConfig
+	SupplementalLight    SupplementalLight
+	Forecast             Forecast
+	MQTT                 MQTT
+
+	countdowns []Countdown
+	thresholds []Threshold
+	rules      []Rule
+	placeTags  map[string]string
+	// locationTags are the tags of a named location
+	locationTags map[string]string
+}
+
+type InfluxDB struct {
+	Address           string
+	Username          string
+	Password          string
+	MeasurementPrefix string
+	Database          string
+	RetentionPolicy   string
+	Token             string
+	Organization      string
+	Bucket            string
+	SkipVerifySsl     bool
+	FlushInterval     time.Duration
+	VerifyInterval    uint
+	Wait              bool
+	WaitTimeout       time.Duration
+	UserAgent         string
+	Headers           map[string]string
+	ErrorLog          ErrorLog
+	Version           int
+	Flight            InfluxFlight
+
+	// delivery holds the flush, batch and retry settings of the output
+	// writing to this InfluxDB
+	delivery OutputConfig
+}
+
+// DefaultFlushInterval is how long points are buffered before being written
+// to InfluxDB unless influxDB.flushInterval says otherwise
+const DefaultFlushInterval = 30 * time.Second
+
+// DefaultPollInterval is the time between samples unless pollInterval says
+// otherwise
+const DefaultPollInterval = time.Minute
+
+// MinPollInterval is the shortest pollInterval accepted; the sun moves a
+// quarter of a minute of arc per second, so shorter intervals only add load
+const MinPollInterval = time.Second
+
+// ValidateFlushInterval checks that points are flushed at a rate the client
+// can honour, which counts in milliseconds
+func (i InfluxDB) ValidateFlushInterval() error {
+	if i.FlushInterval <= 0 {
+		return fmt.Errorf("influxDB.flushInterval must be positive, e.g. 30s, got %s", i.FlushInterval)
+	}
+	if i.FlushInterval < time.Millisecond {
+		return fmt.Errorf("influxDB.flushInterval must be at least 1ms, got %s", i.FlushInterval)
+	}
+	return nil
+}
+
+// FlushEvery returns the flush interval of the client, that of the output
+// writing to this InfluxDB when it sets one
+func (i InfluxDB) FlushEvery() time.Duration {
+	if i.delivery.FlushInterval > 0 {
+		return i.delivery.FlushInterval
+	}
+	if i.FlushInterval == 0 {
+		return DefaultFlushInterval
+	}
+	return i.FlushInterval
+}
+
+// Delivery returns the flush, batch and retry settings of the output writing
+// to this InfluxDB
+func (i InfluxDB) Delivery() OutputConfig {
+	return i.delivery
+}
+
+// WithDelivery returns the settings of this InfluxDB as written to by an
+// output with the given flush, batch and retry settings
+func (i InfluxDB) WithDelivery(output OutputConfig) InfluxDB {
+	i.delivery = output
+	return i
+}
+
+// NewConfiguration returns the configuration of a location with the defaults
+// LoadConfiguration starts from, for programs computing samples without a
+// configuration file
+func NewConfiguration(latitude, longitude float64) Configuration {
+	configuration := Configuration{
+		Latitude:     latitude,
+		Longitude:    longitude,
+		PollInterval: DefaultPollInterval,
+		GoldenHour:   DefaultGoldenHour,
+		BlueHour:     DefaultBlueHour,
+	}
+	configuration.Privacy.CoordinatePrecision = -1
+	configuration.InfluxDB.FlushInterval = DefaultFlushInterval
+	configuration.HTTP.Recent = DefaultRecentSamples
+	configuration.GRPC.Notice = DefaultGRPCNotice
+	return configuration
+}
+
+// Load a config file and return the Config struct
+func LoadConfiguration(configPath string) (*Configuration, error) {
+	remote, isRemote := ParseRemoteSource(configPath)
+	if !isRemote {
+		viper.SetConfigFile(configPath)
+	}
+	viper.AutomaticEnv()
+	viper.SetConfigType("yml")
+	viper.SetDefault("privacy.coordinatePrecision", -1)
+	viper.SetDefault("influxDB.flushInterval", DefaultFlushInterval)
+	viper.SetDefault("pollInterval", DefaultPollInterval)
+	viper.SetDefault("http.recent", DefaultRecentSamples)
+	viper.SetDefault("grpc.notice", DefaultGRPCNotice)
+	viper.SetDefault("goldenHour.low", DefaultGoldenHour.Low)
+	viper.SetDefault("goldenHour.high", DefaultGoldenHour.High)
+	viper.SetDefault("blueHour.low", DefaultBlueHour.Low)
+	viper.SetDefault("blueHour.high", DefaultBlueHour.High)
+
+	if isRemote {
+		err := readRemoteConfig(remote)
+		if err != nil {
+			return nil, fmt.Errorf("error reading config from %s, %s", remote, err)
+		}
+	} else {
+		err := viper.ReadInConfig()
+		if err != nil {
+			// -lat and -lon are enough to compute with the defaults
+			if !ConfigMissing(configPath) || !CoordinateFlagsSet() {
+				return nil, fmt.Errorf("error reading config file %s, %s", configPath, err)
+			}
+		}
+	}
+	err := ConfigOverrides.Apply()
+	if err != nil {
+		return nil, err
+	}
+	if LatitudeFlag != "" {
+		viper.Set("latitude", LatitudeFlag)
+	}
+	if LongitudeFlag != "" {
+		viper.Set("longitude", LongitudeFlag)
+	}
+
+	// Coordinates may be given in degrees, minutes and seconds
+	for _, key := range []string{"latitude", "longitude"} {
+		if value, ok := viper.Get(key).(string); ok {
+			coordinate, err := ParseCoordinate(value, key)
+			if err != nil {
+				return nil, err
+			}
+			viper.Set(key, coordinate)
+		}
+	}
+
+	normalizeOutputs()
+
+	err = NormalizeDurations()
+	if err != nil {
+		return nil, err
+	}
+
+	var configuration Configuration
+	err = viper.Unmarshal(&configuration)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode config into struct, %s", err)
+	}
+
+	if configuration.HomeAssistant.Enabled() {
+		location, err := configuration.HomeAssistant.Location(context.Background())
+		if err != nil {
+			if !viper.IsSet("latitude") || !viper.IsSet("longitude") {
+				return nil, err
+			}
+			log.WithFields(log.Fields{
+				"op":    "config.LoadConfiguration",
+				"error": err,
+			}).Warn("failed to read location from Home Assistant, using the configured one")
+		} else {
+			configuration.Latitude, configuration.Longitude = location.Latitude, location.Longitude
+		}
+	}
+
+	err = configuration.Prepare()
+	if err != nil {
+		return nil, err
+	}
+	return &configuration, nil
+}
+
+// Prepare validates a configuration and resolves what is derived from its
+// settings: the parsed countdowns, thresholds and rules, the time zone of
+// localTime and the holiday calendar. LoadConfiguration prepares the
+// configurations it returns; one built in code is prepared before use.
+func (config *Configuration) Prepare() error {
+	err := config.Validate()
+	if err != nil {
+		return err
+	}
+
+	config.countdowns, err = ParseCountdowns(config.Countdowns)
+	if err != nil {
+		return err
+	}
+
+	config.thresholds, err = ParseThresholds(config.Thresholds)
+	if err != nil {
+		return err
+	}
+
+	config.rules, err = ParseRules(config.Rules, config.Notifiers)
+	if err != nil {
+		return err
+	}
+	if config.Digest.Enabled() {
+		err = config.Digest.Validate(config.Notifiers)
+		if err != nil {
+			return err
+		}
+	}
+
+	if config.LocalTime.Enabled() {
+		err = config.LocalTime.load()
+		if err != nil {
+			return err
+		}
+	}
+
+	if config.Calendar.Enabled() {
+		err = config.Calendar.load()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Validate checks the configuration for values that cannot work
+func (config Configuration) Validate() error {
+	err := ValidateCoordinates(config.Latitude, config.Longitude)
+	if err != nil {
+		return err
+	}
+	err = ValidateAltitude(config.Altitude)
+	if err != nil {
+		return err
+	}
+	err = config.Calendar.Validate()
+	if err != nil {
+		return err
+	}
+	if config.WakingHours.Enabled() {
+		err := config.WakingHours.Validate()
+		if err != nil {
+			return err
+		}
+	}
+	err = config.ColorTemperature.Validate()
+	if err != nil {
+		return err
+	}
+	if config.Exposure.Enabled {
+		err = config.Exposure.Validate()
+		if err != nil {
+			return err
+		}
+	}
+	for i, hook := range config.Hooks {
+		if len(hook.Command) == 0 {
+			return fmt.Errorf("hook %d has no command", i+1)
+		}
+	}
+	err = config.FIFO.Validate()
+	if err != nil {
+		return err
+	}
+	err = config.GoldenHour.Validate("goldenHour")
+	if err != nil {
+		return err
+	}
+	err = config.BlueHour.Validate("blueHour")
+	if err != nil {
+		return err
+	}
+	err = config.LightSensor.Validate()
+	if err != nil {
+		return err
+	}
+	err = config.DynamicTags.Validate()
+	if err != nil {
+		return err
+	}
+	err = config.OverrunPolicy.Validate()
+	if err != nil {
+		return err
+	}
+	err = config.WriteMode.Validate()
+	if err != nil {
+		return err
+	}
+	err = config.HTTP.Validate()
+	if err != nil {
+		return err
+	}
+	if config.Heartbeat < 0 {
+		return fmt.Errorf("heartbeat must not be negative")
+	}
+	if config.LowPower && config.WriteMode != WriteTransitions {
+		return fmt.Errorf("lowPower needs writeMode: transitions, since every sample is written otherwise")
+	}
+	if config.PollInterval < MinPollInterval {
+		return fmt.Errorf("pollInterval must be at least %s, e.g. 30s or 5m, got %s", FormatDuration(MinPollInterval), config.PollInterval)
+	}
+	if config.RecomputeInterval < 0 {
+		return fmt.Errorf("recomputeInterval must not be negative")
+	}
+	if config.InfluxDB.Version < 0 || config.InfluxDB.Version > InfluxDB3Version {
+		return fmt.Errorf("unsupported influxDB.version %d, must be 1, 2 or 3", config.InfluxDB.Version)
+	}
+	err = config.InfluxDB.ValidateFlushInterval()
+	if err != nil {
+		return err
+	}
+	err = ValidateLocationGroups(config.LocationGroups)
+	if err != nil {
+		return err
+	}
+	err = ValidateLocations(config.LocationName, config.Locations)
+	if err != nil {
+		return err
+	}
+	err = config.InfluxDB.ErrorLog.Validate()
+	if err != nil {
+		return err
+	}
+	err = config.Spool.Validate()
+	if err != nil {
+		return err
+	}
+	err = config.Memory.Validate()
+	if err != nil {
+		return err
+	}
+	err = config.GRPC.Validate()
+	if err != nil {
+		return err
+	}
+	if config.BackfillToday && config.GapFill.Enabled {
+		return fmt.Errorf("backfillToday and gapFill.enabled write overlapping samples, enable only one")
+	}
+	if config.GapFill.MaxGap < 0 {
+		return fmt.Errorf("gapFill.maxGap must not be negative")
+	}
+	if config.CrossCheck.Threshold < 0 {
+		return fmt.Errorf("crossCheck.threshold must not be negative")
+	}
+	err = config.Role.Validate()
+	if err != nil {
+		return err
+	}
+	err = config.validateOutputs()
+	if err != nil {
+		return err
+	}
+	err = config.SupplementalLight.Validate()
+	if err != nil {
+		return err
+	}
+	err = config.Forecast.Validate()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type InfluxWriteConfigError struct{}
+
+func (r *InfluxWriteConfigError) Error() string {
+	return "must configure at least one of bucket or database/retention policy"
+}
+
+// InfluxWriteDestination returns the bucket, or database/retention policy for
+// InfluxDB v1, that points are written to
+func InfluxWriteDestination(config *Configuration) (string, error) {
+	if config.InfluxDB.Version == InfluxDB3Version {
+		if config.InfluxDB.Database == "" {
+			return "", fmt.Errorf("must configure database for InfluxDB 3")
+		}
+		return config.InfluxDB.Database, nil
+	}
+	if config.InfluxDB.Bucket != "" {
+		return config.InfluxDB.Bucket, nil
+	} else if config.InfluxDB.Database != "" && config.InfluxDB.RetentionPolicy != "" {
+		return fmt.Sprintf("%s/%s", config.InfluxDB.Database, config.InfluxDB.RetentionPolicy), nil
+	}
+	return "", &InfluxWriteConfigError{}
+}
+
+// DefaultRecentSamples is how many samples /v1/recent keeps unless
+// http.recent says otherwise
+const DefaultRecentSamples = 100
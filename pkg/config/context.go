@@ -0,0 +1,40 @@
+package config
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	log "github.com/sirupsen/logrus"
+)
+
+type contextKey int
+
+const requestIDKey contextKey = iota
+
+// NewRequestID returns a random ID correlating the logs of one poll iteration
+// or HTTP request
+func NewRequestID() string {
+	var id [8]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// WithRequestID returns a context carrying the request ID id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the request ID carried by ctx, if any
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// Logger returns a log entry carrying the request ID of ctx as the
+// request_id field
+func Logger(ctx context.Context) *log.Entry {
+	if id := RequestID(ctx); id != "" {
+		return log.WithField("request_id", id)
+	}
+	return log.NewEntry(log.StandardLogger())
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ParseCoordinate converts a latitude or longitude given as decimal degrees
+// ("-97.7322", "97.7322W") or degrees, minutes and seconds ("30°16'56\"N",
+// "30 16 56 N", "97:43:56W") to signed decimal degrees. axis is either
+// "latitude" or "longitude" and decides which hemisphere letters are allowed.
+func ParseCoordinate(value string, axis string) (float64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	if s == "" {
+		return 0, fmt.Errorf("empty %s", axis)
+	}
+
+	hemispheres := "NS"
+	if axis == "longitude" {
+		hemispheres = "EW"
+	}
+	sign := 1.0
+	hemisphere := ""
+	if last := s[len(s)-1:]; strings.ContainsAny(last, "NSEW") {
+		hemisphere, s = last, s[:len(s)-1]
+	} else if first := s[:1]; strings.ContainsAny(first, "NSEW") {
+		hemisphere, s = first, s[1:]
+	}
+	if hemisphere != "" {
+		if !strings.Contains(hemispheres, hemisphere) {
+			return 0, fmt.Errorf("invalid %s %q, hemisphere must be one of %s", axis, value, hemispheres)
+		}
+		if hemisphere == "S" || hemisphere == "W" {
+			sign = -1
+		}
+	}
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		if hemisphere != "" {
+			return 0, fmt.Errorf("invalid %s %q, use either a sign or a hemisphere", axis, value)
+		}
+		if s[0] == '-' {
+			sign = -1
+		}
+		s = s[1:]
+	}
+
+	parts := strings.FieldsFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune("°º'′\"″:", r)
+	})
+	if len(parts) == 0 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid %s %q, expected decimal degrees or degrees, minutes and seconds", axis, value)
+	}
+
+	var degrees float64
+	for i, part := range parts {
+		number, err := strconv.ParseFloat(part, 64)
+		if err != nil || number < 0 {
+			return 0, fmt.Errorf("invalid %s %q, %q is not a number", axis, value, part)
+		}
+		if i > 0 && number >= 60 {
+			return 0, fmt.Errorf("invalid %s %q, minutes and seconds must be below 60", axis, value)
+		}
+		if i < len(parts)-1 && number != float64(int(number)) {
+			return 0, fmt.Errorf("invalid %s %q, only the last component may have decimals", axis, value)
+		}
+		switch i {
+		case 0:
+			degrees = number
+		case 1:
+			degrees += number / 60
+		case 2:
+			degrees += number / 3600
+		}
+	}
+
+	return sign * degrees, nil
+}
+
+// ValidateCoordinates checks that a latitude and longitude are in range
+func ValidateCoordinates(latitude, longitude float64) error {
+	if latitude < -90 || latitude > 90 {
+		return fmt.Errorf("latitude %f out of range [-90, 90]", latitude)
+	}
+	if longitude < -180 || longitude > 180 {
+		return fmt.Errorf("longitude %f out of range [-180, 180]", longitude)
+	}
+	return nil
+}
+
+// ValidateAltitude checks that an observer altitude in meters is plausible
+func ValidateAltitude(altitude float64) error {
+	if altitude < -500 || altitude > 9000 {
+		return fmt.Errorf("altitude %g out of range [-500, 9000]", altitude)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseCoordinate(t *testing.T) {
+	tests := []struct {
+		value string
+		axis  string
+		want  float64
+		// err is part of the error expected instead
+		err string
+	}{
+		{"30.2822", "latitude", 30.2822, ""},
+		{"-97.7322", "longitude", -97.7322, ""},
+		{"+97.7322", "longitude", 97.7322, ""},
+		{" 97.7322W ", "longitude", -97.7322, ""},
+		{"97.7322e", "longitude", 97.7322, ""},
+		{"S33.8688", "latitude", -33.8688, ""},
+		{`30°16'56"N`, "latitude", 30 + 16.0/60 + 56.0/3600, ""},
+		{"30°16′56″S", "latitude", -(30 + 16.0/60 + 56.0/3600), ""},
+		{"30 16 56 N", "latitude", 30 + 16.0/60 + 56.0/3600, ""},
+		{"97:43:56W", "longitude", -(97 + 43.0/60 + 56.0/3600), ""},
+		{"W 97 43 56", "longitude", -(97 + 43.0/60 + 56.0/3600), ""},
+		{"-97 43 56", "longitude", -(97 + 43.0/60 + 56.0/3600), ""},
+		{"30 16.5N", "latitude", 30 + 16.5/60, ""},
+		{"30 16 56.4", "latitude", 30 + 16.0/60 + 56.4/3600, ""},
+		{"", "latitude", 0, "empty latitude"},
+		{"30.2822E", "latitude", 0, "hemisphere must be one of NS"},
+		{"97.7322N", "longitude", 0, "hemisphere must be one of EW"},
+		{"-30.2822S", "latitude", 0, "use either a sign or a hemisphere"},
+		{"30 16 56 12", "latitude", 0, "expected decimal degrees or degrees, minutes and seconds"},
+		{"N", "latitude", 0, "expected decimal degrees or degrees, minutes and seconds"},
+		{"thirty", "latitude", 0, `"THIRTY" is not a number`},
+		{"30 60 0", "latitude", 0, "minutes and seconds must be below 60"},
+		{"30 16 60", "latitude", 0, "minutes and seconds must be below 60"},
+		{"30.5 16", "latitude", 0, "only the last component may have decimals"},
+	}
+	for _, test := range tests {
+		t.Run(test.axis+" "+test.value, func(t *testing.T) {
+			got, err := ParseCoordinate(test.value, test.axis)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want one containing %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(got-test.want) > 1e-9 {
+				t.Errorf("ParseCoordinate(%q, %s) = %g, want %g", test.value, test.axis, got, test.want)
+			}
+		})
+	}
+}
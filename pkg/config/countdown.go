@@ -0,0 +1,66 @@
+package config
+
+import (
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Countdown is a named field counting the seconds until a sun event plus an
+// offset, e.g. pool_pump_start = sunrise+2h
+type Countdown = daylight.Countdown
+
+// ParseCountdown parses an anchor such as sunrise, sunset-30m or
+// civil_dusk+1h15m
+func ParseCountdown(name, anchor string) (Countdown, error) {
+	anchor = strings.ReplaceAll(anchor, " ", "")
+	event, offset := anchor, ""
+	if i := strings.IndexAny(anchor, "+-"); i >= 0 {
+		event, offset = anchor[:i], anchor[i:]
+	}
+
+	for _, field := range daylight.SampleFields {
+		if field == name {
+			return Countdown{}, fmt.Errorf("invalid countdown %s, the name is taken by a built-in field", name)
+		}
+	}
+	countdown := Countdown{Name: name, Event: strings.ToLower(event)}
+	valid := false
+	for _, e := range daylight.CountdownEvents {
+		valid = valid || e == countdown.Event
+	}
+	if !valid {
+		return Countdown{}, fmt.Errorf("invalid countdown %s, unknown sun event %q, expected one of %s", name, event, strings.Join(daylight.CountdownEvents, ", "))
+	}
+	if offset != "" {
+		var err error
+		countdown.Offset, err = time.ParseDuration(offset)
+		if err != nil {
+			return Countdown{}, fmt.Errorf("invalid countdown %s, offset %q, %s", name, offset, err)
+		}
+	}
+	return countdown, nil
+}
+
+// ParseCountdowns parses the configured countdowns, sorted by name
+func ParseCountdowns(anchors map[string]string) ([]Countdown, error) {
+	countdowns := make([]Countdown, 0, len(anchors))
+	for name, anchor := range anchors {
+		countdown, err := ParseCountdown(name, anchor)
+		if err != nil {
+			return nil, err
+		}
+		countdowns = append(countdowns, countdown)
+	}
+	sort.Slice(countdowns, func(i, j int) bool {
+		return countdowns[i].Name < countdowns[j].Name
+	})
+	return countdowns, nil
+}
+
+// ParsedCountdowns returns the countdowns parsed from Countdowns while loading
+func (config Configuration) ParsedCountdowns() []Countdown {
+	return config.countdowns
+}
@@ -0,0 +1,13 @@
+package config
+
+import (
+	"time"
+)
+
+// CrossCheck compares the sunrise and sunset used for samples, from go-sunrise
+// or the ephemeris file, against the NOAA equations of the solar position
+// engine, so that a regression in either shows up in production
+type CrossCheck struct {
+	Enabled   bool
+	Threshold time.Duration
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"time"
+)
+
+// DaylightOptions returns the options of the daylight computation of the
+// configured location, with the hooks of the enabled calendar, waking hours,
+// color temperature and exposure models
+func (config Configuration) DaylightOptions() daylight.Options {
+	opts := daylight.Options{
+		Latitude:             config.Latitude,
+		Longitude:            config.Longitude,
+		Altitude:             config.Altitude,
+		TimeOffset:           config.TimeOffset,
+		Fields:               config.Fields,
+		EphemerisFile:        config.EphemerisFile,
+		RecomputeInterval:    config.RecomputeInterval,
+		ExactTransitionTimes: config.ExactTransitionTimes,
+		TransitionPoints:     config.TransitionPoints,
+		Aviation:             config.Aviation,
+		CrossCheck:           config.CrossCheck.Enabled,
+		CrossCheckThreshold:  config.CrossCheck.Threshold,
+		Thresholds:           config.ParsedThresholds(),
+		Countdowns:           config.ParsedCountdowns(),
+		GoldenHour:           config.GoldenHour,
+		BlueHour:             config.BlueHour,
+		SupplementalLight: daylight.SupplementalLight{
+			TargetPhotoperiod: config.SupplementalLight.TargetPhotoperiod,
+			Schedule:          config.SupplementalLight.Schedule,
+		},
+		ForecastDays:    config.Forecast.Days,
+		LocalTimeFormat: config.LocalTime.Format,
+		Tags:            map[string]string{},
+		SampleTags:      map[string]string{},
+	}
+	if config.TagCoordinates {
+		opts.Tags = config.CoordinateTags()
+	}
+	for key, value := range config.PlaceTags() {
+		opts.Tags[key] = value
+	}
+	for key, value := range config.LocationTags() {
+		opts.SampleTags[key] = value
+	}
+	if config.LocationName != "" {
+		opts.SampleTags["location"] = config.LocationName
+	}
+	if config.LocalTime.Enabled() {
+		opts.LocalTime = config.LocalTime.Location()
+	}
+	if config.Calendar.Enabled() {
+		opts.DayType = config.Calendar.DayType
+	}
+	if config.Calendar.WorkingHours.Enabled() {
+		opts.WorkingDaylight = config.Calendar.WorkingDaylight
+	}
+	if config.WakingHours.Enabled() {
+		wakingHours := config.WakingHours
+		opts.WakingDaylight = func(sunrise, sunset time.Time, polarDay bool, t time.Time) time.Duration {
+			return WakingDaylight(wakingHours, sunrise, sunset, polarDay, t)
+		}
+	}
+	if config.ColorTemperature.Enabled() {
+		opts.ColorTemperature = config.ColorTemperature.Kelvin
+	}
+	if config.Exposure.Enabled {
+		opts.Exposure = config.Exposure.Hint
+	}
+	return opts
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Digest sends a daily summary of the day's sun events and the exporter's
+// health to notifiers at a local time of day
+type Digest struct {
+	At       string
+	Timezone string
+	Notify   []string
+}
+
+func (d Digest) Enabled() bool {
+	return d.At != ""
+}
+
+func (d Digest) Validate(notifiers map[string]Notifier) error {
+	if _, err := time.Parse("15:04", d.At); err != nil {
+		return fmt.Errorf("invalid digest time %q, expected HH:MM", d.At)
+	}
+	if _, err := d.Location(); err != nil {
+		return err
+	}
+	for _, name := range d.Notify {
+		if _, ok := notifiers[strings.ToLower(name)]; !ok {
+			return fmt.Errorf("digest refers to unknown notifier %s", name)
+		}
+	}
+	return nil
+}
+
+func (d Digest) Location() (*time.Location, error) {
+	if d.Timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(d.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid digest timezone %s, %s", d.Timezone, err)
+	}
+	return loc, nil
+}
+
+// Next returns the first digest time after now
+func (d Digest) Next(now time.Time, loc *time.Location) time.Time {
+	at, _ := time.Parse("15:04", d.At)
+	local := now.In(loc)
+	t := time.Date(local.Year(), local.Month(), local.Day(), at.Hour(), at.Minute(), 0, 0, loc)
+	if !t.After(now) {
+		t = time.Date(local.Year(), local.Month(), local.Day()+1, at.Hour(), at.Minute(), 0, 0, loc)
+	}
+	return t
+}
@@ -0,0 +1,29 @@
+package config
+
+// Display configures the summary image for e-ink dashboards
+type Display struct {
+	Output    string
+	Format    string
+	Width     int
+	Height    int
+	Interval  uint
+	TrendDays int
+	Timezone  string
+}
+
+// DisplayDefaults fills in the size, format and trend length when unset
+func DisplayDefaults(display Display) Display {
+	if display.Width == 0 {
+		display.Width = 800
+	}
+	if display.Height == 0 {
+		display.Height = 480
+	}
+	if display.Format == "" {
+		display.Format = "png"
+	}
+	if display.TrendDays == 0 {
+		display.TrendDays = 30
+	}
+	return display
+}
@@ -0,0 +1,4 @@
+// Package config holds the configuration of daylight-timeseries: the
+// settings LoadConfiguration reads from a YAML file, or a program builds in
+// code starting from NewConfiguration, and their validation by Prepare.
+package config
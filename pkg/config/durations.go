@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LegacyDurations maps duration options to the unit bare integers were given
+// in before the configuration moved to duration strings such as 30s or 5m
+var LegacyDurations = map[string]time.Duration{
+	"pollInterval":               time.Second,
+	"timeOffset":                 time.Minute,
+	"influxDB.flushInterval":     time.Second,
+	"influxDB.verifyInterval":    time.Second,
+	"status.maxAge":              time.Second,
+	"display.interval":           time.Second,
+	"lightingSchedule.onOffset":  time.Minute,
+	"lightingSchedule.offOffset": time.Minute,
+}
+
+// DurationKeys lists the options of LegacyDurations that are stored as
+// time.Duration rather than in their legacy unit
+var DurationKeys = map[string]bool{
+	"pollInterval":           true,
+	"timeOffset":             true,
+	"influxDB.flushInterval": true,
+}
+
+// LegacyDurationKeys returns the keys of LegacyDurations in a stable order
+func LegacyDurationKeys() []string {
+	keys := make([]string, 0, len(LegacyDurations))
+	for key := range LegacyDurations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// NormalizeDurations converts duration strings in the loaded configuration to
+// the integer units the options are stored in, or bare integers to durations
+// for the options of DurationKeys, and warns about options still given as
+// bare integers, which keep their legacy unit
+func NormalizeDurations() error {
+	for _, key := range LegacyDurationKeys() {
+		unit := LegacyDurations[key]
+		value := viper.Get(key)
+		if value == nil {
+			continue
+		}
+		// Environment variables are always strings, so bare integers may
+		// arrive either way; zero means the same in any unit
+		text := strings.TrimSpace(fmt.Sprint(value))
+		if number, err := strconv.ParseInt(text, 10, 64); err == nil {
+			if number != 0 {
+				warnLegacyDuration(key, unit)
+			}
+			if DurationKeys[key] {
+				viper.Set(key, time.Duration(number)*unit)
+			}
+			continue
+		}
+		d, err := time.ParseDuration(text)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q for %s, %s", text, key, err)
+		}
+		if DurationKeys[key] {
+			viper.Set(key, d)
+			continue
+		}
+		if d%unit != 0 {
+			return fmt.Errorf("invalid duration %q for %s, must be a whole number of %s", text, key, unitName(unit))
+		}
+		viper.Set(key, int64(d/unit))
+	}
+	return nil
+}
+
+func warnLegacyDuration(key string, unit time.Duration) {
+	log.WithFields(log.Fields{
+		"op":   "config.LoadConfiguration",
+		"key":  key,
+		"unit": unitName(unit),
+	}).Warn("bare integer durations are deprecated, use a duration string such as 30s or 5m; run the migrate-config command to rewrite the configuration")
+}
+
+func unitName(unit time.Duration) string {
+	if unit == time.Minute {
+		return "minutes"
+	}
+	return "seconds"
+}
+
+// FormatDuration formats d like time.Duration.String without the zero
+// trailing units, e.g. 5m rather than 5m0s
+func FormatDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loadYAML loads a configuration file holding text
+func loadYAML(t *testing.T, text string) (*Configuration, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte("latitude: 30.2822\nlongitude: -97.7322\n"+text), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	return LoadConfiguration(path)
+}
+
+func TestPollIntervalUnits(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+		// err is part of the error expected instead
+		err string
+	}{
+		{"milliseconds", "1500ms", 1500 * time.Millisecond, ""},
+		{"seconds", "30s", 30 * time.Second, ""},
+		{"minutes", "5m", 5 * time.Minute, ""},
+		{"hours", "1h", time.Hour, ""},
+		{"mixed units", "1h30m", 90 * time.Minute, ""},
+		{"bare integer in seconds", "30", 30 * time.Second, ""},
+		{"quoted bare integer in seconds", `"30"`, 30 * time.Second, ""},
+		{"default", "", DefaultPollInterval, ""},
+		{"below the minimum", "500ms", 0, "pollInterval must be at least 1s"},
+		{"unknown unit", "30x", 0, `invalid duration "30x" for pollInterval`},
+		{"words", "five minutes", 0, `invalid duration "five minutes" for pollInterval`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			text := ""
+			if test.value != "" {
+				text = "pollInterval: " + test.value + "\n"
+			}
+			cfg, err := loadYAML(t, text)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want one containing %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.PollInterval != test.want {
+				t.Errorf("pollInterval %s loaded as %s, want %s", test.value, cfg.PollInterval, test.want)
+			}
+		})
+	}
+}
+
+func TestLegacyMinuteUnits(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+		err   string
+	}{
+		{"minutes", "30m", 30 * time.Minute, ""},
+		{"hours", "1h", time.Hour, ""},
+		{"seconds", "90s", 90 * time.Second, ""},
+		{"negative", "-15m", -15 * time.Minute, ""},
+		{"bare integer in minutes", "45", 45 * time.Minute, ""},
+		{"zero", "0", 0, ""},
+		{"unknown unit", "30x", 0, `invalid duration "30x" for timeOffset`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg, err := loadYAML(t, "timeOffset: "+test.value+"\n")
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want one containing %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.TimeOffset != test.want {
+				t.Errorf("timeOffset %s loaded as %s, want %s", test.value, cfg.TimeOffset, test.want)
+			}
+		})
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0s"},
+		{500 * time.Millisecond, "500ms"},
+		{30 * time.Second, "30s"},
+		{90 * time.Second, "1m30s"},
+		{5 * time.Minute, "5m"},
+		{time.Hour, "1h"},
+		{90 * time.Minute, "1h30m"},
+		{time.Hour + 5*time.Second, "1h0m5s"},
+		{-30 * time.Minute, "-30m"},
+	}
+	for _, test := range tests {
+		t.Run(test.want, func(t *testing.T) {
+			if got := FormatDuration(test.d); got != test.want {
+				t.Errorf("FormatDuration(%d) = %s, want %s", test.d, got, test.want)
+			}
+		})
+	}
+}
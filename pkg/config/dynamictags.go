@@ -0,0 +1,116 @@
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// DynamicTags reads tags to attach to every written point from a JSON object
+// served at URL or stored in File, e.g. {"phase": "pilot", "experiment":
+// "42"}, at startup and every RefreshInterval. Numbers and booleans become
+// their text; tags the exporter sets itself, such as location, win.
+type DynamicTags struct {
+	URL             string
+	File            string
+	Headers         map[string]string
+	RefreshInterval time.Duration
+	Timeout         time.Duration
+	SkipVerifySsl   bool
+}
+
+func (d DynamicTags) Enabled() bool {
+	return d.URL != "" || d.File != ""
+}
+
+func (d DynamicTags) Validate() error {
+	if d.URL != "" && d.File != "" {
+		return fmt.Errorf("dynamicTags must set only one of url or file")
+	}
+	if d.RefreshInterval < 0 || d.Timeout < 0 {
+		return fmt.Errorf("dynamicTags.refreshInterval and dynamicTags.timeout must not be negative")
+	}
+	return nil
+}
+
+// Fetch reads the current tags
+func (d DynamicTags) Fetch(ctx context.Context) (map[string]string, error) {
+	var data []byte
+	var err error
+	if d.File != "" {
+		data, err = os.ReadFile(d.File)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read dynamic tags, %s", err)
+		}
+	} else {
+		data, err = d.get(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var values map[string]interface{}
+	err = json.Unmarshal(data, &values)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode dynamic tags, %s", err)
+	}
+	tags := make(map[string]string, len(values))
+	for key, value := range values {
+		switch v := value.(type) {
+		case nil:
+		case string:
+			if v != "" {
+				tags[key] = v
+			}
+		case float64:
+			tags[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			tags[key] = strconv.FormatBool(v)
+		default:
+			return nil, fmt.Errorf("unable to decode dynamic tags, %s is not a string, number or boolean", key)
+		}
+	}
+	return tags, nil
+}
+
+func (d DynamicTags) get(ctx context.Context) ([]byte, error) {
+	timeout := d.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create dynamic tags request, %s", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	for name, value := range d.Headers {
+		req.Header.Set(name, value)
+	}
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: d.SkipVerifySsl},
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch dynamic tags, %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch dynamic tags, %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch dynamic tags, %s", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// SMTPNotifier delivers notifications as email. TLS is "starttls" (the
+// default), "tls" for implicit TLS, usually on port 465, or "none"; Subject
+// and Body are text/template templates executed with the notification.
+type SMTPNotifier struct {
+	Server             string
+	Username           string
+	Password           string
+	From               string
+	To                 []string
+	TLS                string
+	InsecureSkipVerify bool
+	Subject            string
+	Body               string
+}
+
+const (
+	defaultEmailSubject = "daylight-timeseries: {{.Rule}}"
+	defaultEmailBody    = "{{.Message}}\n\nTime: {{.Time.Format \"2006-01-02T15:04:05Z07:00\"}}\n{{if .RequestID}}Request ID: {{.RequestID}}\n{{end}}"
+)
+
+func (s SMTPNotifier) Enabled() bool {
+	return s.Server != ""
+}
+
+func (s SMTPNotifier) Validate(name string) error {
+	if _, _, err := net.SplitHostPort(s.Server); err != nil {
+		return fmt.Errorf("invalid smtp server of notifier %s, expected host:port, %s", name, err)
+	}
+	if s.From == "" || len(s.To) == 0 {
+		return fmt.Errorf("smtp notifier %s must set from and to", name)
+	}
+	switch s.TLS {
+	case "", "starttls", "tls", "none":
+	default:
+		return fmt.Errorf("invalid smtp tls mode %q of notifier %s, expected starttls, tls or none", s.TLS, name)
+	}
+	if _, _, err := s.templates(); err != nil {
+		return fmt.Errorf("invalid template of notifier %s, %s", name, err)
+	}
+	return nil
+}
+
+func (s SMTPNotifier) templates() (*template.Template, *template.Template, error) {
+	subject, body := s.Subject, s.Body
+	if subject == "" {
+		subject = defaultEmailSubject
+	}
+	if body == "" {
+		body = defaultEmailBody
+	}
+	subjectTemplate, err := template.New("subject").Parse(subject)
+	if err != nil {
+		return nil, nil, err
+	}
+	bodyTemplate, err := template.New("body").Parse(body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return subjectTemplate, bodyTemplate, nil
+}
+
+// message renders the notification as an RFC 5322 message
+func (s SMTPNotifier) message(notification Notification) ([]byte, error) {
+	subjectTemplate, bodyTemplate, err := s.templates()
+	if err != nil {
+		return nil, err
+	}
+	var subject, body bytes.Buffer
+	if err = subjectTemplate.Execute(&subject, notification); err != nil {
+		return nil, fmt.Errorf("unable to render email subject, %s", err)
+	}
+	if err = bodyTemplate.Execute(&body, notification); err != nil {
+		return nil, fmt.Errorf("unable to render email body, %s", err)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.To, ", "))
+	// Header lines must not contain line breaks
+	fmt.Fprintf(&msg, "Subject: %s\r\n", strings.Join(strings.Fields(subject.String()), " "))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(strings.ReplaceAll(body.String(), "\r\n", "\n"), "\n", "\r\n"))
+	return msg.Bytes(), nil
+}
+
+// Send emails a notification, giving up when ctx is done
+func (s SMTPNotifier) Send(ctx context.Context, notification Notification) error {
+	msg, err := s.message(notification)
+	if err != nil {
+		return err
+	}
+	host, _, _ := net.SplitHostPort(s.Server)
+	tlsConfig := &tls.Config{ServerName: host, InsecureSkipVerify: s.InsecureSkipVerify}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.Server)
+	if err != nil {
+		return fmt.Errorf("unable to connect to smtp server %s, %s", s.Server, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if s.TLS == "tls" {
+		conn = tls.Client(conn, tlsConfig)
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("unable to connect to smtp server %s, %s", s.Server, err)
+	}
+	defer client.Close()
+
+	if s.TLS == "" || s.TLS == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("smtp server %s does not support STARTTLS", s.Server)
+		}
+		if err = client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("unable to start TLS with smtp server %s, %s", s.Server, err)
+		}
+	}
+	if s.Username != "" {
+		if err = client.Auth(smtp.PlainAuth("", s.Username, s.Password, host)); err != nil {
+			return fmt.Errorf("unable to authenticate with smtp server %s, %s", s.Server, err)
+		}
+	}
+	if err = client.Mail(s.From); err != nil {
+		return fmt.Errorf("smtp server %s rejected sender %s, %s", s.Server, s.From, err)
+	}
+	for _, to := range s.To {
+		if err = client.Rcpt(to); err != nil {
+			return fmt.Errorf("smtp server %s rejected recipient %s, %s", s.Server, to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("unable to send email, %s", err)
+	}
+	if _, err = w.Write(msg); err != nil {
+		return fmt.Errorf("unable to send email, %s", err)
+	}
+	if err = w.Close(); err != nil {
+		return fmt.Errorf("unable to send email, %s", err)
+	}
+	return client.Quit()
+}
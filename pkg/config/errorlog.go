@@ -0,0 +1,36 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// ErrorLog limits how often a repeating error is logged. Errors spend tokens
+// from a bucket of Burst tokens refilled one per Interval, an error identical
+// to the previous logged one is not logged again, and the errors suppressed
+// either way are summarized every SummaryInterval.
+type ErrorLog struct {
+	Burst           int
+	Interval        time.Duration
+	SummaryInterval time.Duration
+}
+
+func (e ErrorLog) WithDefaults() ErrorLog {
+	if e.Burst == 0 {
+		e.Burst = 5
+	}
+	if e.Interval == 0 {
+		e.Interval = time.Minute
+	}
+	if e.SummaryInterval == 0 {
+		e.SummaryInterval = 10 * time.Minute
+	}
+	return e
+}
+
+func (e ErrorLog) Validate() error {
+	if e.Burst < 0 || e.Interval < 0 || e.SummaryInterval < 0 {
+		return fmt.Errorf("influxDB.errorLog values must not be negative")
+	}
+	return nil
+}
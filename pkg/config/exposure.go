@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"math"
+)
+
+// Exposure configures exposure hints for timelapse camera controllers: the
+// exposure_bias field, the exposure compensation in EV, and exposure_iso
+// follow Curve by solar elevation, interpolated linearly between its points
+// and held beyond its ends
+type Exposure struct {
+	Enabled bool
+	Curve   []ExposurePoint
+}
+
+// ExposurePoint is the exposure hinted at a solar elevation in degrees
+type ExposurePoint struct {
+	Elevation float64
+	Bias      float64
+	ISO       int
+}
+
+// DefaultExposureCurve darkens the exposure and raises the ISO from daylight
+// through sunset and the twilights, so night frames stay dark without
+// underexposing the stars
+var DefaultExposureCurve = []ExposurePoint{
+	{Elevation: -18, Bias: -2.5, ISO: 6400},
+	{Elevation: -12, Bias: -2, ISO: 3200},
+	{Elevation: -6, Bias: -1, ISO: 800},
+	{Elevation: 0, Bias: -0.3, ISO: 200},
+	{Elevation: 6, Bias: 0, ISO: 100},
+}
+
+func (e Exposure) curve() []ExposurePoint {
+	if len(e.Curve) == 0 {
+		return DefaultExposureCurve
+	}
+	return e.Curve
+}
+
+func (e Exposure) Validate() error {
+	curve := e.curve()
+	for i, point := range curve {
+		if point.Elevation < -90 || point.Elevation > 90 {
+			return fmt.Errorf("invalid exposure.curve elevation %g, must be between -90 and 90", point.Elevation)
+		}
+		if point.ISO <= 0 {
+			return fmt.Errorf("invalid exposure.curve iso %d at elevation %g, must be positive", point.ISO, point.Elevation)
+		}
+		if i > 0 && point.Elevation <= curve[i-1].Elevation {
+			return fmt.Errorf("exposure.curve elevations must increase, got %g after %g", point.Elevation, curve[i-1].Elevation)
+		}
+	}
+	return nil
+}
+
+// Hint returns the exposure bias in EV and the ISO hinted at a solar
+// elevation. The ISO is interpolated in stops, so halfway between 100 and
+// 400 is 200.
+func (e Exposure) Hint(elevation float64) (float64, int) {
+	curve := e.curve()
+	first, last := curve[0], curve[len(curve)-1]
+	if elevation <= first.Elevation {
+		return first.Bias, first.ISO
+	}
+	if elevation >= last.Elevation {
+		return last.Bias, last.ISO
+	}
+	i := 1
+	for curve[i].Elevation < elevation {
+		i++
+	}
+	low, high := curve[i-1], curve[i]
+	progress := (elevation - low.Elevation) / (high.Elevation - low.Elevation)
+	bias := low.Bias + progress*(high.Bias-low.Bias)
+	stops := math.Log2(float64(low.ISO)) + progress*(math.Log2(float64(high.ISO))-math.Log2(float64(low.ISO)))
+	return math.Round(bias*100) / 100, int(math.Round(math.Exp2(stops)))
+}
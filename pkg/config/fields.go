@@ -0,0 +1,37 @@
+package config
+
+import (
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"sort"
+)
+
+// FieldEnabled reports whether a field should be emitted; fields are enabled
+// unless explicitly set to false
+func (config Configuration) FieldEnabled(name string) bool {
+	enabled, ok := config.Fields[name]
+	return !ok || enabled
+}
+
+// UnknownFields returns configured field names that no Sample will carry
+func (config Configuration) UnknownFields() []string {
+	known := make(map[string]bool, len(daylight.SampleFields))
+	for _, name := range daylight.SampleFields {
+		known[name] = true
+	}
+	for name := range config.Countdowns {
+		known[name] = true
+	}
+	for name := range config.Thresholds {
+		known[name] = true
+	}
+
+	var unknown []string
+	for name := range config.Fields {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+
+	return unknown
+}
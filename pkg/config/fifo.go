@@ -0,0 +1,20 @@
+package config
+
+import (
+	"fmt"
+)
+
+// FIFO configures writing samples to a named pipe for local consumers
+type FIFO struct {
+	Path   string
+	Format string
+	Create bool
+}
+
+func (f FIFO) Validate() error {
+	switch f.Format {
+	case "", "line", "json":
+		return nil
+	}
+	return fmt.Errorf("unknown fifo format %q, expected line or json", f.Format)
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// FileOutput configures appending samples to a file, as line protocol or one
+// JSON object per line. Samples are written in batches of BatchSize, each
+// compressed as a gzip member or zstd frame of its own when Compression is
+// set and synced to disk, so that a power loss can only tear the last batch.
+// The file is rotated, by renaming it atomically to a name stamped with the
+// time, once it reaches RotateSize bytes or is RotateInterval old.
+type FileOutput struct {
+	Path           string
+	Format         string
+	Compression    string
+	BatchSize      int
+	RotateSize     int64
+	RotateInterval time.Duration
+}
+
+func (f FileOutput) Validate() error {
+	if f.Path == "" {
+		return fmt.Errorf("the file output needs file.path")
+	}
+	switch f.Format {
+	case "", "line", "json":
+	default:
+		return fmt.Errorf("unknown file format %q, expected line or json", f.Format)
+	}
+	switch f.Compression {
+	case "", "none", "gzip", "zstd":
+	default:
+		return fmt.Errorf("unknown file compression %q, expected gzip, zstd or none", f.Compression)
+	}
+	if f.BatchSize < 0 || f.RotateSize < 0 || f.RotateInterval < 0 {
+		return fmt.Errorf("file batchSize, rotateSize and rotateInterval must not be negative")
+	}
+	return nil
+}
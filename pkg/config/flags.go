@@ -0,0 +1,27 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// LatitudeFlag and LongitudeFlag hold the -lat and -lon flags, which override
+// the configured coordinates and, given together, make the configuration file
+// optional
+var LatitudeFlag, LongitudeFlag string
+
+// CoordinateFlagsSet reports whether both -lat and -lon were given
+func CoordinateFlagsSet() bool {
+	return LatitudeFlag != "" && LongitudeFlag != ""
+}
+
+// ConfigMissing reports whether the configuration file does not exist; a
+// remote source is never missing, failing to load instead
+func ConfigMissing(path string) bool {
+	if _, ok := ParseRemoteSource(path); ok {
+		return false
+	}
+	_, err := os.Stat(path)
+	return errors.Is(err, fs.ErrNotExist)
+}
@@ -0,0 +1,23 @@
+package config
+
+import (
+	"fmt"
+)
+
+// Forecast writes the sun events of the next Days solar dates, today
+// included, as one daylight_forecast point per date, so dashboards and rules
+// can refer to upcoming events
+type Forecast struct {
+	Days int
+}
+
+func (f Forecast) Enabled() bool {
+	return f.Days > 0
+}
+
+func (f Forecast) Validate() error {
+	if f.Days < 0 || f.Days > 366 {
+		return fmt.Errorf("forecast.days must be between 0 and 366")
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Geocode tags samples with the country, region and city of the location,
+// reverse geocoded once at startup through a Nominatim compatible API and
+// cached in CacheFile so that restarts do not query it again
+type Geocode struct {
+	Enabled   bool
+	URL       string
+	CacheFile string
+	Language  string
+	Timeout   time.Duration
+}
+
+// Place is the result of reverse geocoding a location; empty parts are not
+// tagged
+type Place struct {
+	Country string `json:"country,omitempty"`
+	Region  string `json:"region,omitempty"`
+	City    string `json:"city,omitempty"`
+}
+
+// Tags returns the place as sample tags
+func (p Place) Tags() map[string]string {
+	tags := map[string]string{}
+	for key, value := range map[string]string{"country": p.Country, "region": p.Region, "city": p.City} {
+		if value != "" {
+			tags[key] = value
+		}
+	}
+	return tags
+}
+
+// cacheKey identifies a location in the cache to about 10 m
+func (g Geocode) cacheKey(latitude, longitude float64) string {
+	return fmt.Sprintf("%.4f,%.4f", latitude, longitude)
+}
+
+func (g Geocode) readCache() map[string]Place {
+	cache := map[string]Place{}
+	if g.CacheFile == "" {
+		return cache
+	}
+	data, err := os.ReadFile(g.CacheFile)
+	if err == nil {
+		err = json.Unmarshal(data, &cache)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		log.WithFields(log.Fields{
+			"op":    "Geocode.Lookup",
+			"file":  g.CacheFile,
+			"error": err,
+		}).Warn("ignoring unreadable geocoding cache")
+	}
+	return cache
+}
+
+// Lookup returns the place of a location from the cache, or from the API,
+// adding it to the cache
+func (g Geocode) Lookup(ctx context.Context, latitude, longitude float64) (Place, error) {
+	cache := g.readCache()
+	key := g.cacheKey(latitude, longitude)
+	if place, ok := cache[key]; ok {
+		return place, nil
+	}
+
+	place, err := g.query(ctx, latitude, longitude)
+	if err != nil {
+		return Place{}, err
+	}
+	if g.CacheFile != "" {
+		cache[key] = place
+		data, _ := json.MarshalIndent(cache, "", "  ")
+		err = os.WriteFile(g.CacheFile, data, 0644)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"op":    "Geocode.Lookup",
+				"file":  g.CacheFile,
+				"error": err,
+			}).Warn("failed to write geocoding cache")
+		}
+	}
+	return place, nil
+}
+
+// query reverse geocodes a location with the API
+func (g Geocode) query(ctx context.Context, latitude, longitude float64) (Place, error) {
+	endpoint := g.URL
+	if endpoint == "" {
+		endpoint = "https://nominatim.openstreetmap.org/reverse"
+	}
+	language := g.Language
+	if language == "" {
+		language = "en"
+	}
+	timeout := g.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	query := url.Values{
+		"format":          {"jsonv2"},
+		"lat":             {fmt.Sprint(latitude)},
+		"lon":             {fmt.Sprint(longitude)},
+		"zoom":            {"10"},
+		"addressdetails":  {"1"},
+		"accept-language": {language},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return Place{}, fmt.Errorf("unable to create geocoding request, %s", err)
+	}
+	// Nominatim's usage policy requires an identifying user agent
+	req.Header.Set("User-Agent", "daylight-timeseries")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return Place{}, fmt.Errorf("unable to reverse geocode location, %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Place{}, fmt.Errorf("unable to reverse geocode location, %s", resp.Status)
+	}
+
+	var result struct {
+		Error   string            `json:"error"`
+		Address map[string]string `json:"address"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return Place{}, fmt.Errorf("unable to decode geocoding response, %s", err)
+	}
+	if result.Error != "" {
+		return Place{}, fmt.Errorf("unable to reverse geocode location, %s", result.Error)
+	}
+	place := Place{
+		Country: strings.ToUpper(result.Address["country_code"]),
+		Region:  firstOf(result.Address, "state", "region", "province", "county"),
+		City:    firstOf(result.Address, "city", "town", "village", "municipality"),
+	}
+	return place, nil
+}
+
+// firstOf returns the first of keys that is set in address
+func firstOf(address map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if value := address[key]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// ResolvePlace reverse geocodes the public coordinates of the configuration
+// into the tags of its samples, logging a warning and tagging nothing when
+// that fails
+func (config *Configuration) ResolvePlace(ctx context.Context) {
+	latitude, longitude := config.PublicCoordinates()
+	place, err := config.Geocode.Lookup(ctx, latitude, longitude)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"op":    "Geocode",
+			"error": err,
+		}).Warn("failed to reverse geocode location, not tagging samples with it")
+		config.placeTags = nil
+		return
+	}
+	config.placeTags = place.Tags()
+}
+
+// PlaceTags returns the tags of the place the location was geocoded to
+func (config Configuration) PlaceTags() map[string]string {
+	return config.placeTags
+}
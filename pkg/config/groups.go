@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+)
+
+// LocationGroup is a named fleet of locations whose daylight is aggregated
+// into one point per poll
+type LocationGroup = daylight.LocationGroup
+
+// GroupLocation is one member of a location group
+type GroupLocation = daylight.GroupLocation
+
+// ValidateLocationGroups checks that groups are named uniquely and their
+// locations are valid
+func ValidateLocationGroups(groups []LocationGroup) error {
+	names := map[string]bool{}
+	for i, group := range groups {
+		if group.Name == "" {
+			return fmt.Errorf("location group %d has no name", i+1)
+		}
+		if names[group.Name] {
+			return fmt.Errorf("location group %s is defined more than once", group.Name)
+		}
+		names[group.Name] = true
+		if len(group.Locations) == 0 {
+			return fmt.Errorf("location group %s has no locations", group.Name)
+		}
+		for j, location := range group.Locations {
+			err := ValidateCoordinates(location.Latitude, location.Longitude)
+			if err != nil {
+				name := location.Name
+				if name == "" {
+					name = fmt.Sprint(j + 1)
+				}
+				return fmt.Errorf("invalid location %s of group %s, %s", name, group.Name, err)
+			}
+		}
+	}
+	return nil
+}
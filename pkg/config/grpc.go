@@ -0,0 +1,35 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultGRPCNotice is how long before a sun event gRPC subscribers are
+// notified of it by default
+const DefaultGRPCNotice = 15 * time.Minute
+
+// GRPC configures the embedded gRPC server streaming daylight events to
+// subscribers; it serves TLS when CertFile and KeyFile are set and accepts
+// the same users, tokens and oauth2 options as the HTTP endpoint groups
+type GRPC struct {
+	Address   string
+	Network   string
+	Interface string
+	CertFile  string
+	KeyFile   string
+	// Notice is how long before a sun event subscribers are notified of it
+	// unless they ask otherwise
+	Notice time.Duration
+	Auth   HTTPAuth
+}
+
+func (g GRPC) Validate() error {
+	if (g.CertFile == "") != (g.KeyFile == "") {
+		return fmt.Errorf("grpc.certFile and grpc.keyFile must be set together")
+	}
+	if g.Notice < 0 {
+		return fmt.Errorf("grpc.notice must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HomeAssistant reads the location from a Home Assistant zone through its
+// REST API, at startup and every RefreshInterval
+type HomeAssistant struct {
+	URL             string
+	Token           string
+	Entity          string
+	RefreshInterval time.Duration
+	SkipVerifySsl   bool
+}
+
+// Location is a pair of coordinates in decimal degrees
+type Location struct {
+	Latitude  float64
+	Longitude float64
+}
+
+func (h HomeAssistant) Enabled() bool {
+	return h.URL != ""
+}
+
+// Location fetches the coordinates of the configured zone, zone.home unless
+// set otherwise
+func (h HomeAssistant) Location(ctx context.Context) (Location, error) {
+	entity := h.Entity
+	if entity == "" {
+		entity = "zone.home"
+	}
+	url := strings.TrimSuffix(h.URL, "/") + "/api/states/" + entity
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Location{}, fmt.Errorf("unable to create Home Assistant request, %s", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+h.Token)
+	req.Header.Set("Accept", "application/json")
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: h.SkipVerifySsl},
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Location{}, fmt.Errorf("unable to query Home Assistant, %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Location{}, fmt.Errorf("unable to read %s from Home Assistant, %s", entity, resp.Status)
+	}
+
+	var state struct {
+		Attributes struct {
+			Latitude  *float64 `json:"latitude"`
+			Longitude *float64 `json:"longitude"`
+		} `json:"attributes"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&state)
+	if err != nil {
+		return Location{}, fmt.Errorf("unable to decode Home Assistant state of %s, %s", entity, err)
+	}
+	if state.Attributes.Latitude == nil || state.Attributes.Longitude == nil {
+		return Location{}, fmt.Errorf("Home Assistant entity %s has no latitude and longitude", entity)
+	}
+	location := Location{Latitude: *state.Attributes.Latitude, Longitude: *state.Attributes.Longitude}
+	err = ValidateCoordinates(location.Latitude, location.Longitude)
+	if err != nil {
+		return Location{}, fmt.Errorf("invalid location of Home Assistant entity %s, %s", entity, err)
+	}
+	return location, nil
+}
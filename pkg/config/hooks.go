@@ -0,0 +1,14 @@
+package config
+
+import (
+	"time"
+)
+
+// Hook is an external command that enriches or transforms each sample before
+// it is written. The sample is passed as JSON on stdin and the command prints
+// the sample to write as JSON on stdout, adding, changing or removing fields
+// and tags as it likes.
+type Hook struct {
+	Command []string
+	Timeout time.Duration
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"fmt"
+)
+
+type HTTP struct {
+	Address   string
+	Network   string
+	Interface string
+	Recent    int
+	Health    HTTPAuth
+	Data      HTTPAuth
+	// Pprof serves the runtime profiles at /debug/pprof/ in the data group
+	Pprof bool
+}
+
+// Validate refuses to serve the profiles unauthenticated, since they expose
+// memory contents and the symbols of the binary
+func (h HTTP) Validate() error {
+	if h.Pprof && !h.Data.Enabled() {
+		return fmt.Errorf("http.pprof needs http.data to configure users, tokens or oauth2")
+	}
+	return nil
+}
@@ -0,0 +1,335 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ICSHolidays are the holidays of an iCalendar file: the dates of one-off
+// events and the yearly rules of recurring ones
+type ICSHolidays struct {
+	dates  map[string]bool
+	yearly []yearlyEvent
+}
+
+// yearlyEvent is an event repeating by a FREQ=YEARLY rule. Without BYMONTH
+// it repeats in the month of its start, and without BYDAY or BYMONTHDAY on
+// the day of the month of its start.
+type yearlyEvent struct {
+	start     time.Time
+	days      int
+	interval  int
+	months    []time.Month
+	weekdays  []icsWeekday
+	monthDays []int
+	until     time.Time
+	count     int
+	except    map[string]bool
+}
+
+// icsWeekday is a BYDAY value such as TH, 4TH or -1MO; n counts the weekday
+// within the month, from its end when negative, and 0 means every one
+type icsWeekday struct {
+	weekday time.Weekday
+	n       int
+}
+
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// Contains reports whether an event covers the date of day
+func (h *ICSHolidays) Contains(day time.Time) bool {
+	if h.dates[day.Format("2006-01-02")] {
+		return true
+	}
+	for _, event := range h.yearly {
+		if event.covers(day) {
+			return true
+		}
+	}
+	return false
+}
+
+// covers reports whether an occurrence of the event covers the date of day,
+// including one starting the year before and running into the next
+func (e yearlyEvent) covers(day time.Time) bool {
+	date := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	for year := date.Year() - 1; year <= date.Year(); year++ {
+		for _, occurrence := range e.occurrences(year) {
+			if !date.Before(occurrence) && date.Before(occurrence.AddDate(0, 0, e.days)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// occurrences returns the start dates of the event in a year, within UNTIL
+// and COUNT and leaving out EXDATE
+func (e yearlyEvent) occurrences(year int) []time.Time {
+	if year < e.start.Year() {
+		return nil
+	}
+	// COUNT counts the occurrences since the start, excluded dates included
+	seen := 0
+	if e.count > 0 {
+		for y := e.start.Year(); y < year; y++ {
+			seen += len(e.dates(y))
+		}
+	}
+	var occurrences []time.Time
+	for _, date := range e.dates(year) {
+		seen++
+		if e.count > 0 && seen > e.count {
+			break
+		}
+		if !e.until.IsZero() && date.After(e.until) {
+			break
+		}
+		if !e.except[date.Format("2006-01-02")] {
+			occurrences = append(occurrences, date)
+		}
+	}
+	return occurrences
+}
+
+// dates returns the dates matching the rule in a year, from the start on
+func (e yearlyEvent) dates(year int) []time.Time {
+	if year < e.start.Year() || (year-e.start.Year())%e.interval != 0 {
+		return nil
+	}
+	months := e.months
+	if len(months) == 0 {
+		months = []time.Month{e.start.Month()}
+	}
+	var dates []time.Time
+	for _, month := range months {
+		length := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		for day := 1; day <= length; day++ {
+			date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+			if !date.Before(e.start) && e.matches(date, length) {
+				dates = append(dates, date)
+			}
+		}
+	}
+	return dates
+}
+
+// matches reports whether a date of a month of length days matches BYDAY
+// and BYMONTHDAY, or the day of the month of the start without either
+func (e yearlyEvent) matches(date time.Time, length int) bool {
+	if len(e.weekdays) == 0 && len(e.monthDays) == 0 {
+		return date.Day() == e.start.Day()
+	}
+	if len(e.monthDays) > 0 {
+		matched := false
+		for _, day := range e.monthDays {
+			if day == date.Day() || day == date.Day()-length-1 {
+				matched = true
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(e.weekdays) > 0 {
+		matched := false
+		for _, weekday := range e.weekdays {
+			if weekday.weekday != date.Weekday() {
+				continue
+			}
+			first, last := (date.Day()-1)/7+1, -((length-date.Day())/7 + 1)
+			if weekday.n == 0 || weekday.n == first || weekday.n == last {
+				matched = true
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseICS returns the holidays of the events of an iCalendar file. An event
+// covers its start date up to, excluding, its end date. Recurring events
+// must repeat by FREQ=YEARLY with at most INTERVAL, BYMONTH, BYDAY,
+// BYMONTHDAY, UNTIL and COUNT; other rules are rejected rather than
+// misread.
+func ParseICS(r io.Reader) (*ICSHolidays, error) {
+	// Long lines are folded onto continuation lines starting with a space
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			if len(lines) > 0 {
+				lines[len(lines)-1] += line[1:]
+			}
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	holidays := &ICSHolidays{dates: map[string]bool{}}
+	var start, end time.Time
+	var rule string
+	var except map[string]bool
+	var inEvent bool
+	for _, line := range lines {
+		name, value, _ := strings.Cut(line, ":")
+		property, _, _ := strings.Cut(name, ";")
+		switch strings.ToUpper(property) {
+		case "BEGIN":
+			if strings.EqualFold(value, "VEVENT") {
+				start, end, rule, except, inEvent = time.Time{}, time.Time{}, "", map[string]bool{}, true
+			}
+		case "DTSTART":
+			start = parseICSDate(value)
+		case "DTEND":
+			end = parseICSDate(value)
+		case "RRULE":
+			rule = value
+		case "EXDATE":
+			for _, date := range strings.Split(value, ",") {
+				if excluded := parseICSDate(date); !excluded.IsZero() {
+					except[excluded.Format("2006-01-02")] = true
+				}
+			}
+		case "END":
+			if !strings.EqualFold(value, "VEVENT") || !inEvent {
+				continue
+			}
+			inEvent = false
+			if start.IsZero() {
+				continue
+			}
+			days := 1
+			if end.After(start) {
+				days = int(end.Sub(start).Hours()/24 + 0.5)
+			}
+			if rule == "" {
+				for d := 0; d < days; d++ {
+					if date := start.AddDate(0, 0, d); !except[date.Format("2006-01-02")] {
+						holidays.dates[date.Format("2006-01-02")] = true
+					}
+				}
+				continue
+			}
+			event, err := parseYearlyRule(rule)
+			if err != nil {
+				return nil, fmt.Errorf("unsupported RRULE %s of the event starting %s, %s", rule, start.Format("2006-01-02"), err)
+			}
+			event.start, event.days, event.except = start, days, except
+			holidays.yearly = append(holidays.yearly, event)
+		}
+	}
+	return holidays, nil
+}
+
+// parseYearlyRule parses the parts of a FREQ=YEARLY RRULE value
+func parseYearlyRule(rule string) (yearlyEvent, error) {
+	event := yearlyEvent{interval: 1}
+	yearly := false
+	for _, part := range strings.Split(rule, ";") {
+		name, value, _ := strings.Cut(part, "=")
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			if !strings.EqualFold(value, "YEARLY") {
+				return event, fmt.Errorf("only FREQ=YEARLY is supported, got FREQ=%s", value)
+			}
+			yearly = true
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 {
+				return event, fmt.Errorf("invalid INTERVAL %s", value)
+			}
+			event.interval = interval
+		case "BYMONTH":
+			for _, month := range strings.Split(value, ",") {
+				m, err := strconv.Atoi(month)
+				if err != nil || m < 1 || m > 12 {
+					return event, fmt.Errorf("invalid BYMONTH %s", month)
+				}
+				event.months = append(event.months, time.Month(m))
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(value, ",") {
+				d, err := strconv.Atoi(day)
+				if err != nil || d == 0 || d < -31 || d > 31 {
+					return event, fmt.Errorf("invalid BYMONTHDAY %s", day)
+				}
+				event.monthDays = append(event.monthDays, d)
+			}
+		case "BYDAY":
+			for _, day := range strings.Split(strings.ToUpper(value), ",") {
+				if len(day) < 2 {
+					return event, fmt.Errorf("invalid BYDAY %s", day)
+				}
+				weekday, ok := icsWeekdays[day[len(day)-2:]]
+				if !ok {
+					return event, fmt.Errorf("invalid BYDAY %s", day)
+				}
+				n := 0
+				if ordinal := day[:len(day)-2]; ordinal != "" {
+					var err error
+					n, err = strconv.Atoi(strings.TrimPrefix(ordinal, "+"))
+					if err != nil || n == 0 || n < -5 || n > 5 {
+						return event, fmt.Errorf("invalid BYDAY %s, the weekday of a month is counted up to 5", day)
+					}
+				}
+				event.weekdays = append(event.weekdays, icsWeekday{weekday: weekday, n: n})
+			}
+		case "UNTIL":
+			event.until = parseICSDate(value)
+			if event.until.IsZero() {
+				return event, fmt.Errorf("invalid UNTIL %s", value)
+			}
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 1 {
+				return event, fmt.Errorf("invalid COUNT %s", value)
+			}
+			event.count = count
+		case "WKST":
+			// The week start only matters to weekly rules
+		default:
+			return event, fmt.Errorf("%s is not supported", name)
+		}
+	}
+	if !yearly {
+		return event, fmt.Errorf("FREQ=YEARLY is missing")
+	}
+	// UNTIL and COUNT follow the dates in order
+	slices.Sort(event.months)
+	if len(event.weekdays) > 0 && len(event.months) == 0 {
+		return event, fmt.Errorf("BYDAY needs BYMONTH, weekdays of the whole year are not supported")
+	}
+	return event, nil
+}
+
+// parseICSDate parses the date of a DATE or DATE-TIME value
+func parseICSDate(value string) time.Time {
+	if len(value) < 8 {
+		return time.Time{}
+	}
+	t, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
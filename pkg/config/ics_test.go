@@ -0,0 +1,104 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseYearlyRule(t *testing.T) {
+	tests := []struct {
+		rule string
+		want yearlyEvent
+		// err is part of the error expected instead
+		err string
+	}{
+		{"FREQ=YEARLY", yearlyEvent{interval: 1}, ""},
+		{"FREQ=YEARLY;INTERVAL=2", yearlyEvent{interval: 2}, ""},
+		{"FREQ=YEARLY;BYMONTH=11;BYDAY=4TH", yearlyEvent{interval: 1, months: []time.Month{time.November}, weekdays: []icsWeekday{{time.Thursday, 4}}}, ""},
+		{"freq=yearly;bymonth=5;byday=-1mo", yearlyEvent{interval: 1, months: []time.Month{time.May}, weekdays: []icsWeekday{{time.Monday, -1}}}, ""},
+		{"FREQ=YEARLY;BYMONTH=11,2;BYDAY=+1MO,FR", yearlyEvent{interval: 1, months: []time.Month{time.February, time.November}, weekdays: []icsWeekday{{time.Monday, 1}, {time.Friday, 0}}}, ""},
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=-1,1", yearlyEvent{interval: 1, months: []time.Month{time.February}, monthDays: []int{-1, 1}}, ""},
+		{"FREQ=YEARLY;COUNT=3;WKST=MO", yearlyEvent{interval: 1, count: 3}, ""},
+		{"FREQ=YEARLY;UNTIL=20301231T235959Z", yearlyEvent{interval: 1, until: time.Date(2030, 12, 31, 0, 0, 0, 0, time.UTC)}, ""},
+		{"FREQ=MONTHLY", yearlyEvent{}, "only FREQ=YEARLY is supported"},
+		{"BYMONTH=1", yearlyEvent{}, "FREQ=YEARLY is missing"},
+		{"FREQ=YEARLY;INTERVAL=0", yearlyEvent{}, "invalid INTERVAL 0"},
+		{"FREQ=YEARLY;BYMONTH=13", yearlyEvent{}, "invalid BYMONTH 13"},
+		{"FREQ=YEARLY;BYMONTHDAY=0", yearlyEvent{}, "invalid BYMONTHDAY 0"},
+		{"FREQ=YEARLY;BYMONTHDAY=32", yearlyEvent{}, "invalid BYMONTHDAY 32"},
+		{"FREQ=YEARLY;BYMONTH=1;BYDAY=XX", yearlyEvent{}, "invalid BYDAY XX"},
+		{"FREQ=YEARLY;BYMONTH=1;BYDAY=6MO", yearlyEvent{}, "the weekday of a month is counted up to 5"},
+		{"FREQ=YEARLY;BYDAY=MO", yearlyEvent{}, "BYDAY needs BYMONTH"},
+		{"FREQ=YEARLY;COUNT=0", yearlyEvent{}, "invalid COUNT 0"},
+		{"FREQ=YEARLY;UNTIL=2030", yearlyEvent{}, "invalid UNTIL 2030"},
+		{"FREQ=YEARLY;BYWEEKNO=20", yearlyEvent{}, "BYWEEKNO is not supported"},
+	}
+	for _, test := range tests {
+		t.Run(test.rule, func(t *testing.T) {
+			got, err := parseYearlyRule(test.rule)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want one containing %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("parseYearlyRule(%s) = %+v, want %+v", test.rule, got, test.want)
+			}
+		})
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	tests := []struct {
+		name   string
+		start  string
+		rule   string
+		except []string
+		year   int
+		want   []string
+	}{
+		{"start date", "2020-07-04", "FREQ=YEARLY", nil, 2026, []string{"2026-07-04"}},
+		{"fourth Thursday", "2020-11-26", "FREQ=YEARLY;BYMONTH=11;BYDAY=4TH", nil, 2026, []string{"2026-11-26"}},
+		{"last Monday", "2020-05-25", "FREQ=YEARLY;BYMONTH=5;BYDAY=-1MO", nil, 2026, []string{"2026-05-25"}},
+		{"last day of a leap February", "2020-02-29", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=-1", nil, 2028, []string{"2028-02-29"}},
+		{"last day of February", "2020-02-29", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=-1", nil, 2026, []string{"2026-02-28"}},
+		{"Friday the 13th", "2020-01-01", "FREQ=YEARLY;BYMONTH=1,2,3,4,5,6,7,8,9,10,11,12;BYDAY=FR;BYMONTHDAY=13", nil, 2026, []string{"2026-02-13", "2026-03-13", "2026-11-13"}},
+		{"before the start", "2020-07-04", "FREQ=YEARLY", nil, 2019, nil},
+		{"the start year before the start", "2020-07-04", "FREQ=YEARLY;BYMONTH=1,7", nil, 2020, []string{"2020-07-04"}},
+		{"every other year on", "2020-07-04", "FREQ=YEARLY;INTERVAL=2", nil, 2022, []string{"2022-07-04"}},
+		{"every other year off", "2020-07-04", "FREQ=YEARLY;INTERVAL=2", nil, 2023, nil},
+		{"last of COUNT", "2020-07-04", "FREQ=YEARLY;COUNT=3", nil, 2022, []string{"2022-07-04"}},
+		{"after COUNT", "2020-07-04", "FREQ=YEARLY;COUNT=3", nil, 2023, nil},
+		{"COUNT within a year", "2020-01-01", "FREQ=YEARLY;COUNT=3;BYMONTH=1,4,7,10;BYMONTHDAY=1", nil, 2020, []string{"2020-01-01", "2020-04-01", "2020-07-01"}},
+		{"COUNT counts excluded dates", "2020-07-04", "FREQ=YEARLY;COUNT=3", []string{"2021-07-04"}, 2023, nil},
+		{"excluded date", "2020-07-04", "FREQ=YEARLY", []string{"2021-07-04"}, 2021, nil},
+		{"on UNTIL", "2020-07-04", "FREQ=YEARLY;UNTIL=20230704", nil, 2023, []string{"2023-07-04"}},
+		{"after UNTIL", "2020-07-04", "FREQ=YEARLY;UNTIL=20230703", nil, 2023, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			event, err := parseYearlyRule(test.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			event.start, _ = time.Parse("2006-01-02", test.start)
+			event.except = map[string]bool{}
+			for _, date := range test.except {
+				event.except[date] = true
+			}
+			var got []string
+			for _, occurrence := range event.occurrences(test.year) {
+				got = append(got, occurrence.Format("2006-01-02"))
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("occurrences in %d = %v, want %v", test.year, got, test.want)
+			}
+		})
+	}
+}
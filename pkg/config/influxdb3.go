@@ -0,0 +1,39 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+)
+
+// InfluxDB3Version selects InfluxDB 3 as the influxDB.version
+const InfluxDB3Version = 3
+
+// InfluxFlight locates the Flight SQL service of InfluxDB 3 used for
+// read-back verification
+type InfluxFlight struct {
+	Address string
+	TLS     bool
+}
+
+// FlightAddress returns the host:port of the InfluxDB 3 Flight SQL service,
+// which defaults to the host and port of the HTTP address
+func (i InfluxDB) FlightAddress() (string, bool, error) {
+	address, err := url.Parse(i.Address)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid InfluxDB address %s, %s", i.Address, err)
+	}
+	secure := address.Scheme == "https"
+	if i.Flight.Address != "" {
+		return i.Flight.Address, secure || i.Flight.TLS, nil
+	}
+	host := address.Host
+	if address.Port() == "" {
+		port := "80"
+		if secure {
+			port = "443"
+		}
+		host = net.JoinHostPort(address.Hostname(), port)
+	}
+	return host, secure, nil
+}
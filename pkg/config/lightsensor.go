@@ -0,0 +1,137 @@
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LightSensor configures a local light sensor read every Interval, from a
+// File such as an IIO in_illuminance_input or a URL, holding a number or,
+// with Field, a JSON object with that numeric member. The brightness peaks
+// at solar noon, so the time of the peak as seen by the local clock tells
+// how far the clock is off on devices without reliable NTP.
+type LightSensor struct {
+	File          string
+	URL           string
+	Field         string
+	Headers       map[string]string
+	Interval      time.Duration
+	Window        time.Duration
+	Days          int
+	Timeout       time.Duration
+	SkipVerifySsl bool
+}
+
+// Defaults of the light sensor
+const (
+	DefaultLightSensorInterval = time.Minute
+	DefaultLightSensorWindow   = 3 * time.Hour
+	DefaultLightSensorDays     = 7
+)
+
+func (l LightSensor) Enabled() bool {
+	return l.URL != "" || l.File != ""
+}
+
+func (l LightSensor) Validate() error {
+	if l.URL != "" && l.File != "" {
+		return fmt.Errorf("lightSensor must set only one of url or file")
+	}
+	if l.Interval < 0 || l.Window < 0 || l.Days < 0 || l.Timeout < 0 {
+		return fmt.Errorf("lightSensor.interval, window, days and timeout must not be negative")
+	}
+	if l.Window >= 12*time.Hour {
+		return fmt.Errorf("lightSensor.window must be shorter than 12h")
+	}
+	return nil
+}
+
+func (l LightSensor) WithDefaults() LightSensor {
+	if l.Interval == 0 {
+		l.Interval = DefaultLightSensorInterval
+	}
+	if l.Window == 0 {
+		l.Window = DefaultLightSensorWindow
+	}
+	if l.Days == 0 {
+		l.Days = DefaultLightSensorDays
+	}
+	if l.Timeout == 0 {
+		l.Timeout = 10 * time.Second
+	}
+	return l
+}
+
+// Read returns the current brightness
+func (l LightSensor) Read(ctx context.Context) (float64, error) {
+	var data []byte
+	var err error
+	if l.File != "" {
+		data, err = os.ReadFile(l.File)
+		if err != nil {
+			return 0, fmt.Errorf("unable to read the light sensor, %s", err)
+		}
+	} else {
+		data, err = l.get(ctx)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if l.Field == "" {
+		value, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+		if err != nil {
+			return 0, fmt.Errorf("unable to decode the light sensor reading, %s", err)
+		}
+		return value, nil
+	}
+	var values map[string]interface{}
+	err = json.Unmarshal(data, &values)
+	if err != nil {
+		return 0, fmt.Errorf("unable to decode the light sensor reading, %s", err)
+	}
+	value, ok := values[l.Field].(float64)
+	if !ok {
+		return 0, fmt.Errorf("unable to decode the light sensor reading, %s is not a number", l.Field)
+	}
+	return value, nil
+}
+
+func (l LightSensor) get(ctx context.Context) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, l.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create light sensor request, %s", err)
+	}
+	for name, value := range l.Headers {
+		req.Header.Set(name, value)
+	}
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: l.SkipVerifySsl},
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the light sensor, %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to read the light sensor, %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the light sensor, %s", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// LocalTime adds the local_time and utc_offset fields, spelling out each
+// point's time in a time zone for consumers that misread epoch timestamps
+type LocalTime struct {
+	Timezone string
+	Format   string
+
+	location *time.Location
+}
+
+func (l LocalTime) Enabled() bool {
+	return l.Timezone != ""
+}
+
+// load resolves the time zone once so samples do not read the zone database
+func (l *LocalTime) load() error {
+	location, err := time.LoadLocation(l.Timezone)
+	if err != nil {
+		return fmt.Errorf("invalid localTime timezone %s, %s", l.Timezone, err)
+	}
+	l.location = location
+	return nil
+}
+
+// Location returns the time zone of the local_time field, that of the host
+// when none is configured
+func (l LocalTime) Location() *time.Location {
+	if l.location == nil {
+		return time.Local
+	}
+	return l.location
+}
+
+// Fields returns the local time string and the UTC offset in seconds of t
+func (l LocalTime) Fields(t time.Time) (string, int64) {
+	location := l.Location()
+	format := l.Format
+	if format == "" {
+		format = time.RFC3339
+	}
+	local := t.In(location)
+	_, offset := local.Zone()
+	return local.Format(format), int64(offset)
+}
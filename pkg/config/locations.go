@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+)
+
+// NamedLocation is a further site whose daylight is computed and written
+// alongside the configured location, tagged with its name and tags
+type NamedLocation struct {
+	Name      string
+	Latitude  float64
+	Longitude float64
+	Altitude  float64
+	Tags      map[string]string
+}
+
+// ValidateLocations checks that locations are named uniquely, apart from the
+// configured location too, and their coordinates are valid
+func ValidateLocations(locationName string, locations []NamedLocation) error {
+	names := map[string]bool{}
+	if locationName != "" {
+		names[locationName] = true
+	}
+	for i, location := range locations {
+		if location.Name == "" {
+			return fmt.Errorf("location %d has no name", i+1)
+		}
+		if names[location.Name] {
+			return fmt.Errorf("location %s is defined more than once", location.Name)
+		}
+		names[location.Name] = true
+		err := ValidateCoordinates(location.Latitude, location.Longitude)
+		if err == nil {
+			err = ValidateAltitude(location.Altitude)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid location %s, %s", location.Name, err)
+		}
+		if _, ok := location.Tags["location"]; ok {
+			return fmt.Errorf("location %s must not set the location tag, which holds its name", location.Name)
+		}
+	}
+	return nil
+}
+
+// ForLocation returns the configuration computing the samples of a named
+// location: the same settings at its coordinates, without the ephemeris
+// generated for the configured location
+func (config Configuration) ForLocation(location NamedLocation) Configuration {
+	config.Latitude, config.Longitude = location.Latitude, location.Longitude
+	config.Altitude = location.Altitude
+	config.LocationName = location.Name
+	config.Locations = nil
+	config.placeTags = nil
+	config.locationTags = location.Tags
+	return config
+}
+
+// LocationTags returns the tags of the named location the configuration
+// computes the samples of
+func (config Configuration) LocationTags() map[string]string {
+	return config.locationTags
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// LoRaWAN configures handing the daylight state of the configured location,
+// encoded as a compact binary payload, to a LoRaWAN network server or modem
+// bridge for uplink, by HTTP POST to URL or by publishing to the broker and
+// topic of MQTT. Airtime is scarce, so an uplink is only sent when the state
+// flags change or Interval after the previous one.
+type LoRaWAN struct {
+	URL       string
+	Headers   map[string]string
+	MQTT      *MQTT
+	DevEUI    string
+	FPort     uint8
+	Confirmed bool
+	// Encoding is json for an envelope carrying the payload in base64, or raw
+	// for the payload bytes alone
+	Encoding string
+	Interval time.Duration
+	Timeout  time.Duration
+}
+
+func (l LoRaWAN) Validate() error {
+	if (l.URL == "") == (l.MQTT == nil) {
+		return fmt.Errorf("the lorawan output needs exactly one of lorawan.url or lorawan.mqtt")
+	}
+	if l.MQTT != nil {
+		err := l.MQTT.Validate()
+		if err != nil {
+			return err
+		}
+	}
+	if l.DevEUI != "" {
+		eui, err := hex.DecodeString(l.DevEUI)
+		if err != nil || len(eui) != 8 {
+			return fmt.Errorf("invalid lorawan.devEUI %q, expected 16 hexadecimal digits", l.DevEUI)
+		}
+	}
+	if l.FPort > 223 {
+		return fmt.Errorf("invalid lorawan.fPort %d, must be between 1 and 223", l.FPort)
+	}
+	switch l.Encoding {
+	case "", "json", "raw":
+	default:
+		return fmt.Errorf("unknown lorawan.encoding %q, expected json or raw", l.Encoding)
+	}
+	if l.Interval < 0 || l.Timeout < 0 {
+		return fmt.Errorf("lorawan.interval and lorawan.timeout must not be negative")
+	}
+	return nil
+}
+
+func (l LoRaWAN) WithDefaults() LoRaWAN {
+	if l.FPort == 0 {
+		l.FPort = 1
+	}
+	if l.Encoding == "" {
+		l.Encoding = "json"
+	}
+	if l.Interval == 0 {
+		l.Interval = time.Hour
+	}
+	if l.Timeout == 0 {
+		l.Timeout = 10 * time.Second
+	}
+	return l
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"fmt"
+)
+
+// Memory caps what the process buffers in memory, so it stays within tight
+// cgroup memory limits on routers and single board computers
+type Memory struct {
+	// Limit is a soft limit in bytes on the memory of the Go runtime, which
+	// collects garbage more often as it gets close; 0 keeps GOMEMLIMIT
+	Limit int64
+	// MaxQueuedSamples and MaxQueuedBytes cap the samples waiting in all
+	// output queues together; samples beyond either cap are dropped
+	MaxQueuedSamples int
+	MaxQueuedBytes   int64
+	// MaxRetryPoints caps the points each InfluxDB client keeps to retry
+	// failed writes, dropping the oldest beyond it
+	MaxRetryPoints int
+}
+
+func (m Memory) Validate() error {
+	if m.Limit < 0 || m.MaxQueuedSamples < 0 || m.MaxQueuedBytes < 0 || m.MaxRetryPoints < 0 {
+		return fmt.Errorf("memory limits must not be negative")
+	}
+	return nil
+}
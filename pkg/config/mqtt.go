@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MQTT configures publishing samples to an MQTT broker. Samples of the
+// configured location go to Topic and those of other measurements, such as
+// location groups, to Topic/<measurement> and those of named locations to
+// Topic/locations/<name>; state changes of boolean fields
+// go to EventTopic. With Discovery the daylight state and the day's sunrise
+// and sunset are announced to Home Assistant under DiscoveryPrefix.
+type MQTT struct {
+	Broker          string
+	ClientID        string
+	Username        string
+	Password        string
+	Topic           string
+	EventTopic      string
+	QoS             byte
+	Retain          bool
+	EventRetain     bool
+	Timeout         time.Duration
+	SkipVerifySsl   bool
+	Discovery       bool
+	DiscoveryPrefix string
+}
+
+func (m MQTT) Enabled() bool {
+	return m.Broker != ""
+}
+
+func (m MQTT) Validate() error {
+	if m.Broker == "" {
+		return fmt.Errorf("the mqtt output needs mqtt.broker")
+	}
+	if m.QoS > 2 {
+		return fmt.Errorf("invalid mqtt qos %d, must be 0, 1 or 2", m.QoS)
+	}
+	return nil
+}
+
+func (m MQTT) WithDefaults() MQTT {
+	if m.ClientID == "" {
+		m.ClientID = "daylight-timeseries"
+	}
+	if m.Topic == "" {
+		m.Topic = "daylight"
+	}
+	if m.EventTopic == "" {
+		m.EventTopic = m.Topic + "/events"
+	}
+	if m.Timeout == 0 {
+		m.Timeout = 10 * time.Second
+	}
+	if m.DiscoveryPrefix == "" {
+		m.DiscoveryPrefix = "homeassistant"
+	}
+	return m
+}
+
+// SunTopic is where the day's sunrise and sunset are published for discovery
+func (m MQTT) SunTopic() string {
+	return m.Topic + "/sun"
+}
+
+// StatusTopic carries online, or offline as the will of a lost connection,
+// for discovery
+func (m MQTT) StatusTopic() string {
+	return m.Topic + "/status"
+}
+
+// DiscoveryMessages returns the Home Assistant discovery configurations by
+// topic: a light binary sensor for daylight and timestamp sensors for
+// sunrise and sunset, grouped into one device
+func (m MQTT) DiscoveryMessages() map[string]map[string]interface{} {
+	node := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return '_'
+	}, m.ClientID)
+	device := map[string]interface{}{
+		"identifiers": []string{node},
+		"name":        "Daylight",
+		"model":       "daylight-timeseries",
+	}
+	entity := func(name, id string) map[string]interface{} {
+		return map[string]interface{}{
+			"name":                  name,
+			"unique_id":             node + "_" + id,
+			"object_id":             node + "_" + id,
+			"availability_topic":    m.StatusTopic(),
+			"payload_available":     "online",
+			"payload_not_available": "offline",
+			"device":                device,
+		}
+	}
+
+	daylight := entity("Daylight", "daylight")
+	daylight["state_topic"] = m.Topic
+	daylight["value_template"] = "{{ 'ON' if value_json.fields.daylight else 'OFF' }}"
+	daylight["device_class"] = "light"
+
+	messages := map[string]map[string]interface{}{
+		m.DiscoveryPrefix + "/binary_sensor/" + node + "/daylight/config": daylight,
+	}
+	for _, event := range []struct{ id, name, icon string }{
+		{"sunrise", "Sunrise", "mdi:weather-sunset-up"},
+		{"sunset", "Sunset", "mdi:weather-sunset-down"},
+	} {
+		sensor := entity(event.name, event.id)
+		sensor["state_topic"] = m.SunTopic()
+		sensor["value_template"] = "{{ value_json." + event.id + " }}"
+		sensor["device_class"] = "timestamp"
+		sensor["icon"] = event.icon
+		messages[m.DiscoveryPrefix+"/sensor/"+node+"/"+event.id+"/config"] = sensor
+	}
+	return messages
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Notifier delivers notifications, either as a JSON POST to URL, as JSON on
+// the stdin of Command or as email through SMTP. Besides the rules and the
+// digest naming it, a notifier receives the built-in Events it lists:
+// sunrise, sunset, the <field>_start and <field>_end transitions of other
+// boolean fields, and write_error.
+type Notifier struct {
+	URL     string
+	Headers map[string]string
+	Command []string
+	SMTP    SMTPNotifier
+	Events  []string
+	Timeout time.Duration
+}
+
+// EventWriteError is the built-in event of InfluxDB writes starting to fail
+const EventWriteError = "write_error"
+
+// Notification is the JSON document notifiers receive
+type Notification struct {
+	Time    time.Time `json:"time"`
+	Rule    string    `json:"rule"`
+	Message string    `json:"message"`
+	// RequestID correlates the notification with the logs of the poll
+	// iteration that triggered it
+	RequestID string `json:"request_id,omitempty"`
+	// Data holds structured details, such as the digest summary
+	Data interface{} `json:"data,omitempty"`
+}
+
+func (n Notifier) Validate(name string) error {
+	set := 0
+	for _, ok := range []bool{n.URL != "", len(n.Command) > 0, n.SMTP.Enabled()} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("notifier %s must set exactly one of url, command or smtp", name)
+	}
+	if n.SMTP.Enabled() {
+		return n.SMTP.Validate(name)
+	}
+	return nil
+}
+
+// Subscribed reports whether the notifier lists the built-in event
+func (n Notifier) Subscribed(event string) bool {
+	for _, e := range n.Events {
+		if strings.EqualFold(e, event) {
+			return true
+		}
+	}
+	return false
+}
+
+// Send delivers a notification
+func (n Notifier) Send(ctx context.Context, notification Notification) error {
+	timeout := n.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if n.SMTP.Enabled() {
+		return n.SMTP.Send(ctx, notification)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(notification)
+	if err != nil {
+		return fmt.Errorf("unable to encode notification, %s", err)
+	}
+	body := buf.Bytes()
+
+	if len(n.Command) > 0 {
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, n.Command[0], n.Command[1:]...)
+		cmd.Stdin = bytes.NewReader(body)
+		cmd.Stderr = &stderr
+		err = cmd.Run()
+		if err != nil {
+			return fmt.Errorf("notification command %s failed, %s: %s", n.Command[0], err, strings.TrimSpace(stderr.String()))
+		}
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to create notification request, %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range n.Headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to send notification, %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unable to send notification, %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package config
+
+// OPCUA configures the embedded OPC UA server of the opcua output
+type OPCUA struct {
+	Enabled   bool
+	Host      string
+	Network   string
+	Interface string
+	Port      int
+	Hostnames []string
+	CertFile  string
+	KeyFile   string
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"strings"
+	"time"
+)
+
+// OutputConfig configures one output. In the outputs list it may also be
+// given as just the type, e.g. influxdb; the name defaults to the type.
+type OutputConfig struct {
+	Name      string
+	Type      string
+	QueueSize int
+	// FlushInterval and BatchSize flush the output at the latest that long
+	// after, or that many samples after, the previous flush; Retry retries
+	// failed writes
+	FlushInterval time.Duration
+	BatchSize     int
+	Retry         RetryPolicy
+	// InfluxDB and Spool configure an influxdb output writing somewhere else
+	// than the influxDB section; gap filling only applies to the latter
+	InfluxDB *InfluxDB
+	Spool    Spool
+	FIFO     *FIFO
+	File     FileOutput
+	MQTT     *MQTT
+	LoRaWAN  LoRaWAN
+}
+
+// RetryPolicy retries a failed write up to MaxRetries times, waiting
+// Interval before the first retry and twice as long before each next one, up
+// to MaxInterval
+type RetryPolicy struct {
+	MaxRetries  int
+	Interval    time.Duration
+	MaxInterval time.Duration
+}
+
+func (r RetryPolicy) Validate() error {
+	if r.MaxRetries < 0 || r.Interval < 0 || r.MaxInterval < 0 {
+		return fmt.Errorf("retry.maxRetries, retry.interval and retry.maxInterval must not be negative")
+	}
+	return nil
+}
+
+func (r RetryPolicy) WithDefaults() RetryPolicy {
+	if r.Interval == 0 {
+		r.Interval = time.Second
+	}
+	if r.MaxInterval == 0 {
+		r.MaxInterval = time.Minute
+	}
+	return r
+}
+
+// mqtt returns the broker settings of an mqtt output, the mqtt section unless
+// it has its own
+func (output OutputConfig) Broker(config *Configuration) MQTT {
+	if output.MQTT != nil {
+		return *output.MQTT
+	}
+	return config.MQTT
+}
+
+// normalizeOutputs turns the outputs given as just a type into maps before
+// the configuration is decoded
+func normalizeOutputs() {
+	outputs, ok := viper.Get("outputs").([]interface{})
+	if !ok {
+		return
+	}
+	for i, output := range outputs {
+		if kind, ok := output.(string); ok {
+			outputs[i] = map[string]interface{}{"type": kind}
+		}
+	}
+	viper.Set("outputs", outputs)
+}
+
+// OutputConfigs returns the outputs the collector writes to: the outputs list
+// of the configuration or, when it is empty, InfluxDB along with the named
+// pipe, OPC UA server and MQTT broker when those are configured
+func (config Configuration) OutputConfigs() []OutputConfig {
+	if len(config.Outputs) > 0 {
+		outputs := make([]OutputConfig, len(config.Outputs))
+		for i, output := range config.Outputs {
+			output.Type = strings.ToLower(output.Type)
+			if output.Name == "" {
+				output.Name = output.Type
+			}
+			outputs[i] = output
+		}
+		return outputs
+	}
+	outputs := []OutputConfig{{Name: "influxdb", Type: "influxdb"}}
+	if config.FIFO.Path != "" {
+		outputs = append(outputs, OutputConfig{Name: "fifo", Type: "fifo"})
+	}
+	if config.OPCUA.Enabled {
+		outputs = append(outputs, OutputConfig{Name: "opcua", Type: "opcua"})
+	}
+	if config.MQTT.Enabled() {
+		outputs = append(outputs, OutputConfig{Name: "mqtt", Type: "mqtt"})
+	}
+	return outputs
+}
+
+func (config Configuration) validateOutputs() error {
+	seen := map[string]bool{}
+	for _, output := range config.OutputConfigs() {
+		if seen[output.Name] {
+			return fmt.Errorf("output %s is defined more than once", output.Name)
+		}
+		seen[output.Name] = true
+		if output.QueueSize < 0 || output.FlushInterval < 0 || output.BatchSize < 0 {
+			return fmt.Errorf("queueSize, flushInterval and batchSize of output %s must not be negative", output.Name)
+		}
+		err := output.Retry.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid output %s, %s", output.Name, err)
+		}
+		switch output.Type {
+		case "influxdb":
+			if output.InfluxDB != nil {
+				instance := config
+				instance.InfluxDB = *output.InfluxDB
+				if instance.InfluxDB.FlushInterval != 0 {
+					err = instance.InfluxDB.ValidateFlushInterval()
+					if err != nil {
+						break
+					}
+				}
+				_, err = InfluxWriteDestination(&instance)
+				if err == nil {
+					err = output.Spool.Validate()
+				}
+			}
+		case "fifo":
+			if output.FIFO != nil {
+				err = output.FIFO.Validate()
+			}
+		case "file":
+			err = output.File.Validate()
+		case "mqtt":
+			err = output.Broker(&config).Validate()
+		case "lorawan":
+			err = output.LoRaWAN.Validate()
+		}
+		if err != nil {
+			return fmt.Errorf("invalid output %s, %s", output.Name, err)
+		}
+	}
+	return nil
+}
+
+// OutputSettings returns everything an output is opened with: its entry and
+// the sections of the configuration its type reads
+func (config Configuration) OutputSettings(output OutputConfig) []interface{} {
+	settings := []interface{}{output, config.InfluxDB.ErrorLog}
+	switch output.Type {
+	case "influxdb":
+		settings = append(settings, config.InfluxDB, config.Spool, config.GapFill)
+	case "fifo":
+		settings = append(settings, config.FIFO)
+	case "opcua":
+		settings = append(settings, config.OPCUA)
+	case "mqtt", "lorawan":
+		// These compute sun events and transitions of the site themselves
+		settings = append(settings, output.Broker(&config), config.Latitude, config.Longitude, config.TimeOffset,
+			config.ExactTransitionTimes, config.SupplementalLight, config.EphemerisFile)
+	}
+	return settings
+}
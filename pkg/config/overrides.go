@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+	"strings"
+)
+
+// ConfigOverrides holds the key=value pairs given with -set, applied on top
+// of the configuration file and the environment
+var ConfigOverrides Overrides
+
+// Overrides is a repeatable flag of key=value pairs. Keys are dotted paths
+// into the configuration such as influxDB.bucket; values are YAML, so
+// numbers, booleans, lists like [a, b] and maps work as in the file.
+type Overrides []string
+
+func (o *Overrides) String() string {
+	return strings.Join(*o, " ")
+}
+
+func (o *Overrides) Set(value string) error {
+	key, _, ok := strings.Cut(value, "=")
+	if !ok || strings.TrimSpace(key) == "" {
+		return fmt.Errorf("expected key=value")
+	}
+	*o = append(*o, value)
+	return nil
+}
+
+// Apply sets the overrides in viper
+func (o Overrides) Apply() error {
+	for _, override := range o {
+		key, text, _ := strings.Cut(override, "=")
+		var value interface{}
+		err := yaml.Unmarshal([]byte(text), &value)
+		if err != nil {
+			return fmt.Errorf("invalid value of -set %s, %s", key, err)
+		}
+		if value == nil && strings.TrimSpace(text) != "null" && strings.TrimSpace(text) != "~" {
+			// An empty value sets an empty string rather than nothing
+			value = ""
+		}
+		viper.Set(strings.TrimSpace(key), value)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// OverrunPolicy decides what happens to samples whose scheduled time passed
+// while the poll loop was blocked, e.g. on a slow InfluxDB write
+type OverrunPolicy string
+
+const (
+	// OverrunSkip drops the missed samples and waits for the next scheduled one
+	OverrunSkip OverrunPolicy = "skip"
+	// OverrunCoalesce takes a single sample straight away for all missed ones
+	OverrunCoalesce OverrunPolicy = "coalesce"
+	// OverrunQueue takes every missed sample with its original timestamp
+	OverrunQueue OverrunPolicy = "queue"
+)
+
+func (p OverrunPolicy) Validate() error {
+	switch p {
+	case "", OverrunSkip, OverrunCoalesce, OverrunQueue:
+		return nil
+	}
+	return fmt.Errorf("unknown overrun policy %q, expected skip, coalesce or queue", p)
+}
+
+// DueSamples returns the times to sample at when the loop wakes at now with
+// the sample at scheduled being due, and when the loop should wake next.
+// Samples stay on the grid of scheduled plus multiples of interval whatever
+// the policy, so overruns never drift the series.
+func (p OverrunPolicy) DueSamples(scheduled, now time.Time, interval time.Duration) ([]time.Time, time.Time) {
+	if now.Before(scheduled) {
+		return nil, scheduled
+	}
+	due := int(now.Sub(scheduled)/interval) + 1
+	next := scheduled.Add(time.Duration(due) * interval)
+	if due == 1 {
+		return []time.Time{now}, next
+	}
+
+	switch p {
+	case OverrunSkip:
+		return nil, next
+	case OverrunQueue:
+		times := make([]time.Time, due)
+		for i := range times {
+			times[i] = scheduled.Add(time.Duration(i) * interval)
+		}
+		return times, next
+	}
+	return []time.Time{now}, next
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+)
+
+type Privacy struct {
+	CoordinatePrecision int
+	CoordinateFuzz      float64
+}
+
+// PublicCoordinates returns the configured location as it may appear in tags
+// and logs. Coordinates are shifted by a stable pseudo-random offset of up to
+// CoordinateFuzz degrees, derived from the precise location so that it does
+// not average out across restarts, then rounded to CoordinatePrecision decimal
+// places. A negative precision leaves them unrounded.
+func (config Configuration) PublicCoordinates() (float64, float64) {
+	latitude, longitude := config.Latitude, config.Longitude
+
+	if fuzz := config.Privacy.CoordinateFuzz; fuzz > 0 {
+		h := fnv.New64a()
+		binary.Write(h, binary.LittleEndian, latitude)
+		binary.Write(h, binary.LittleEndian, longitude)
+		sum := h.Sum64()
+		latitude += fuzz * (float64(sum&0xffffffff)/math.MaxUint32*2 - 1)
+		longitude += fuzz * (float64(sum>>32)/math.MaxUint32*2 - 1)
+		latitude = math.Max(-90, math.Min(90, latitude))
+		longitude = math.Mod(longitude+540, 360) - 180
+	}
+
+	if precision := config.Privacy.CoordinatePrecision; precision >= 0 {
+		scale := math.Pow(10, float64(precision))
+		latitude = math.Round(latitude*scale) / scale
+		longitude = math.Round(longitude*scale) / scale
+	}
+
+	return latitude, longitude
+}
+
+// CoordinateTags returns the public coordinates formatted as tag values
+func (config Configuration) CoordinateTags() map[string]string {
+	latitude, longitude := config.PublicCoordinates()
+	return map[string]string{
+		"latitude":  formatCoordinate(latitude, config.Privacy.CoordinatePrecision),
+		"longitude": formatCoordinate(longitude, config.Privacy.CoordinatePrecision),
+	}
+}
+
+func formatCoordinate(value float64, precision int) string {
+	if precision < 0 {
+		return fmt.Sprint(value)
+	}
+	return fmt.Sprintf("%.*f", precision, value)
+}
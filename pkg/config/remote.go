@@ -0,0 +1,262 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/viper"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RemoteSource is a configuration kept under a key of etcd or Consul KV
+// rather than in a file, named by -config as etcd3://host:2379/key or
+// consul://host:8500/key; a +https suffix on the scheme connects with TLS
+type RemoteSource struct {
+	Provider string
+	Endpoint string
+	Key      string
+}
+
+// remoteTimeout bounds a read of a remote source, and connecting to it for
+// a watch
+const remoteTimeout = 10 * time.Second
+
+// consulWait is how long a Consul blocking query waits for a change; Consul
+// adds up to a sixteenth of it as jitter
+const consulWait = 5 * time.Minute
+
+// remoteClient reads remote sources. watchClient follows the blocking
+// queries and streams of watches, which last until the value changes, so
+// only connecting and the response headers are bounded.
+var (
+	remoteClient = &http.Client{Timeout: remoteTimeout}
+	watchClient  = &http.Client{Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: remoteTimeout}).DialContext,
+		TLSHandshakeTimeout:   remoteTimeout,
+		ResponseHeaderTimeout: consulWait + consulWait/16 + remoteTimeout,
+	}}
+)
+
+// ParseRemoteSource parses a -config value naming a remote source, reporting
+// false for a file path
+func ParseRemoteSource(path string) (RemoteSource, bool) {
+	scheme, rest, ok := strings.Cut(path, "://")
+	if !ok {
+		return RemoteSource{}, false
+	}
+	provider, transport, _ := strings.Cut(scheme, "+")
+	if provider != "etcd3" && provider != "consul" {
+		return RemoteSource{}, false
+	}
+	if transport == "" {
+		transport = "http"
+	}
+	host, key, _ := strings.Cut(rest, "/")
+	return RemoteSource{Provider: provider, Endpoint: transport + "://" + host, Key: key}, true
+}
+
+// readRemoteConfig reads the configuration from a remote source into viper,
+// in place of a file
+func readRemoteConfig(s RemoteSource) error {
+	err := s.Validate()
+	if err != nil {
+		return err
+	}
+	value, err := s.Read(context.Background())
+	if err != nil {
+		return err
+	}
+	return viper.ReadConfig(bytes.NewReader(value))
+}
+
+func (s RemoteSource) String() string {
+	return s.Provider + " key " + s.Key + " at " + s.Endpoint
+}
+
+// Validate checks that a remote source names a key of a supported transport
+func (s RemoteSource) Validate() error {
+	if !strings.HasPrefix(s.Endpoint, "http://") && !strings.HasPrefix(s.Endpoint, "https://") {
+		return fmt.Errorf("unsupported transport in %s, must be http or https", s.Endpoint)
+	}
+	if s.Key == "" {
+		return fmt.Errorf("no key given for %s at %s", s.Provider, s.Endpoint)
+	}
+	return nil
+}
+
+// Read fetches the configuration stored under the key
+func (s RemoteSource) Read(ctx context.Context) ([]byte, error) {
+	if s.Provider == "consul" {
+		value, _, err := s.consulGet(ctx, 0)
+		return value, err
+	}
+	value, _, err := s.etcdRange(ctx)
+	return value, err
+}
+
+// Watch calls changed whenever the value under the key changes, until ctx is
+// done or the connection fails
+func (s RemoteSource) Watch(ctx context.Context, changed func()) error {
+	if s.Provider == "consul" {
+		return s.consulWatch(ctx, changed)
+	}
+	return s.etcdWatch(ctx, changed)
+}
+
+func (s RemoteSource) do(client *http.Client, req *http.Request) (*http.Response, error) {
+	if token := os.Getenv("CONSUL_HTTP_TOKEN"); s.Provider == "consul" && token != "" {
+		req.Header.Set("X-Consul-Token", token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query %s, %s", s.Provider, err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, fmt.Errorf("key %s not found in %s", s.Key, s.Provider)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unable to read key %s from %s, %s", s.Key, s.Provider, resp.Status)
+	}
+	return resp, nil
+}
+
+// consulGet reads the key, blocking until its index passes index when that
+// is not zero, and returns its value and index
+func (s RemoteSource) consulGet(ctx context.Context, index uint64) ([]byte, uint64, error) {
+	query := url.Values{"raw": {""}}
+	client := remoteClient
+	if index != 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", consulWait.String())
+		client = watchClient
+	}
+	target := strings.TrimSuffix(s.Endpoint, "/") + "/v1/kv/" + strings.TrimPrefix(s.Key, "/") + "?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to create Consul request, %s", err)
+	}
+	resp, err := s.do(client, req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	value, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to read key %s from Consul, %s", s.Key, err)
+	}
+	index, _ = strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	return value, index, nil
+}
+
+func (s RemoteSource) consulWatch(ctx context.Context, changed func()) error {
+	_, index, err := s.consulGet(ctx, 0)
+	if err != nil {
+		return err
+	}
+	for {
+		_, next, err := s.consulGet(ctx, index)
+		if err != nil {
+			return err
+		}
+		switch {
+		case next > index:
+			changed()
+		case next < index:
+			// The index went backwards, e.g. after a restore; start over
+			next = 0
+		}
+		index = next
+	}
+}
+
+// etcdPost posts a JSON request to the etcd v3 gRPC gateway
+func (s RemoteSource) etcdPost(ctx context.Context, client *http.Client, path string, body interface{}) (*http.Response, error) {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode etcd request, %s", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(s.Endpoint, "/")+path, bytes.NewReader(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create etcd request, %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return s.do(client, req)
+}
+
+// etcdRange reads the key and returns its value and the revision it was
+// last modified at
+func (s RemoteSource) etcdRange(ctx context.Context) ([]byte, int64, error) {
+	resp, err := s.etcdPost(ctx, remoteClient, "/v3/kv/range", map[string]string{
+		"key": base64.StdEncoding.EncodeToString([]byte(s.Key)),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	var result struct {
+		Kvs []struct {
+			Value       []byte `json:"value"`
+			ModRevision int64  `json:"mod_revision,string"`
+		} `json:"kvs"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to decode etcd response, %s", err)
+	}
+	if len(result.Kvs) == 0 {
+		return nil, 0, fmt.Errorf("key %s not found in etcd", s.Key)
+	}
+	return result.Kvs[0].Value, result.Kvs[0].ModRevision, nil
+}
+
+func (s RemoteSource) etcdWatch(ctx context.Context, changed func()) error {
+	_, revision, err := s.etcdRange(ctx)
+	if err != nil {
+		return err
+	}
+	resp, err := s.etcdPost(ctx, watchClient, "/v3/watch", map[string]interface{}{
+		"create_request": map[string]interface{}{
+			"key":            base64.StdEncoding.EncodeToString([]byte(s.Key)),
+			"start_revision": strconv.FormatInt(revision+1, 10),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var message struct {
+			Result struct {
+				Canceled bool              `json:"canceled"`
+				Reason   string            `json:"cancel_reason"`
+				Events   []json.RawMessage `json:"events"`
+			} `json:"result"`
+		}
+		err := decoder.Decode(&message)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("unable to read etcd watch, %s", err)
+		}
+		if message.Result.Canceled {
+			return fmt.Errorf("etcd canceled the watch, %s", message.Result.Reason)
+		}
+		if len(message.Result.Events) > 0 {
+			changed()
+		}
+	}
+}
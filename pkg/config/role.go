@@ -0,0 +1,43 @@
+package config
+
+import (
+	"fmt"
+)
+
+// Role selects the components the daemon runs. The collector computes
+// samples and writes them to InfluxDB and the local outputs; the server
+// computes the same samples without writing them and serves the HTTP API,
+// rules, event notifications, the digest and the display. An empty role runs
+// both in one process.
+type Role string
+
+const (
+	RoleAll       Role = ""
+	RoleCollector Role = "collector"
+	RoleServer    Role = "server"
+)
+
+func (r Role) Validate() error {
+	switch r {
+	case RoleAll, RoleCollector, RoleServer:
+		return nil
+	}
+	return fmt.Errorf("invalid role %q, expected collector, server or empty for both", r)
+}
+
+// Collects reports whether the role writes samples
+func (r Role) Collects() bool {
+	return r != RoleServer
+}
+
+// Serves reports whether the role runs the API and notification components
+func (r Role) Serves() bool {
+	return r != RoleCollector
+}
+
+func (r Role) String() string {
+	if r == RoleAll {
+		return "collector and server"
+	}
+	return string(r)
+}
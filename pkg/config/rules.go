@@ -0,0 +1,169 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule is a declarative alert. A condition rule sets When, e.g.
+// "day_length < 9h", and fires whenever the condition becomes true; an event
+// rule sets At, a sun event with an optional offset such as "sunset-15m", and
+// fires when that moment passes.
+type Rule struct {
+	Name    string
+	When    string
+	At      string
+	Message string
+	Notify  []string
+
+	condition *ruleCondition
+	event     *Countdown
+}
+
+// ruleCondition compares a sample field, or day_length, against a value
+type ruleCondition struct {
+	field    string
+	operator string
+	number   float64
+	boolean  *bool
+}
+
+var ruleOperators = []string{"<=", ">=", "==", "!=", "<", ">"}
+
+// parseRuleCondition parses "<field> <operator> <value>", where the value is
+// a number, a duration compared as seconds, or true or false
+func parseRuleCondition(when string) (*ruleCondition, error) {
+	for _, operator := range ruleOperators {
+		field, value, ok := strings.Cut(when, operator)
+		if !ok {
+			continue
+		}
+		condition := &ruleCondition{field: strings.TrimSpace(field), operator: operator}
+		value = strings.TrimSpace(value)
+		if condition.field == "" || value == "" {
+			break
+		}
+		if b, err := strconv.ParseBool(value); err == nil {
+			if operator != "==" && operator != "!=" {
+				return nil, fmt.Errorf("booleans can only be compared with == or !=")
+			}
+			condition.boolean = &b
+		} else if n, err := strconv.ParseFloat(value, 64); err == nil {
+			condition.number = n
+		} else if d, err := time.ParseDuration(value); err == nil {
+			condition.number = d.Seconds()
+		} else {
+			return nil, fmt.Errorf("invalid value %q, expected a number, duration or boolean", value)
+		}
+		return condition, nil
+	}
+	return nil, fmt.Errorf("expected <field> <operator> <value> with one of the operators %s", strings.Join(ruleOperators, " "))
+}
+
+// holds reports whether the condition holds for a value; values of the wrong
+// type or missing ones never match
+func (c *ruleCondition) holds(value interface{}) bool {
+	if c.boolean != nil {
+		b, ok := value.(bool)
+		if !ok {
+			return false
+		}
+		return (b == *c.boolean) == (c.operator == "==")
+	}
+	var n float64
+	switch v := value.(type) {
+	case float64:
+		n = v
+	case int64:
+		n = float64(v)
+	default:
+		return false
+	}
+	switch c.operator {
+	case "<":
+		return n < c.number
+	case "<=":
+		return n <= c.number
+	case ">":
+		return n > c.number
+	case ">=":
+		return n >= c.number
+	case "==":
+		return n == c.number
+	}
+	return n != c.number
+}
+
+// Field returns the sample field the condition of a parsed rule compares,
+// empty for a rule at a sun event
+func (r Rule) Field() string {
+	if r.condition == nil {
+		return ""
+	}
+	return r.condition.field
+}
+
+// Holds reports whether the condition of a parsed rule holds for a value of
+// its field
+func (r Rule) Holds(value interface{}) bool {
+	return r.condition != nil && r.condition.holds(value)
+}
+
+// Event returns the sun event of a parsed rule set with At, nil for a
+// condition
+func (r Rule) Event() *Countdown {
+	return r.event
+}
+
+// ParsedRules returns the rules parsed from Rules while loading
+func (config Configuration) ParsedRules() []Rule {
+	return config.rules
+}
+
+// ParseRules validates the rules and the notifiers they refer to
+func ParseRules(rules []Rule, notifiers map[string]Notifier) ([]Rule, error) {
+	for name, notifier := range notifiers {
+		err := notifier.Validate(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	parsed := make([]Rule, 0, len(rules))
+	names := map[string]bool{}
+	for i, rule := range rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("rule %d has no name", i+1)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("rule %s is defined more than once", rule.Name)
+		}
+		names[rule.Name] = true
+		if (rule.When == "") == (rule.At == "") {
+			return nil, fmt.Errorf("rule %s must set exactly one of when or at", rule.Name)
+		}
+		if rule.When != "" {
+			condition, err := parseRuleCondition(rule.When)
+			if err != nil {
+				return nil, fmt.Errorf("invalid condition of rule %s, %s", rule.Name, err)
+			}
+			rule.condition = condition
+		} else {
+			event, err := ParseCountdown(rule.Name, rule.At)
+			if err != nil {
+				return nil, fmt.Errorf("invalid event of rule %s, %s", rule.Name, err)
+			}
+			rule.event = &event
+		}
+		for i, notifier := range rule.Notify {
+			// viper lower cases the keys of the notifiers map
+			rule.Notify[i] = strings.ToLower(notifier)
+			if _, ok := notifiers[rule.Notify[i]]; !ok {
+				return nil, fmt.Errorf("rule %s refers to unknown notifier %s", rule.Name, notifier)
+			}
+		}
+		parsed = append(parsed, rule)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,10 @@
+package config
+
+type LightingSchedule struct {
+	Twilight   string
+	OnOffset   int
+	OffOffset  int
+	EarliestOn string
+	LatestOff  string
+	Timezone   string
+}
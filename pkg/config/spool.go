@@ -0,0 +1,40 @@
+package config
+
+import (
+	"fmt"
+)
+
+// Spool configures store-and-forward delivery: samples are appended to a
+// local store on disk as line protocol and transports drain it to their
+// destinations whenever those are reachable, so computation never waits on
+// delivery
+type Spool struct {
+	Directory   string
+	SegmentSize int64
+	MaxSize     int64
+	BatchSize   int
+}
+
+func (s Spool) Enabled() bool {
+	return s.Directory != ""
+}
+
+func (s Spool) WithDefaults() Spool {
+	if s.SegmentSize == 0 {
+		s.SegmentSize = 1 << 20
+	}
+	if s.MaxSize == 0 {
+		s.MaxSize = 100 << 20
+	}
+	if s.BatchSize == 0 {
+		s.BatchSize = 500
+	}
+	return s
+}
+
+func (s Spool) Validate() error {
+	if s.SegmentSize < 0 || s.MaxSize < 0 || s.BatchSize < 0 {
+		return fmt.Errorf("spool sizes must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,6 @@
+package config
+
+type StatusConfig struct {
+	File   string
+	MaxAge uint
+}
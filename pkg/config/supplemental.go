@@ -0,0 +1,36 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// SupplementalLight writes, once per day, the hours of artificial light
+// needed on top of the natural day length to reach TargetPhotoperiod, as
+// billed by agricultural lighting contracts. With a Schedule it also acts as
+// a photoperiod controller, switching the light on before sunrise (morning),
+// after sunset (evening) or half of the time each (split).
+type SupplementalLight struct {
+	TargetPhotoperiod time.Duration
+	Schedule          string
+}
+
+func (s SupplementalLight) Enabled() bool {
+	return s.TargetPhotoperiod != 0
+}
+
+// Controls reports whether the photoperiod controller is enabled
+func (s SupplementalLight) Controls() bool {
+	return s.Enabled() && s.Schedule != ""
+}
+
+func (s SupplementalLight) Validate() error {
+	if s.TargetPhotoperiod < 0 || s.TargetPhotoperiod > 24*time.Hour {
+		return fmt.Errorf("supplementalLight.targetPhotoperiod must be between 0 and 24h")
+	}
+	switch s.Schedule {
+	case "", "morning", "evening", "split":
+		return nil
+	}
+	return fmt.Errorf("unknown supplementalLight.schedule %q, expected morning, evening or split", s.Schedule)
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Threshold is a named boolean field telling whether the sun is at or above
+// an elevation, e.g. lights_on = -4° or solar_panels = +10°
+type Threshold = daylight.Threshold
+
+// ParseThreshold parses an elevation in degrees such as -4, -4° or +10deg
+func ParseThreshold(name, elevation string) (Threshold, error) {
+	text := strings.TrimSpace(elevation)
+	text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(text, "°"), "deg"))
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return Threshold{}, fmt.Errorf("invalid threshold %s, elevation %q is not a number of degrees", name, elevation)
+	}
+	if value < -90 || value > 90 {
+		return Threshold{}, fmt.Errorf("invalid threshold %s, elevation %g out of range [-90, 90]", name, value)
+	}
+	for _, field := range daylight.SampleFields {
+		if field == name {
+			return Threshold{}, fmt.Errorf("invalid threshold %s, the name is taken by a built-in field", name)
+		}
+	}
+	return Threshold{Name: name, Elevation: value}, nil
+}
+
+// ParseThresholds parses the configured thresholds, sorted by name
+func ParseThresholds(elevations map[string]string) ([]Threshold, error) {
+	thresholds := make([]Threshold, 0, len(elevations))
+	for name, elevation := range elevations {
+		threshold, err := ParseThreshold(name, elevation)
+		if err != nil {
+			return nil, err
+		}
+		thresholds = append(thresholds, threshold)
+	}
+	sort.Slice(thresholds, func(i, j int) bool {
+		return thresholds[i].Name < thresholds[j].Name
+	})
+	return thresholds, nil
+}
+
+// ElevationBand is a range of solar elevations in degrees, from Low up to
+// but excluding High, such as the golden and blue hours
+type ElevationBand = daylight.ElevationBand
+
+// The golden hour, when the low sun gives warm light, and the blue hour of
+// deep twilight before it in the morning and after it in the evening
+var (
+	DefaultGoldenHour = ElevationBand{Low: -4, High: 6}
+	DefaultBlueHour   = ElevationBand{Low: -6, High: -4}
+)
+
+// ParsedThresholds returns the thresholds parsed from Thresholds while loading
+func (config Configuration) ParsedThresholds() []Threshold {
+	return config.thresholds
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// WakingHours is the local daily window, as HH:MM, in which the user is awake;
+// End before Start spans midnight
+type WakingHours struct {
+	Start string
+	End   string
+}
+
+func (w WakingHours) Enabled() bool {
+	return w.Start != "" || w.End != ""
+}
+
+func (w WakingHours) Validate() error {
+	for _, clock := range []string{w.Start, w.End} {
+		if _, err := time.Parse("15:04", clock); err != nil {
+			return fmt.Errorf("invalid waking hours time %q, expected HH:MM", clock)
+		}
+	}
+	return nil
+}
+
+// Window returns the waking window starting on the local day of t
+func (w WakingHours) Window(t time.Time) (time.Time, time.Time) {
+	start, _ := time.Parse("15:04", w.Start)
+	end, _ := time.Parse("15:04", w.End)
+	windowStart := time.Date(t.Year(), t.Month(), t.Day(), start.Hour(), start.Minute(), 0, 0, t.Location())
+	windowEnd := time.Date(t.Year(), t.Month(), t.Day(), end.Hour(), end.Minute(), 0, 0, t.Location())
+	if !windowEnd.After(windowStart) {
+		windowEnd = windowEnd.AddDate(0, 0, 1)
+	}
+	return windowStart, windowEnd
+}
+
+// WakingDaylight returns how much of the waking window starting on the day of
+// t lies between sunrise and sunset; on days without either the sun is up
+// since midnight or until the next one, and the whole window is daylight
+// during polar day
+func WakingDaylight(wakingHours WakingHours, sunriseTime, sunsetTime time.Time, polarDay bool, t time.Time) time.Duration {
+	windowStart, windowEnd := wakingHours.Window(t)
+	if sunriseTime.IsZero() && sunsetTime.IsZero() {
+		if polarDay {
+			return windowEnd.Sub(windowStart)
+		}
+		return 0
+	}
+	start := windowStart
+	if !sunriseTime.IsZero() && sunriseTime.After(start) {
+		start = sunriseTime
+	}
+	end := windowEnd
+	if !sunsetTime.IsZero() && sunsetTime.Before(end) {
+		end = sunsetTime
+	}
+	if end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
@@ -0,0 +1,24 @@
+package config
+
+import (
+	"fmt"
+)
+
+// WriteMode decides which samples the collector writes
+type WriteMode string
+
+const (
+	// WriteInterval writes every sample, once per pollInterval
+	WriteInterval WriteMode = "interval"
+	// WriteTransitions writes a sample only when a boolean field changed, and
+	// otherwise once per heartbeat when one is configured
+	WriteTransitions WriteMode = "transitions"
+)
+
+func (m WriteMode) Validate() error {
+	switch m {
+	case "", WriteInterval, WriteTransitions:
+		return nil
+	}
+	return fmt.Errorf("unknown writeMode %q, expected interval or transitions", m)
+}
@@ -0,0 +1,94 @@
+package daylight
+
+import (
+	"time"
+)
+
+// Countdown is a named field counting the seconds until a sun event plus an
+// offset, e.g. pool_pump_start = sunrise+2h
+type Countdown struct {
+	Name   string
+	Event  string
+	Offset time.Duration
+}
+
+// BuiltInCountdowns are the countdown fields every sample carries
+var BuiltInCountdowns = []Countdown{
+	{Name: "seconds_until_sunrise", Event: "sunrise"},
+	{Name: "seconds_until_sunset", Event: "sunset"},
+}
+
+// CountdownEvents lists the sun events a countdown can be anchored to
+var CountdownEvents = []string{
+	"astronomical_dawn",
+	"nautical_dawn",
+	"civil_dawn",
+	"sunrise",
+	"sunset",
+	"civil_dusk",
+	"nautical_dusk",
+	"astronomical_dusk",
+}
+
+// Event returns the time of a named sun event, which is zero when the event
+// does not happen that day
+func (e SunEvents) Event(name string) time.Time {
+	switch name {
+	case "astronomical_dawn":
+		return e.AstronomicalDawn
+	case "nautical_dawn":
+		return e.NauticalDawn
+	case "civil_dawn":
+		return e.CivilDawn
+	case "sunrise":
+		return e.Sunrise
+	case "sunset":
+		return e.Sunset
+	case "civil_dusk":
+		return e.CivilDusk
+	case "nautical_dusk":
+		return e.NauticalDusk
+	case "astronomical_dusk":
+		return e.AstronomicalDusk
+	}
+	return time.Time{}
+}
+
+// NextCountdown returns the next time the countdown reaches zero after now, looking at
+// the anchors of the given days in order; it is zero when none of them has
+// the event, e.g. during polar day or night
+func NextCountdown(c Countdown, now time.Time, days []SunEvents) time.Time {
+	for _, events := range days {
+		event := events.Event(c.Event)
+		if event.IsZero() {
+			continue
+		}
+		if t := event.Add(c.Offset); t.After(now) {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// NextEvent returns the first sun event after now among the named ones, or
+// among all of CountdownEvents when none is named; the name is empty when
+// none happens within the next two days, e.g. during polar day or night
+func NextEvent(opts Options, now time.Time, names ...string) (string, time.Time) {
+	if len(names) == 0 {
+		names = CountdownEvents
+	}
+	var next string
+	var at time.Time
+	day := SolarDate(now, opts.Longitude)
+	for offset := -1; offset <= 2 && next == ""; offset++ {
+		d := day.AddDate(0, 0, offset)
+		events := DayEvents(opts, d.Year(), d.Month(), d.Day())
+		for _, name := range names {
+			t := events.Event(name)
+			if t.After(now) && (next == "" || t.Before(at)) {
+				next, at = name, t
+			}
+		}
+	}
+	return next, at
+}
@@ -0,0 +1,70 @@
+package daylight
+
+import (
+	"math"
+	"time"
+)
+
+// CrossCheck reports whether the engines disagree on the sunrise or sunset of day
+// by more than threshold (five minutes when zero), logging the disagreement.
+// Days on which either engine has no sunrise or sunset are not compared,
+// since near the polar circles the engines may differ on whether the sun
+// rises at all.
+func CrossCheck(threshold time.Duration, latitude, longitude, altitude float64, day time.Time, sunrise, sunset time.Time) bool {
+	if threshold == 0 {
+		threshold = 5 * time.Minute
+	}
+	noaaSunrise, noaaSunset := NOAASunriseSunset(latitude, longitude, altitude, day.Year(), day.Month(), day.Day())
+
+	mismatch := false
+	for _, event := range []struct {
+		name       string
+		used, noaa time.Time
+	}{
+		{"sunrise", sunrise, noaaSunrise},
+		{"sunset", sunset, noaaSunset},
+	} {
+		if event.used.IsZero() || event.noaa.IsZero() {
+			continue
+		}
+		delta := event.used.Sub(event.noaa)
+		if delta.Abs() <= threshold {
+			continue
+		}
+		mismatch = true
+		Log.Warn("CrossCheck.Check", "astronomy engines disagree", map[string]interface{}{
+			"date":      day.Format("2006-01-02"),
+			"event":     event.name,
+			"used":      event.used.UTC().Format(time.RFC3339),
+			"noaa":      event.noaa.UTC().Format(time.RFC3339),
+			"delta":     delta.Round(time.Second).String(),
+			"threshold": threshold.String(),
+		})
+	}
+	return mismatch
+}
+
+// NOAASunriseSunset computes the sunrise and sunset of a day from the NOAA
+// solar position equations, independently of go-sunrise. Each event is
+// refined by recomputing the declination and equation of time at the previous
+// estimate. Events that do not happen are zero.
+func NOAASunriseSunset(latitude, longitude, altitude float64, year int, month time.Month, day int) (time.Time, time.Time) {
+	midnight := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	event := func(direction float64) time.Time {
+		t := midnight.Add(time.Duration((720 - 4*longitude) * float64(time.Minute)))
+		for i := 0; i < 3; i++ {
+			position := CalculateSolarPosition(latitude, longitude, t)
+			lat := latitude * degree
+			dec := position.Declination * degree
+			cosHourAngle := (math.Sin(SunriseElevation(altitude)*degree) - math.Sin(lat)*math.Sin(dec)) / (math.Cos(lat) * math.Cos(dec))
+			if cosHourAngle < -1 || cosHourAngle > 1 {
+				return time.Time{}
+			}
+			hourAngle := math.Acos(cosHourAngle) / degree
+			minutes := 720 - 4*longitude - position.EquationOfTime + direction*4*hourAngle
+			t = midnight.Add(time.Duration(minutes * float64(time.Minute)))
+		}
+		return t
+	}
+	return event(-1), event(1)
+}
@@ -0,0 +1,59 @@
+package daylight
+
+import (
+	"time"
+)
+
+// DaylightAt answers whether it was, or will be, daylight at an instant at
+// the configured location, e.g. for incident investigations
+type DaylightAt struct {
+	Time           time.Time  `json:"time"`
+	Daylight       bool       `json:"daylight"`
+	DaylightOffset bool       `json:"daylight_offset"`
+	Phase          string     `json:"phase"`
+	Elevation      float64    `json:"elevation"`
+	Sunrise        *time.Time `json:"sunrise,omitempty"`
+	Sunset         *time.Time `json:"sunset,omitempty"`
+}
+
+// SunPhase names the part of the day the sun's elevation falls in: day,
+// civil_twilight, nautical_twilight, astronomical_twilight or night
+func SunPhase(elevation float64) string {
+	switch {
+	case elevation >= sunriseElevation:
+		return "day"
+	case elevation >= CivilTwilight:
+		return "civil_twilight"
+	case elevation >= NauticalTwilight:
+		return "nautical_twilight"
+	case elevation >= AstronomicalTwilight:
+		return "astronomical_twilight"
+	}
+	return "night"
+}
+
+// CalculateDaylightAt computes the daylight state at t analytically, the same way the
+// poller computes samples
+func CalculateDaylightAt(opts Options, t time.Time) DaylightAt {
+	day := SolarDate(t, opts.Longitude)
+	sunrise, sunset := SunriseSunset(opts, day.Year(), day.Month(), day.Day())
+	position := CalculateSolarPosition(opts.Latitude, opts.Longitude, t)
+	daylight, daylightOffset := Daylight(sunrise, sunset, SunUp(position.Elevation, opts.Altitude), t, opts.TimeOffset)
+
+	result := DaylightAt{
+		Time:           t,
+		Daylight:       daylight,
+		DaylightOffset: daylightOffset,
+		Phase:          SunPhase(position.Elevation),
+		Elevation:      position.Elevation,
+	}
+	if !sunrise.IsZero() {
+		sunrise = sunrise.In(t.Location())
+		result.Sunrise = &sunrise
+	}
+	if !sunset.IsZero() {
+		sunset = sunset.In(t.Location())
+		result.Sunset = &sunset
+	}
+	return result
+}
@@ -0,0 +1,5 @@
+// Package daylight computes the daylight state of a location: the positions
+// of the sun and moon, the sun events and twilights of each day, and the
+// samples and transitions a Poller derives from them. Subscribe delivers the
+// transitions on a channel as they happen.
+package daylight
@@ -0,0 +1,223 @@
+package daylight
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const ephemerisHeader = "date,astronomical_dawn,nautical_dawn,civil_dawn,sunrise,sunset,civil_dusk,nautical_dusk,astronomical_dusk"
+
+// Ephemeris is a pre-generated table of sun events keyed by 2006-01-02 date
+type Ephemeris struct {
+	Latitude  float64
+	Longitude float64
+	Altitude  float64
+	Days      map[string]SunEvents
+}
+
+// WriteEphemeris writes sun events for the given number of days starting at
+// from. Times are Unix seconds so that minimal readers need no time parsing;
+// events that do not happen are left empty.
+func WriteEphemeris(w io.Writer, latitude, longitude, altitude float64, from time.Time, days int) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "# daylight-timeseries ephemeris latitude=%f longitude=%f altitude=%f\n", latitude, longitude, altitude)
+	fmt.Fprintln(out, ephemerisHeader)
+
+	for i := 0; i < days; i++ {
+		d := from.AddDate(0, 0, i)
+		e := CalculateSunEvents(latitude, longitude, altitude, d.Year(), d.Month(), d.Day())
+		fmt.Fprint(out, d.Format("2006-01-02"))
+		for _, t := range []time.Time{
+			e.AstronomicalDawn, e.NauticalDawn, e.CivilDawn, e.Sunrise,
+			e.Sunset, e.CivilDusk, e.NauticalDusk, e.AstronomicalDusk,
+		} {
+			if t.IsZero() {
+				fmt.Fprint(out, ",")
+			} else {
+				fmt.Fprintf(out, ",%d", t.Unix())
+			}
+		}
+		fmt.Fprintln(out)
+	}
+
+	return out.Flush()
+}
+
+// ReadEphemeris loads a complete ephemeris file
+func ReadEphemeris(r io.Reader) (*Ephemeris, error) {
+	ephemeris := &Ephemeris{Days: map[string]SunEvents{}}
+	err := ScanEphemeris(r, func(comment string) {
+		// Files written before altitude was configurable leave it out
+		fmt.Sscanf(comment, "daylight-timeseries ephemeris latitude=%f longitude=%f altitude=%f",
+			&ephemeris.Latitude, &ephemeris.Longitude, &ephemeris.Altitude)
+	}, func(date string, events SunEvents) bool {
+		ephemeris.Days[date] = events
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ephemeris, nil
+}
+
+// FindEphemerisDay scans an ephemeris file for a single date without loading
+// the rest of it
+func FindEphemerisDay(r io.Reader, date string) (SunEvents, error) {
+	var found *SunEvents
+	err := ScanEphemeris(r, nil, func(d string, events SunEvents) bool {
+		if d == date {
+			found = &events
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return SunEvents{}, err
+	}
+	if found == nil {
+		return SunEvents{}, fmt.Errorf("date %s not in ephemeris", date)
+	}
+	return *found, nil
+}
+
+// ScanEphemeris reads an ephemeris file line by line, passing the text of
+// comment lines to comment, when not nil, and the date and sun events of
+// each row to row until it returns false
+func ScanEphemeris(r io.Reader, comment func(string), row func(string, SunEvents) bool) error {
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text == ephemerisHeader {
+			continue
+		}
+		if strings.HasPrefix(text, "#") {
+			if comment != nil {
+				comment(strings.TrimSpace(strings.TrimPrefix(text, "#")))
+			}
+			continue
+		}
+
+		record, err := csv.NewReader(strings.NewReader(text)).Read()
+		if err != nil {
+			return fmt.Errorf("ephemeris line %d: %s", line, err)
+		}
+		if len(record) != 9 {
+			return fmt.Errorf("ephemeris line %d: expected 9 columns, got %d", line, len(record))
+		}
+		var times [8]time.Time
+		for i, value := range record[1:] {
+			if value == "" {
+				continue
+			}
+			seconds, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("ephemeris line %d: invalid time %q", line, value)
+			}
+			times[i] = time.Unix(seconds, 0).UTC()
+		}
+		events := SunEvents{
+			AstronomicalDawn: times[0],
+			NauticalDawn:     times[1],
+			CivilDawn:        times[2],
+			Sunrise:          times[3],
+			Sunset:           times[4],
+			CivilDusk:        times[5],
+			NauticalDusk:     times[6],
+			AstronomicalDusk: times[7],
+		}
+		if !row(record[0], events) {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("unable to read ephemeris, %s", err)
+	}
+	return nil
+}
+
+// LoadEphemeris reads an ephemeris file and checks that it was generated for
+// the given location
+func LoadEphemeris(path string, latitude, longitude, altitude float64) (*Ephemeris, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open ephemeris file %s, %s", path, err)
+	}
+	defer f.Close()
+
+	ephemeris, err := ReadEphemeris(f)
+	if err != nil {
+		return nil, err
+	}
+	if math.Abs(ephemeris.Latitude-latitude) > 1e-6 || math.Abs(ephemeris.Longitude-longitude) > 1e-6 {
+		return nil, fmt.Errorf("ephemeris file %s was generated for %f,%f, not the configured location", path, ephemeris.Latitude, ephemeris.Longitude)
+	}
+	if math.Abs(ephemeris.Altitude-altitude) > 1e-3 {
+		return nil, fmt.Errorf("ephemeris file %s was generated for an altitude of %gm, not the configured %gm", path, ephemeris.Altitude, altitude)
+	}
+	return ephemeris, nil
+}
+
+// ephemerides are the ephemeris files loaded by UseEphemeris, by path
+var ephemerides sync.Map
+
+// UseEphemeris loads the ephemeris file of opts, if it has one, for
+// SunEvents to read the days it covers
+func UseEphemeris(opts Options) error {
+	if opts.EphemerisFile == "" {
+		return nil
+	}
+	ephemeris, err := LoadEphemeris(opts.EphemerisFile, opts.Latitude, opts.Longitude, opts.Altitude)
+	if err != nil {
+		return err
+	}
+	ephemerides.Store(opts.EphemerisFile, ephemeris)
+	return nil
+}
+
+// ephemerisFor returns the loaded ephemeris of opts, nil when none
+// was loaded or it was generated for other coordinates, as for named locations
+func ephemerisFor(opts Options) *Ephemeris {
+	if opts.EphemerisFile == "" {
+		return nil
+	}
+	value, ok := ephemerides.Load(opts.EphemerisFile)
+	if !ok {
+		return nil
+	}
+	ephemeris := value.(*Ephemeris)
+	if math.Abs(ephemeris.Latitude-opts.Latitude) > 1e-6 || math.Abs(ephemeris.Longitude-opts.Longitude) > 1e-6 || math.Abs(ephemeris.Altitude-opts.Altitude) > 1e-3 {
+		return nil
+	}
+	return ephemeris
+}
+
+// DayEvents returns the sun events for a date, from the ephemeris when one is
+// loaded and it covers the date, computing them otherwise
+func DayEvents(opts Options, year int, month time.Month, day int) SunEvents {
+	if ephemeris := ephemerisFor(opts); ephemeris != nil {
+		date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+		if events, ok := ephemeris.Days[date]; ok {
+			return events
+		}
+		Log.Warn("DayEvents", "date not covered by ephemeris file, computing instead", map[string]interface{}{
+			"date": date,
+		})
+	}
+	return CalculateSunEvents(opts.Latitude, opts.Longitude, opts.Altitude, year, month, day)
+}
+
+// SunriseSunset returns the sunrise and sunset for a date
+func SunriseSunset(opts Options, year int, month time.Month, day int) (time.Time, time.Time) {
+	events := DayEvents(opts, year, month, day)
+	return events.Sunrise, events.Sunset
+}
@@ -0,0 +1,59 @@
+package daylight
+
+// SampleFields lists every field a Sample may carry; each can be turned off
+// through Options.Fields
+var SampleFields = []string{
+	"daylight",
+	"daylight_offset",
+	"elevation",
+	"azimuth",
+	"sun_phase",
+	"civil_daylight",
+	"nautical_daylight",
+	"astronomical_daylight",
+	"civil_dawn",
+	"civil_dusk",
+	"nautical_dawn",
+	"nautical_dusk",
+	"astronomical_dawn",
+	"astronomical_dusk",
+	"declination",
+	"equation_of_time",
+	"day_length_seconds",
+	"day_length_delta_seconds",
+	"tomorrow_sunrise",
+	"tomorrow_sunset",
+	"seconds_until_sunrise",
+	"seconds_until_sunset",
+	"elevation_rate",
+	"waking_daylight_seconds",
+	"working_daylight_seconds",
+	"color_temperature",
+	"exposure_bias",
+	"exposure_iso",
+	"supplemental_light",
+	"data_quality",
+	"local_time",
+	"bmct",
+	"bmct_local",
+	"eect",
+	"eect_local",
+	"utc_offset",
+	"moon_elevation",
+	"moon_illumination",
+	"moon_phase",
+	"moonlight_lux",
+	"moon_phase_angle",
+	"moon_transit",
+	"moon_lower_transit",
+	"moonrise",
+	"moonset",
+	"solar_noon",
+	"solar_midnight",
+	"minutes_to_solar_noon",
+	"polar_day",
+	"polar_night",
+	"golden_hour",
+	"blue_hour",
+	"clock_offset_seconds",
+}
@@ -0,0 +1,59 @@
+package daylight
+
+import (
+	"time"
+)
+
+// ForecastMeasurement is the measurement of the daily forecast points
+const ForecastMeasurement = Measurement + "_forecast"
+
+// ForecastPoller produces the forecast samples once per solar date
+type ForecastPoller struct {
+	Options Options
+	date    string
+}
+
+// NewForecastPoller returns a poller of the forecast samples of the
+// ForecastDays of opts, which its first poll returns
+func NewForecastPoller(opts Options) *ForecastPoller {
+	return &ForecastPoller{Options: opts}
+}
+
+// Poll returns the forecast samples from the solar date of now on when they
+// were not returned yet that day, that is at startup and on the first poll of
+// every day, so the forecast is recomputed with the newest day appended
+func (p *ForecastPoller) Poll(now time.Time) []Sample {
+	day := SolarDate(now, p.Options.Longitude)
+	date := day.Format("2006-01-02")
+	if date == p.date {
+		return nil
+	}
+	p.date = date
+	samples := make([]Sample, 0, p.Options.ForecastDays)
+	for i := 0; i < p.Options.ForecastDays; i++ {
+		samples = append(samples, ForecastSample(p.Options, day.AddDate(0, 0, i)))
+	}
+	return samples
+}
+
+// ForecastSample computes the forecast sample of a solar date, stamped with
+// the local solar midnight starting it so that the point of a date written
+// again replaces the earlier one. Events that do not happen that date, e.g.
+// during polar day or night, are left out.
+func ForecastSample(opts Options, day time.Time) Sample {
+	events := DayEvents(opts, day.Year(), day.Month(), day.Day())
+	sample := Sample{
+		Measurement: ForecastMeasurement,
+		Time:        day.Add(-time.Duration(opts.Longitude / 15 * float64(time.Hour))),
+		Tags:        opts.tags(),
+		Fields: map[string]interface{}{
+			"day_length_seconds": DayLength(opts.Latitude, opts.Longitude, events, day.Year(), day.Month(), day.Day()).Seconds(),
+		},
+	}
+	for _, name := range CountdownEvents {
+		if event := events.Event(name); !event.IsZero() {
+			sample.Fields[name] = event.Unix()
+		}
+	}
+	return sample
+}
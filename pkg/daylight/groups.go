@@ -0,0 +1,95 @@
+package daylight
+
+import (
+	"time"
+)
+
+// GroupMeasurement is the InfluxDB measurement location group aggregates are
+// written to, tagged with the group name
+const GroupMeasurement = Measurement + "_group"
+
+// groupDay caches the day length and sun events of a group member for one
+// solar date
+type groupDay struct {
+	date      string
+	sunrise   time.Time
+	sunset    time.Time
+	dayLength time.Duration
+}
+
+// LocationGroup is a named fleet of locations whose daylight is aggregated
+// into one point per poll
+type LocationGroup struct {
+	Name      string
+	Locations []GroupLocation
+}
+
+// GroupLocation is one member of a location group
+type GroupLocation struct {
+	Name      string
+	Latitude  float64
+	Longitude float64
+}
+
+// GroupPoller computes the aggregate daylight of a location group, refreshing
+// the sun events of each member when its solar date changes
+type GroupPoller struct {
+	Group LocationGroup
+	days  []groupDay
+}
+
+// NewGroupPoller returns a poller of the aggregate samples of a group
+func NewGroupPoller(group LocationGroup) *GroupPoller {
+	return &GroupPoller{Group: group, days: make([]groupDay, len(group.Locations))}
+}
+
+// Poll computes the aggregate sample of the group for now: the number of
+// locations, how many of them are in daylight, and the earliest sunrise and
+// latest sunset of their current days as Unix seconds. Locations in polar day
+// count as in daylight; the sunrise and sunset fields are left out when no
+// location has one.
+func (g *GroupPoller) Poll(now time.Time) Sample {
+	var inDaylight int64
+	var earliestSunrise, latestSunset time.Time
+	for i, location := range g.Group.Locations {
+		day := SolarDate(now, location.Longitude)
+		date := day.Format("2006-01-02")
+		if g.days[i].date != date {
+			events := CalculateSunEvents(location.Latitude, location.Longitude, 0, day.Year(), day.Month(), day.Day())
+			g.days[i] = groupDay{
+				date:      date,
+				sunrise:   events.Sunrise,
+				sunset:    events.Sunset,
+				dayLength: DayLength(location.Latitude, location.Longitude, events, day.Year(), day.Month(), day.Day()),
+			}
+		}
+
+		d := g.days[i]
+		if daylight, _ := Daylight(d.sunrise, d.sunset, d.dayLength > 0, now, 0); daylight {
+			inDaylight++
+		}
+		if !d.sunrise.IsZero() && (earliestSunrise.IsZero() || d.sunrise.Before(earliestSunrise)) {
+			earliestSunrise = d.sunrise
+		}
+		if !d.sunset.IsZero() && d.sunset.After(latestSunset) {
+			latestSunset = d.sunset
+		}
+	}
+
+	sample := Sample{
+		Measurement: GroupMeasurement,
+		Time:        now,
+		Tags:        map[string]string{"group": g.Group.Name},
+		Fields: map[string]interface{}{
+			"locations":   int64(len(g.Group.Locations)),
+			"in_daylight": inDaylight,
+		},
+	}
+	if !earliestSunrise.IsZero() {
+		sample.Fields["earliest_sunrise"] = earliestSunrise.Unix()
+	}
+	if !latestSunset.IsZero() {
+		sample.Fields["latest_sunset"] = latestSunset.Unix()
+	}
+	return sample
+}
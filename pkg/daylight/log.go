@@ -0,0 +1,16 @@
+package daylight
+
+// Logger receives the warnings of the package, such as a date missing from
+// an ephemeris file or the astronomy engines disagreeing; op names the
+// function warning and fields add context
+type Logger interface {
+	Warn(op, msg string, fields map[string]interface{})
+}
+
+// Log is where the package logs to; warnings are dropped until a program
+// sets it
+var Log Logger = discardLogger{}
+
+type discardLogger struct{}
+
+func (discardLogger) Warn(op, msg string, fields map[string]interface{}) {}
@@ -0,0 +1,82 @@
+package daylight
+
+import (
+	"time"
+)
+
+// EarliestAfter returns the earliest of the non-zero instants after now, or
+// zero when there is none
+func EarliestAfter(now time.Time, instants ...time.Time) time.Time {
+	var earliest time.Time
+	for _, instant := range instants {
+		if instant.After(now) && (earliest.IsZero() || instant.Before(earliest)) {
+			earliest = instant
+		}
+	}
+	return earliest
+}
+
+// solarMidnight returns the end of the solar date of t at longitude
+func solarMidnight(t time.Time, longitude float64) time.Time {
+	return SolarDate(t, longitude).AddDate(0, 0, 1).Add(-time.Duration(longitude / 15 * float64(time.Hour)))
+}
+
+// NextChange returns the earliest instant after now at which a boolean field
+// of the poller's samples can change: one of today's or tomorrow's sun and
+// twilight events, shifted by timeOffset for daylight_offset, a
+// supplemental light window edge, a threshold crossing, the sun entering or
+// leaving the golden or blue hour, or the end of the
+// solar date, when the events are refreshed
+func (p *Poller) NextChange(now time.Time) time.Time {
+	offset := p.Options.TimeOffset
+	instants := []time.Time{solarMidnight(now, p.Options.Longitude)}
+	day := SolarDate(now, p.Options.Longitude)
+	for i, events := range []SunEvents{p.today, p.tomorrow} {
+		for _, name := range CountdownEvents {
+			instants = append(instants, events.Event(name))
+		}
+		if !events.Sunrise.IsZero() {
+			instants = append(instants, events.Sunrise.Add(offset))
+		}
+		if !events.Sunset.IsZero() {
+			instants = append(instants, events.Sunset.Add(-offset))
+		}
+		if p.Options.SupplementalLight.Controls() {
+			for _, window := range SupplementalWindows(p.Options.SupplementalLight, p.Options.Latitude, p.Options.Longitude, day.AddDate(0, 0, i), events.Sunrise, events.Sunset) {
+				instants = append(instants, window.On, window.Off)
+			}
+		}
+		for _, threshold := range p.Options.Thresholds {
+			rising, setting := ThresholdCrossings(threshold, p.Options.Latitude, p.Options.Longitude, day.AddDate(0, 0, i))
+			instants = append(instants, rising, setting)
+		}
+		for _, band := range []ElevationBand{p.Options.GoldenHour, p.Options.BlueHour} {
+			enter, leave := BandEdges(band, p.Options.Latitude, p.Options.Longitude, day.AddDate(0, 0, i))
+			instants = append(append(instants, enter...), leave...)
+		}
+	}
+	return EarliestAfter(now, instants...)
+}
+
+// NextChange returns the earliest instant after now at which the count of
+// members in daylight can change: a member's sunrise or sunset, or the end
+// of its solar date
+func (g *GroupPoller) NextChange(now time.Time) time.Time {
+	var instants []time.Time
+	for i, location := range g.Group.Locations {
+		instants = append(instants, g.days[i].sunrise, g.days[i].sunset, solarMidnight(now, location.Longitude))
+	}
+	return EarliestAfter(now, instants...)
+}
+
+// LowPowerWake returns when the poll loop wakes next in low power mode: the
+// first time on the grid of scheduled plus multiples of interval at or after
+// change, the earliest instant a sample can differ, so the state is still
+// sampled on the grid but the nights in between are slept through
+func LowPowerWake(scheduled time.Time, interval time.Duration, change time.Time) time.Time {
+	if change.IsZero() || !change.After(scheduled) {
+		return scheduled
+	}
+	steps := (change.Sub(scheduled) + interval - 1) / interval
+	return scheduled.Add(steps * interval)
+}
@@ -0,0 +1,204 @@
+package daylight
+
+import (
+	"math"
+	"time"
+)
+
+// MeanMoonDistance is the mean distance between the centers of the earth and
+// the moon in kilometers
+const MeanMoonDistance = 384400.0
+
+// MoonPosition describes where the moon is and how much of it is lit for an
+// observer at a moment in time
+type MoonPosition struct {
+	Elevation float64 // degrees above the horizon seen from the surface, without refraction
+	Azimuth   float64 // degrees clockwise from true north
+	HourAngle float64 // degrees west of the meridian, from -180 to 180
+	Distance  float64 // kilometers from the center of the earth
+	// PhaseAngle is the angle between the sun and the earth seen from the
+	// moon in degrees, 0 at full moon and 180 at new moon
+	PhaseAngle float64
+	// Illumination is the lit fraction of the disk, from 0 to 1
+	Illumination float64
+	// Phase is the position in the lunation, 0 at new moon, 0.25 at first
+	// quarter, 0.5 at full moon and 0.75 at last quarter
+	Phase float64
+}
+
+// eclipticToEquatorial converts ecliptic longitude and latitude to right
+// ascension and declination, all in radians
+func eclipticToEquatorial(longitude, latitude float64) (rightAscension, declination float64) {
+	obliquity := 23.4397 * degree
+	rightAscension = math.Atan2(math.Sin(longitude)*math.Cos(obliquity)-math.Tan(latitude)*math.Sin(obliquity), math.Cos(longitude))
+	declination = math.Asin(math.Sin(latitude)*math.Cos(obliquity) + math.Cos(latitude)*math.Sin(obliquity)*math.Sin(longitude))
+	return rightAscension, declination
+}
+
+// CalculateMoonPosition computes the position and phase of the moon from the
+// low precision lunar theory of Meeus' Astronomical Algorithms, good to a few
+// tenths of a degree, which is plenty for illuminance estimates
+func CalculateMoonPosition(latitude, longitude float64, t time.Time) MoonPosition {
+	d := julianCentury(t) * 36525
+
+	meanLongitude := (218.316 + 13.176396*d) * degree
+	meanAnomaly := (134.963 + 13.064993*d) * degree
+	meanDistance := (93.272 + 13.229350*d) * degree
+	moonLongitude := meanLongitude + 6.289*degree*math.Sin(meanAnomaly)
+	moonLatitude := 5.128 * degree * math.Sin(meanDistance)
+	distance := 385001 - 20905*math.Cos(meanAnomaly)
+	moonRA, moonDec := eclipticToEquatorial(moonLongitude, moonLatitude)
+
+	sunAnomaly := (357.5291 + 0.98560028*d) * degree
+	center := (1.9148*math.Sin(sunAnomaly) + 0.02*math.Sin(2*sunAnomaly) + 0.0003*math.Sin(3*sunAnomaly)) * degree
+	sunLongitude := sunAnomaly + center + 102.9372*degree + math.Pi
+	sunRA, sunDec := eclipticToEquatorial(sunLongitude, 0)
+	const sunDistance = 149598000.0
+
+	lat := latitude * degree
+	sidereal := (280.16+360.9856235*d)*degree + longitude*degree
+	hourAngle := sidereal - moonRA
+	elevation := math.Asin(math.Sin(lat)*math.Sin(moonDec) + math.Cos(lat)*math.Cos(moonDec)*math.Cos(hourAngle))
+	azimuth := math.Atan2(math.Sin(hourAngle), math.Cos(hourAngle)*math.Sin(lat)-math.Tan(moonDec)*math.Cos(lat))
+	// The moon is close enough for the observer being off the center of the
+	// earth to lower it by up to a degree
+	elevation -= math.Asin(6378.14 / distance * math.Cos(elevation))
+
+	elongation := math.Acos(math.Sin(sunDec)*math.Sin(moonDec) + math.Cos(sunDec)*math.Cos(moonDec)*math.Cos(sunRA-moonRA))
+	phaseAngle := math.Atan2(sunDistance*math.Sin(elongation), distance-sunDistance*math.Cos(elongation))
+	// The sign of the position angle of the bright limb tells waxing from
+	// waning
+	limb := math.Atan2(math.Cos(sunDec)*math.Sin(sunRA-moonRA),
+		math.Sin(sunDec)*math.Cos(moonDec)-math.Cos(sunDec)*math.Sin(moonDec)*math.Cos(sunRA-moonRA))
+	phase := 0.5 + 0.5*phaseAngle/math.Pi
+	if limb < 0 {
+		phase = 0.5 - 0.5*phaseAngle/math.Pi
+	}
+
+	return MoonPosition{
+		Elevation:    elevation / degree,
+		Azimuth:      math.Mod(azimuth/degree+540, 360),
+		HourAngle:    math.Mod(math.Mod(hourAngle/degree+180, 360)+360, 360) - 180,
+		Distance:     distance,
+		PhaseAngle:   phaseAngle / degree,
+		Illumination: (1 + math.Cos(phaseAngle)) / 2,
+		Phase:        math.Mod(phase+1, 1),
+	}
+}
+
+// MoonIlluminance estimates the illuminance in lux the moon casts on a
+// horizontal surface under a clear sky, following Krisciunas and Schaefer
+// (1991): the apparent magnitude of the moon from its phase angle and
+// distance, dimmed by the extinction through the airmass towards it and
+// projected onto the ground. It is about 0.25 lux for a high full moon and 0
+// while the moon is below the horizon.
+func MoonIlluminance(moon MoonPosition) float64 {
+	if moon.Elevation <= 0 {
+		return 0
+	}
+	alpha := math.Abs(moon.PhaseAngle)
+	magnitude := -12.73 + 0.026*alpha + 4e-9*math.Pow(alpha, 4)
+	// Illuminance normal to the moonlight above the atmosphere
+	lux := math.Pow(10, -0.4*(magnitude+13.99)) * math.Pow(MeanMoonDistance/moon.Distance, 2)
+
+	zenith := (90 - moon.Elevation) * degree
+	airmass := 1 / math.Sqrt(1-0.96*math.Pow(math.Sin(zenith), 2))
+	const extinction = 0.172 // magnitudes per airmass in the V band
+	lux *= math.Pow(10, -0.4*extinction*airmass)
+	return lux * math.Sin(moon.Elevation*degree)
+}
+
+// MoonTransits returns the first upper transit, when the moon crosses the
+// meridian, and the first lower transit, half a lunar day apart, within [from,
+// to), which are zero when none happens; the lunar day being about 24h50m,
+// one solar day a month and a half has no upper or lower transit. Tide
+// predictions key off these times.
+func MoonTransits(latitude, longitude float64, from, to time.Time) (upper, lower time.Time) {
+	crossing := func(target float64) time.Time {
+		// offset is the hour angle relative to the transit sought, which
+		// rises through zero at the transit
+		offset := func(t time.Time) float64 {
+			h := CalculateMoonPosition(latitude, longitude, t).HourAngle - target
+			return math.Mod(math.Mod(h+180, 360)+360, 360) - 180
+		}
+		const step = time.Hour
+		previous := offset(from)
+		for t := from; t.Before(to); t = t.Add(step) {
+			end := t.Add(step)
+			if end.After(to) {
+				end = to
+			}
+			current := offset(end)
+			// The hour angle advances about 14.5 degrees an hour, so a
+			// crossing of zero is a small rise, unlike the wrap at 180
+			if previous < 0 && current >= 0 && current-previous < 90 {
+				low, high := t, end
+				for high.Sub(low) > time.Second {
+					middle := low.Add(high.Sub(low) / 2)
+					if offset(middle) < 0 {
+						low = middle
+					} else {
+						high = middle
+					}
+				}
+				if high.Before(to) {
+					return high.Truncate(time.Second)
+				}
+				return time.Time{}
+			}
+			previous = current
+		}
+		return time.Time{}
+	}
+	return crossing(0), crossing(180)
+}
+
+// MoonRadius is the mean radius of the moon in kilometers
+const MoonRadius = 1737.4
+
+// moonHorizon returns how far the moon's upper limb is above the horizon in
+// degrees, allowing for refraction as for sunrise
+func moonHorizon(latitude, longitude float64, t time.Time) float64 {
+	moon := CalculateMoonPosition(latitude, longitude, t)
+	semidiameter := math.Asin(MoonRadius/moon.Distance) / degree
+	return moon.Elevation + semidiameter + 0.5667
+}
+
+// MoonRiseSet returns the first moonrise and moonset within [from, to), when
+// the moon's upper limb crosses the horizon, which are zero when none
+// happens: one solar day a month has no moonrise and one no moonset, and
+// near the poles the moon can stay up or down for days.
+func MoonRiseSet(latitude, longitude float64, from, to time.Time) (rise, set time.Time) {
+	// The moon moves by a few degrees of elevation within a step, so no
+	// crossing is missed outside grazing ones at high latitudes
+	const step = 10 * time.Minute
+	previous := moonHorizon(latitude, longitude, from)
+	for t := from; t.Before(to) && (rise.IsZero() || set.IsZero()); t = t.Add(step) {
+		end := t.Add(step)
+		if end.After(to) {
+			end = to
+		}
+		current := moonHorizon(latitude, longitude, end)
+		rising := previous < 0 && current >= 0
+		if (rising && rise.IsZero()) || (previous >= 0 && current < 0 && set.IsZero()) {
+			low, high := t, end
+			for high.Sub(low) > time.Second {
+				middle := low.Add(high.Sub(low) / 2)
+				if (moonHorizon(latitude, longitude, middle) >= 0) == rising {
+					high = middle
+				} else {
+					low = middle
+				}
+			}
+			if high.Before(to) {
+				if rising {
+					rise = high.Truncate(time.Second)
+				} else {
+					set = high.Truncate(time.Second)
+				}
+			}
+		}
+		previous = current
+	}
+	return rise, set
+}
@@ -0,0 +1,127 @@
+package daylight
+
+import (
+	"maps"
+	"time"
+)
+
+// Options are the settings of the daylight computation of a location. Every
+// field but the coordinates is optional, and the hooks computing fields from
+// models outside of the package leave those fields out when nil. pkg/config
+// builds them from a configuration file.
+type Options struct {
+	Latitude  float64
+	Longitude float64
+	// Altitude in meters lowers the apparent horizon
+	Altitude float64
+	// TimeOffset moves sunrise later and sunset earlier for daylight_offset
+	TimeOffset time.Duration
+	// Fields turns off the fields set to false; all others are enabled
+	Fields map[string]bool
+	// EphemerisFile supplies the sun events of the days it covers once
+	// UseEphemeris loaded it
+	EphemerisFile string
+	// RecomputeInterval is how long a Poller reuses a solar position
+	RecomputeInterval time.Duration
+	// ExactTransitionTimes stamps transitions with the instant they
+	// happened rather than that of the sample detecting them
+	ExactTransitionTimes bool
+	// TransitionPoints adds a sample at each sunrise and sunset
+	TransitionPoints bool
+	// Aviation adds bmct and eect, the begin and end of civil twilight
+	Aviation bool
+	// CrossCheck compares sunrise and sunset against the NOAA equations,
+	// flagging the days they differ by more than CrossCheckThreshold
+	CrossCheck          bool
+	CrossCheckThreshold time.Duration
+
+	Thresholds        []Threshold
+	Countdowns        []Countdown
+	GoldenHour        ElevationBand
+	BlueHour          ElevationBand
+	SupplementalLight SupplementalLight
+	// ForecastDays is the number of solar dates a ForecastPoller covers
+	ForecastDays int
+
+	// LocalTime adds local_time and utc_offset in its time zone, formatted
+	// by LocalTimeFormat (RFC 3339 by default), which bmct_local and
+	// eect_local use too, in the host's zone without LocalTime
+	LocalTime       *time.Location
+	LocalTimeFormat string
+
+	// Tags are set on every sample, SampleTags on top on those of a Poller
+	Tags       map[string]string
+	SampleTags map[string]string
+
+	// DayType returns the day_type tag of the local date of t
+	DayType func(t time.Time) string
+	// WakingDaylight and WorkingDaylight return how much of the waking and
+	// working hours of the day of t lie between sunrise and sunset
+	WakingDaylight  func(sunrise, sunset time.Time, polarDay bool, t time.Time) time.Duration
+	WorkingDaylight func(sunrise, sunset time.Time, polarDay bool, t time.Time) time.Duration
+	// ColorTemperature returns the suggested color temperature in kelvin
+	// and Exposure the exposure bias in EV and ISO at a solar elevation
+	ColorTemperature func(elevation float64) int
+	Exposure         func(elevation float64) (float64, int)
+}
+
+// FieldEnabled reports whether a field should be emitted; fields are enabled
+// unless explicitly set to false
+func (o Options) FieldEnabled(name string) bool {
+	enabled, ok := o.Fields[name]
+	return !ok || enabled
+}
+
+// ThresholdNames returns the names of the threshold fields
+func (o Options) ThresholdNames() []string {
+	names := make([]string, len(o.Thresholds))
+	for i, threshold := range o.Thresholds {
+		names[i] = threshold.Name
+	}
+	return names
+}
+
+// Threshold returns the threshold of a field
+func (o Options) Threshold(field string) (Threshold, bool) {
+	for _, threshold := range o.Thresholds {
+		if threshold.Name == field {
+			return threshold, true
+		}
+	}
+	return Threshold{}, false
+}
+
+// Band returns the elevation band of a field
+func (o Options) Band(field string) (ElevationBand, bool) {
+	switch field {
+	case "golden_hour":
+		return o.GoldenHour, true
+	case "blue_hour":
+		return o.BlueHour, true
+	}
+	return ElevationBand{}, false
+}
+
+// localTime formats t as the local_time field does
+func (o Options) localTime(t time.Time) (string, int64) {
+	location := o.LocalTime
+	if location == nil {
+		location = time.Local
+	}
+	format := o.LocalTimeFormat
+	if format == "" {
+		format = time.RFC3339
+	}
+	local := t.In(location)
+	_, offset := local.Zone()
+	return local.Format(format), int64(offset)
+}
+
+// tags returns a copy of Tags for a new sample
+func (o Options) tags() map[string]string {
+	tags := maps.Clone(o.Tags)
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	return tags
+}
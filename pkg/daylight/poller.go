@@ -0,0 +1,261 @@
+package daylight
+
+import (
+	"time"
+)
+
+// Data quality values of the data_quality field; anything but QualityOK marks
+// a sample computed in a degraded interval
+const (
+	QualityOK = "ok"
+	// QualityNoEvents marks days without a sunrise or sunset, such as polar
+	// day or night, for which daylight cannot be derived from them
+	QualityNoEvents = "no_events"
+	// QualityClockJump marks the first sample after the clock went backwards
+	// or skipped one or more days, e.g. after a suspend or a replay seek
+	QualityClockJump = "clock_jump"
+	// QualityEngineMismatch marks days on which the cross-check found the
+	// astronomy engines disagreeing on sunrise or sunset
+	QualityEngineMismatch = "engine_mismatch"
+)
+
+// Poller carries the state of the polling loop from one sample to the next.
+// It never reads the clock itself, so the same code serves the live loop and
+// replays of historical or future time ranges.
+type Poller struct {
+	Options Options
+	// today and tomorrow hold the sun events of the current solar date and
+	// the next
+	today    SunEvents
+	tomorrow SunEvents
+	// dayLength is the day length of today and dayLengthDelta its change
+	// since yesterday
+	dayLength      time.Duration
+	dayLengthDelta time.Duration
+	// moonTransit and moonLowerTransit are the lunar transits of the
+	// current solar date, zero on days without one
+	moonTransit      time.Time
+	moonLowerTransit time.Time
+	// moonrise and moonset are those of the current solar date, zero on
+	// days without one
+	moonrise time.Time
+	moonset  time.Time
+	// solarNoon and solarMidnight are the sun's transits of the current
+	// solar date, the midnight being the one starting it
+	solarNoon     time.Time
+	solarMidnight time.Time
+	date          string
+	position      SolarPosition
+	computed      time.Time
+	moved         bool
+	mismatch      bool
+	previous      Sample
+	// events holds the sun events of yesterday, today and tomorrow for
+	// the countdowns, refreshed with sunrise and sunset
+	events []SunEvents
+	// edges holds the samples at the sunrise or sunset found by the last
+	// poll, with transitionPoints
+	edges []Sample
+}
+
+// NewPoller returns a poller of the location of opts with the astronomy
+// computed for now
+func NewPoller(opts Options, now time.Time) *Poller {
+	p := &Poller{Options: opts}
+	p.recompute(now)
+	return p
+}
+
+// SolarDate returns the calendar date of t in local mean solar time at
+// longitude, so that a day's sunrise and sunset always fall on the same date
+// whatever the time zone of the host
+func SolarDate(t time.Time, longitude float64) time.Time {
+	solar := t.UTC().Add(time.Duration(longitude / 15 * float64(time.Hour)))
+	return time.Date(solar.Year(), solar.Month(), solar.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// refresh recomputes sunrise and sunset when now falls on a different solar
+// date than the current ones were computed for. Comparing whole dates rather
+// than day numbers keeps the refresh working across month and year
+// boundaries, leap days and gaps of more than a day.
+func (p *Poller) refresh(now time.Time) {
+	day := SolarDate(now, p.Options.Longitude)
+	date := day.Format("2006-01-02")
+	if date == p.date && !p.moved {
+		return
+	}
+	p.today = DayEvents(p.Options, day.Year(), day.Month(), day.Day())
+	yesterday, tomorrow := day.AddDate(0, 0, -1), day.AddDate(0, 0, 1)
+	p.tomorrow = DayEvents(p.Options, tomorrow.Year(), tomorrow.Month(), tomorrow.Day())
+	p.dayLength = DayLength(p.Options.Latitude, p.Options.Longitude, p.today, day.Year(), day.Month(), day.Day())
+	p.dayLengthDelta = p.dayLength - DayLength(p.Options.Latitude, p.Options.Longitude,
+		DayEvents(p.Options, yesterday.Year(), yesterday.Month(), yesterday.Day()), yesterday.Year(), yesterday.Month(), yesterday.Day())
+	if p.Options.FieldEnabled("moon_transit") || p.Options.FieldEnabled("moon_lower_transit") {
+		start := day.Add(-time.Duration(p.Options.Longitude / 15 * float64(time.Hour)))
+		p.moonTransit, p.moonLowerTransit = MoonTransits(p.Options.Latitude, p.Options.Longitude, start, start.Add(24*time.Hour))
+	}
+	if p.Options.FieldEnabled("moonrise") || p.Options.FieldEnabled("moonset") {
+		start := day.Add(-time.Duration(p.Options.Longitude / 15 * float64(time.Hour)))
+		p.moonrise, p.moonset = MoonRiseSet(p.Options.Latitude, p.Options.Longitude, start, start.Add(24*time.Hour))
+	}
+	p.solarNoon = SolarNoon(p.Options.Longitude, day.Year(), day.Month(), day.Day())
+	p.solarMidnight = SolarMidnight(p.Options.Longitude, day.Year(), day.Month(), day.Day())
+	p.date = date
+	p.moved = false
+	if p.Options.CrossCheck {
+		p.mismatch = CrossCheck(p.Options.CrossCheckThreshold, p.Options.Latitude, p.Options.Longitude, p.Options.Altitude, day, p.today.Sunrise, p.today.Sunset)
+	}
+
+	p.events = nil
+	if len(p.Options.Countdowns) > 0 || p.Options.FieldEnabled("seconds_until_sunrise") || p.Options.FieldEnabled("seconds_until_sunset") {
+		for i := -1; i <= 1; i++ {
+			d := day.AddDate(0, 0, i)
+			p.events = append(p.events, DayEvents(p.Options, d.Year(), d.Month(), d.Day()))
+		}
+	}
+}
+
+// recompute refreshes the astronomy for now: the solar position always, and
+// sunrise and sunset when the date changed
+func (p *Poller) recompute(now time.Time) {
+	p.refresh(now)
+	p.position = CalculateSolarPosition(p.Options.Latitude, p.Options.Longitude, now)
+	p.computed = now
+}
+
+// due reports whether the astronomy must be recomputed for a sample at now.
+// Samples within RecomputeInterval of the last computation reuse it, except
+// that a new solar date always triggers a recompute so that slow and fast
+// writers alike pick up the new day's sunrise and sunset at midnight.
+func (p *Poller) due(now time.Time) bool {
+	if p.moved || now.Sub(p.computed) >= p.Options.RecomputeInterval || now.Before(p.computed) {
+		return true
+	}
+	return SolarDate(now, p.Options.Longitude).Format("2006-01-02") != p.date
+}
+
+// Poll computes the sample for now and the transitions since the previous one
+func (p *Poller) Poll(now time.Time) (Sample, []Transition) {
+	previousDate := p.date
+	if p.due(now) {
+		p.recompute(now)
+	}
+	sample := p.sampleAt(p.position, now)
+	if p.Options.FieldEnabled("data_quality") {
+		sample.Fields["data_quality"] = p.quality(previousDate, now)
+	}
+
+	transitions := DetectTransitions(p.previous, sample, p.Options.ThresholdNames()...)
+	p.edges = nil
+	if p.Options.TransitionPoints {
+		p.edges = p.edgeSamples(transitions, sample)
+	}
+	StampTransitions(p.Options, transitions, p.previous.Time)
+	p.previous = sample
+	return sample, transitions
+}
+
+// sampleAt computes the sample at now from the astronomy of the current
+// solar date and the solar position
+func (p *Poller) sampleAt(position SolarPosition, now time.Time) Sample {
+	sample := ComputeSample(p.Options, p.today, position, now)
+	if p.Options.FieldEnabled("day_length_seconds") {
+		sample.Fields["day_length_seconds"] = p.dayLength.Seconds()
+	}
+	if p.Options.FieldEnabled("day_length_delta_seconds") {
+		sample.Fields["day_length_delta_seconds"] = p.dayLengthDelta.Seconds()
+	}
+	if !p.tomorrow.Sunrise.IsZero() && p.Options.FieldEnabled("tomorrow_sunrise") {
+		sample.Fields["tomorrow_sunrise"] = p.tomorrow.Sunrise.Unix()
+	}
+	if !p.tomorrow.Sunset.IsZero() && p.Options.FieldEnabled("tomorrow_sunset") {
+		sample.Fields["tomorrow_sunset"] = p.tomorrow.Sunset.Unix()
+	}
+	if !p.moonTransit.IsZero() && p.Options.FieldEnabled("moon_transit") {
+		sample.Fields["moon_transit"] = p.moonTransit.Unix()
+	}
+	if !p.moonLowerTransit.IsZero() && p.Options.FieldEnabled("moon_lower_transit") {
+		sample.Fields["moon_lower_transit"] = p.moonLowerTransit.Unix()
+	}
+	if !p.moonrise.IsZero() && p.Options.FieldEnabled("moonrise") {
+		sample.Fields["moonrise"] = p.moonrise.Unix()
+	}
+	if !p.moonset.IsZero() && p.Options.FieldEnabled("moonset") {
+		sample.Fields["moonset"] = p.moonset.Unix()
+	}
+	if p.Options.FieldEnabled("solar_noon") {
+		sample.Fields["solar_noon"] = p.solarNoon.Unix()
+	}
+	if p.Options.FieldEnabled("solar_midnight") {
+		sample.Fields["solar_midnight"] = p.solarMidnight.Unix()
+	}
+	if p.Options.FieldEnabled("minutes_to_solar_noon") {
+		sample.Fields["minutes_to_solar_noon"] = p.solarNoon.Sub(now).Minutes()
+	}
+	for _, countdown := range append(BuiltInCountdowns[:len(BuiltInCountdowns):len(BuiltInCountdowns)], p.Options.Countdowns...) {
+		next := NextCountdown(countdown, now, p.events)
+		if !next.IsZero() && p.Options.FieldEnabled(countdown.Name) {
+			sample.Fields[countdown.Name] = next.Sub(now).Seconds()
+		}
+	}
+	return sample
+}
+
+// edgeSamples returns a sample at the instant of each sunrise or sunset
+// between the previous sample and current, so that the series shows the
+// true edge rather than one quantized to the poll interval. Edges on another
+// solar date than current, e.g. after the clock jumped, are left out.
+func (p *Poller) edgeSamples(transitions []Transition, current Sample) []Sample {
+	var edges []Sample
+	for _, transition := range transitions {
+		if transition.Field != "daylight" {
+			continue
+		}
+		instant := TransitionTime(p.Options, transition, p.previous.Time)
+		if !instant.After(p.previous.Time) || !instant.Before(current.Time) || SolarDate(instant, p.Options.Longitude).Format("2006-01-02") != p.date {
+			continue
+		}
+		edge := p.sampleAt(CalculateSolarPosition(p.Options.Latitude, p.Options.Longitude, instant), instant)
+		edge.Fields["daylight"] = transition.Value
+		if quality, ok := current.Fields["data_quality"]; ok {
+			edge.Fields["data_quality"] = quality
+		}
+		edges = append(edges, edge)
+	}
+	return edges
+}
+
+// Edges returns the samples at the sunrise or sunset found by the last poll,
+// to be written before its sample
+func (p *Poller) Edges() []Sample {
+	return p.edges
+}
+
+// quality grades the sample at now given the date of the previous sample
+func (p *Poller) quality(previousDate string, now time.Time) string {
+	if !p.previous.Time.IsZero() {
+		if now.Before(p.previous.Time) {
+			return QualityClockJump
+		}
+		yesterday := SolarDate(now, p.Options.Longitude).AddDate(0, 0, -1).Format("2006-01-02")
+		if previousDate != p.date && previousDate != yesterday {
+			return QualityClockJump
+		}
+	}
+	if p.today.Sunrise.IsZero() || p.today.Sunset.IsZero() {
+		return QualityNoEvents
+	}
+	if p.mismatch {
+		return QualityEngineMismatch
+	}
+	return QualityOK
+}
+
+// Reconfigure applies new options to the poller, such as a reloaded
+// configuration or a new location, recomputing the astronomy on the next
+// poll; the previous sample is kept so a reload is not mistaken for state
+// changes. An ephemeris generated for another location is no longer read.
+func (p *Poller) Reconfigure(opts Options) {
+	p.Options = opts
+	p.moved = true
+}
@@ -0,0 +1,148 @@
+package daylight
+
+import (
+	"testing"
+	"time"
+)
+
+// austin is a location west of Greenwich, whose solar date lags UTC by about
+// six and a half hours
+var austin = Options{Latitude: 30.2822, Longitude: -97.7322}
+
+func TestSolarDate(t *testing.T) {
+	tests := []struct {
+		name      string
+		t         time.Time
+		longitude float64
+		want      string
+	}{
+		{"leap day starts", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC), 0, "2028-02-29"},
+		{"leap day ends", time.Date(2028, 2, 29, 23, 59, 59, 0, time.UTC), 0, "2028-02-29"},
+		{"after the leap day", time.Date(2028, 3, 1, 0, 0, 0, 0, time.UTC), 0, "2028-03-01"},
+		{"leap day still going west", time.Date(2028, 3, 1, 6, 0, 0, 0, time.UTC), -97.7322, "2028-02-29"},
+		{"leap day already east", time.Date(2028, 2, 28, 18, 0, 0, 0, time.UTC), 151.2093, "2028-02-29"},
+		{"new year in UTC", time.Date(2028, 1, 1, 0, 0, 0, 0, time.UTC), 0, "2028-01-01"},
+		{"old year still going west", time.Date(2028, 1, 1, 6, 0, 0, 0, time.UTC), -97.7322, "2027-12-31"},
+		{"new year west", time.Date(2028, 1, 1, 7, 0, 0, 0, time.UTC), -97.7322, "2028-01-01"},
+		{"new year already east", time.Date(2027, 12, 31, 18, 0, 0, 0, time.UTC), 151.2093, "2028-01-01"},
+		{"host zone already in the new year", time.Date(2028, 1, 1, 0, 10, 0, 0, time.FixedZone("UTC-6", -6*3600)), -97.7322, "2027-12-31"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := SolarDate(test.t, test.longitude).Format("2006-01-02")
+			if got != test.want {
+				t.Errorf("SolarDate(%s, %g) = %s, want %s", test.t, test.longitude, got, test.want)
+			}
+		})
+	}
+}
+
+func TestPollerRefresh(t *testing.T) {
+	tests := []struct {
+		name string
+		// polls are the times polled in order; the last one is checked
+		polls   []time.Time
+		date    string
+		quality string
+	}{
+		{
+			name:    "into the leap day",
+			polls:   []time.Time{time.Date(2028, 2, 29, 6, 0, 0, 0, time.UTC), time.Date(2028, 2, 29, 7, 0, 0, 0, time.UTC)},
+			date:    "2028-02-29",
+			quality: QualityOK,
+		},
+		{
+			name:    "out of the leap day",
+			polls:   []time.Time{time.Date(2028, 3, 1, 6, 0, 0, 0, time.UTC), time.Date(2028, 3, 1, 7, 0, 0, 0, time.UTC)},
+			date:    "2028-03-01",
+			quality: QualityOK,
+		},
+		{
+			name:    "across the new year",
+			polls:   []time.Time{time.Date(2028, 1, 1, 6, 0, 0, 0, time.UTC), time.Date(2028, 1, 1, 7, 0, 0, 0, time.UTC)},
+			date:    "2028-01-01",
+			quality: QualityOK,
+		},
+		{
+			name:    "skipping the leap day",
+			polls:   []time.Time{time.Date(2028, 2, 28, 18, 0, 0, 0, time.UTC), time.Date(2028, 3, 1, 18, 0, 0, 0, time.UTC)},
+			date:    "2028-03-01",
+			quality: QualityClockJump,
+		},
+		{
+			name:    "back into the old year",
+			polls:   []time.Time{time.Date(2028, 1, 1, 18, 0, 0, 0, time.UTC), time.Date(2027, 12, 31, 18, 0, 0, 0, time.UTC)},
+			date:    "2027-12-31",
+			quality: QualityClockJump,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := NewPoller(austin, test.polls[0])
+			var sample Sample
+			for _, now := range test.polls {
+				sample, _ = p.Poll(now)
+			}
+			if p.date != test.date {
+				t.Fatalf("poller is on %s, want %s", p.date, test.date)
+			}
+			day, _ := time.Parse("2006-01-02", test.date)
+			want := CalculateSunEvents(austin.Latitude, austin.Longitude, austin.Altitude, day.Year(), day.Month(), day.Day())
+			if !p.today.Sunrise.Equal(want.Sunrise) || !p.today.Sunset.Equal(want.Sunset) {
+				t.Errorf("sunrise and sunset are %s and %s, want %s and %s", p.today.Sunrise, p.today.Sunset, want.Sunrise, want.Sunset)
+			}
+			next := day.AddDate(0, 0, 1)
+			tomorrow := CalculateSunEvents(austin.Latitude, austin.Longitude, austin.Altitude, next.Year(), next.Month(), next.Day())
+			if got := sample.Fields["tomorrow_sunrise"]; got != tomorrow.Sunrise.Unix() {
+				t.Errorf("tomorrow_sunrise = %v, want %d", got, tomorrow.Sunrise.Unix())
+			}
+			if got := sample.Fields["data_quality"]; got != test.quality {
+				t.Errorf("data_quality = %v, want %s", got, test.quality)
+			}
+		})
+	}
+}
+
+func TestReplayAcrossBoundaries(t *testing.T) {
+	tests := []struct {
+		name string
+		from time.Time
+		// dates are the solar dates whose sunrise and sunset the replay
+		// must find, in order
+		dates []string
+	}{
+		{"leap day", time.Date(2028, 2, 28, 12, 0, 0, 0, time.UTC), []string{"2028-02-28", "2028-02-29", "2028-03-01"}},
+		{"new year", time.Date(2027, 12, 30, 12, 0, 0, 0, time.UTC), []string{"2027-12-30", "2027-12-31", "2028-01-01"}},
+		{"new leap year", time.Date(2027, 12, 31, 12, 0, 0, 0, time.UTC), []string{"2027-12-31", "2028-01-01", "2028-01-02"}},
+	}
+	opts := austin
+	opts.ExactTransitionTimes = true
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []Transition
+			for _, transition := range Replay(opts, test.from, test.from.Add(72*time.Hour), time.Minute) {
+				if transition.Field == "daylight" {
+					got = append(got, transition)
+				}
+			}
+			var want []Transition
+			for _, date := range test.dates {
+				day, _ := time.Parse("2006-01-02", date)
+				events := CalculateSunEvents(opts.Latitude, opts.Longitude, opts.Altitude, day.Year(), day.Month(), day.Day())
+				for _, transition := range []Transition{{Time: events.Sunrise, Event: "sunrise"}, {Time: events.Sunset, Event: "sunset"}} {
+					if transition.Time.After(test.from) {
+						want = append(want, transition)
+					}
+				}
+			}
+			if len(got) != len(want) {
+				t.Fatalf("replay found %d sunrises and sunsets, want %d: %v", len(got), len(want), got)
+			}
+			for i := range want {
+				if got[i].Event != want[i].Event || got[i].Time.Sub(want[i].Time).Abs() > time.Second {
+					t.Errorf("transition %d is %s at %s, want %s at %s", i, got[i].Event, got[i].Time, want[i].Event, want[i].Time)
+				}
+			}
+		})
+	}
+}
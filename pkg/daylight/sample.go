@@ -0,0 +1,143 @@
+package daylight
+
+import (
+	"time"
+)
+
+// Sample is one computed set of daylight fields at a moment in time
+type Sample struct {
+	// Measurement is empty for the samples of the configured location,
+	// daylight for those of named locations and daylight_group for those of
+	// location groups
+	Measurement string                 `json:"measurement,omitempty"`
+	Time        time.Time              `json:"time"`
+	Tags        map[string]string      `json:"tags,omitempty"`
+	Fields      map[string]interface{} `json:"fields"`
+}
+
+// ComputeSample calculates all enabled fields for time t from the day's sun
+// events and a recently computed solar position
+func ComputeSample(opts Options, events SunEvents, position SolarPosition, t time.Time) Sample {
+	sunriseTime, sunsetTime := events.Sunrise, events.Sunset
+	above := SunUp(position.Elevation, opts.Altitude)
+	daylight, daylightOffset := Daylight(sunriseTime, sunsetTime, above, t, opts.TimeOffset)
+	polarDay, polarNight := PolarState(events, above)
+
+	sample := Sample{
+		Time: t,
+		Tags: opts.tags(),
+		Fields: map[string]interface{}{
+			"daylight":         daylight,
+			"daylight_offset":  daylightOffset,
+			"polar_day":        polarDay,
+			"polar_night":      polarNight,
+			"elevation":        position.Elevation,
+			"azimuth":          position.Azimuth,
+			"sun_phase":        SunPhase(position.Elevation),
+			"declination":      position.Declination,
+			"equation_of_time": position.EquationOfTime,
+			"elevation_rate":   position.ElevationRate,
+		},
+	}
+
+	for _, twilight := range Twilights {
+		dawn, dusk := events.Event(twilight.Name+"_dawn"), events.Event(twilight.Name+"_dusk")
+		sample.Fields[twilight.Name+"_daylight"] = TwilightDaylight(dawn, dusk, position.Elevation >= twilight.Elevation, t)
+		if !dawn.IsZero() {
+			sample.Fields[twilight.Name+"_dawn"] = dawn.Unix()
+		}
+		if !dusk.IsZero() {
+			sample.Fields[twilight.Name+"_dusk"] = dusk.Unix()
+		}
+	}
+
+	for _, threshold := range opts.Thresholds {
+		sample.Fields[threshold.Name] = position.Elevation >= threshold.Elevation
+	}
+	sample.Fields["golden_hour"] = opts.GoldenHour.Contains(position.Elevation)
+	sample.Fields["blue_hour"] = opts.BlueHour.Contains(position.Elevation)
+
+	if opts.WorkingDaylight != nil {
+		sample.Fields["working_daylight_seconds"] = opts.WorkingDaylight(sunriseTime, sunsetTime, polarDay, t).Seconds()
+	}
+
+	if opts.WakingDaylight != nil {
+		sample.Fields["waking_daylight_seconds"] = opts.WakingDaylight(sunriseTime, sunsetTime, polarDay, t).Seconds()
+	}
+
+	if opts.ColorTemperature != nil {
+		sample.Fields["color_temperature"] = int64(opts.ColorTemperature(position.Elevation))
+	}
+
+	if opts.Exposure != nil {
+		bias, iso := opts.Exposure(position.Elevation)
+		sample.Fields["exposure_bias"] = bias
+		sample.Fields["exposure_iso"] = int64(iso)
+	}
+
+	if opts.SupplementalLight.Controls() {
+		day := SolarDate(t, opts.Longitude)
+		windows := SupplementalWindows(opts.SupplementalLight, opts.Latitude, opts.Longitude, day, sunriseTime, sunsetTime)
+		sample.Fields["supplemental_light"] = LightOn(windows, t)
+	}
+
+	if opts.FieldEnabled("moon_elevation") || opts.FieldEnabled("moon_illumination") ||
+		opts.FieldEnabled("moon_phase") || opts.FieldEnabled("moonlight_lux") || opts.FieldEnabled("moon_phase_angle") {
+		moon := CalculateMoonPosition(opts.Latitude, opts.Longitude, t)
+		sample.Fields["moon_elevation"] = moon.Elevation
+		sample.Fields["moon_illumination"] = moon.Illumination
+		sample.Fields["moon_phase"] = moon.Phase
+		sample.Fields["moonlight_lux"] = MoonIlluminance(moon)
+		sample.Fields["moon_phase_angle"] = moon.PhaseAngle
+	}
+
+	if opts.Aviation {
+		for name, instant := range map[string]time.Time{"bmct": events.CivilDawn, "eect": events.CivilDusk} {
+			if instant.IsZero() {
+				continue
+			}
+			// Almanacs publish the times to the nearest minute
+			instant = instant.Round(time.Minute)
+			sample.Fields[name] = instant.Unix()
+			sample.Fields[name+"_local"], _ = opts.localTime(instant)
+		}
+	}
+
+	if opts.LocalTime != nil {
+		sample.Fields["local_time"], sample.Fields["utc_offset"] = opts.localTime(t)
+	}
+
+	for key, value := range opts.SampleTags {
+		sample.Tags[key] = value
+	}
+	if opts.DayType != nil {
+		sample.Tags["day_type"] = opts.DayType(t)
+	}
+
+	for name := range sample.Fields {
+		if !opts.FieldEnabled(name) {
+			delete(sample.Fields, name)
+		}
+	}
+
+	return sample
+}
+
+// Daylight reports whether t falls between the day's sunrise and sunset, and
+// between sunrise plus offset and sunset minus offset. Near the poles either
+// event is zero on days the sun does not cross the horizon; above tells
+// whether the sun is up at t, which decides polar day or night.
+func Daylight(sunrise time.Time, sunset time.Time, above bool, t time.Time, offset time.Duration) (currentDaylight, offsetDaylight bool) {
+	currentDaylight = TwilightDaylight(sunrise, sunset, above, t)
+	if !sunrise.IsZero() {
+		sunrise = sunrise.Add(offset)
+	}
+	if !sunset.IsZero() {
+		sunset = sunset.Add(-offset)
+	}
+	offsetDaylight = TwilightDaylight(sunrise, sunset, above, t)
+	return currentDaylight, offsetDaylight
+}
+
+// Measurement is the InfluxDB measurement samples are written to
+const Measurement = "daylight"
@@ -0,0 +1,223 @@
+package daylight
+
+import (
+	"github.com/nathan-osman/go-sunrise"
+	"math"
+	"time"
+)
+
+const degree = math.Pi / 180
+
+// SolarPosition describes where the sun is for an observer at a moment in time
+type SolarPosition struct {
+	Declination    float64 // degrees north of the celestial equator
+	EquationOfTime float64 // minutes apparent solar time leads mean solar time
+	HourAngle      float64 // degrees, negative before solar noon
+	Elevation      float64 // degrees above the horizon, without refraction
+	Azimuth        float64 // degrees clockwise from true north
+	ElevationRate  float64 // degrees per hour, positive while the sun rises
+}
+
+// julianCentury returns the Julian centuries since J2000.0 for t
+func julianCentury(t time.Time) float64 {
+	julianDay := float64(t.UnixNano())/float64(24*time.Hour) + 2440587.5
+	return (julianDay - 2451545) / 36525
+}
+
+// CalculateSolarPosition computes the position of the sun following the
+// NOAA solar calculator equations
+func CalculateSolarPosition(latitude, longitude float64, t time.Time) SolarPosition {
+	jc := julianCentury(t)
+
+	meanLongitude := math.Mod(280.46646+jc*(36000.76983+jc*0.0003032), 360)
+	meanAnomaly := 357.52911 + jc*(35999.05029-0.0001537*jc)
+	eccentricity := 0.016708634 - jc*(0.000042037+0.0000001267*jc)
+	center := math.Sin(meanAnomaly*degree)*(1.914602-jc*(0.004817+0.000014*jc)) +
+		math.Sin(2*meanAnomaly*degree)*(0.019993-0.000101*jc) +
+		math.Sin(3*meanAnomaly*degree)*0.000289
+	omega := 125.04 - 1934.136*jc
+	apparentLongitude := meanLongitude + center - 0.00569 - 0.00478*math.Sin(omega*degree)
+	meanObliquity := 23 + (26+(21.448-jc*(46.815+jc*(0.00059-jc*0.001813)))/60)/60
+	obliquity := meanObliquity + 0.00256*math.Cos(omega*degree)
+
+	declination := math.Asin(math.Sin(obliquity*degree)*math.Sin(apparentLongitude*degree)) / degree
+
+	y := math.Pow(math.Tan(obliquity*degree/2), 2)
+	equationOfTime := 4 / degree * (y*math.Sin(2*meanLongitude*degree) -
+		2*eccentricity*math.Sin(meanAnomaly*degree) +
+		4*eccentricity*y*math.Sin(meanAnomaly*degree)*math.Cos(2*meanLongitude*degree) -
+		0.5*y*y*math.Sin(4*meanLongitude*degree) -
+		1.25*eccentricity*eccentricity*math.Sin(2*meanAnomaly*degree))
+
+	utc := t.UTC()
+	minutes := float64(utc.Hour()*60+utc.Minute()) + (float64(utc.Second())+float64(utc.Nanosecond())/1e9)/60
+	trueSolarTime := math.Mod(minutes+equationOfTime+4*longitude, 1440)
+	if trueSolarTime < 0 {
+		trueSolarTime += 1440
+	}
+	hourAngle := trueSolarTime/4 - 180
+
+	lat := latitude * degree
+	dec := declination * degree
+	ha := hourAngle * degree
+	elevation := math.Asin(math.Sin(lat)*math.Sin(dec)+math.Cos(lat)*math.Cos(dec)*math.Cos(ha)) / degree
+	azimuth := math.Mod(math.Atan2(math.Sin(ha), math.Cos(ha)*math.Sin(lat)-math.Tan(dec)*math.Cos(lat))/degree+180, 360)
+
+	// Differentiating the elevation formula with the hour angle advancing 15
+	// degrees per hour; the slow change in declination is negligible
+	var elevationRate float64
+	if cosElevation := math.Cos(elevation * degree); cosElevation > 1e-9 {
+		elevationRate = -math.Cos(lat) * math.Cos(dec) * math.Sin(ha) * 15 / cosElevation
+	}
+
+	return SolarPosition{
+		Declination:    declination,
+		EquationOfTime: equationOfTime,
+		HourAngle:      hourAngle,
+		Elevation:      elevation,
+		Azimuth:        azimuth,
+		ElevationRate:  elevationRate,
+	}
+}
+
+// Twilight elevations in degrees for the standard twilight definitions
+const (
+	CivilTwilight        = -6.0
+	NauticalTwilight     = -12.0
+	AstronomicalTwilight = -18.0
+)
+
+// Twilight is one of the standard twilight definitions, named after its
+// dawn and dusk events
+type Twilight struct {
+	Name      string
+	Elevation float64
+}
+
+// Twilights lists the twilights from the brightest to the darkest
+var Twilights = []Twilight{
+	{"civil", CivilTwilight},
+	{"nautical", NauticalTwilight},
+	{"astronomical", AstronomicalTwilight},
+}
+
+// sunriseElevation is the elevation of the sun's centre at sunrise and sunset,
+// allowing for atmospheric refraction and the solar semidiameter
+const sunriseElevation = -0.833
+
+// HorizonDip returns how many degrees the horizon seen by an observer
+// altitude meters above the surrounding terrain lies below the astronomical
+// one, which makes the sun rise earlier and set later
+func HorizonDip(altitude float64) float64 {
+	if altitude <= 0 {
+		return 0
+	}
+	return 2.076 * math.Sqrt(altitude) / 60
+}
+
+// SunriseElevation returns the elevation of the sun's centre at sunrise and
+// sunset for an observer altitude meters up
+func SunriseElevation(altitude float64) float64 {
+	return sunriseElevation - HorizonDip(altitude)
+}
+
+// SunUp reports whether the sun is above the horizon, seen from an observer
+// altitude meters up, at an elevation
+func SunUp(elevation, altitude float64) bool {
+	return elevation >= SunriseElevation(altitude)
+}
+
+// PolarState reports polar day, when the sun neither rises nor sets during the
+// day and stays up, and polar night, when it stays down; events are the
+// day's and above whether the sun is up now
+func PolarState(events SunEvents, above bool) (polarDay, polarNight bool) {
+	if !events.Sunrise.IsZero() || !events.Sunset.IsZero() {
+		return false, false
+	}
+	return above, !above
+}
+
+// TwilightDaylight reports whether t falls between a twilight's dawn and dusk
+// of the day, either of which is zero when the sun does not cross the
+// twilight elevation; above tells on which side of it the sun is now, for
+// days without either event
+func TwilightDaylight(dawn, dusk time.Time, above bool, t time.Time) bool {
+	switch {
+	case !dawn.IsZero() && !dusk.IsZero():
+		return !t.Before(dawn) && !t.After(dusk)
+	case !dawn.IsZero():
+		return !t.Before(dawn)
+	case !dusk.IsZero():
+		return !t.After(dusk)
+	}
+	return above
+}
+
+// SunEvents holds the sun events of one day; events that do not happen on
+// that day (e.g. during polar day or night) are zero
+type SunEvents struct {
+	AstronomicalDawn time.Time
+	NauticalDawn     time.Time
+	CivilDawn        time.Time
+	Sunrise          time.Time
+	Sunset           time.Time
+	CivilDusk        time.Time
+	NauticalDusk     time.Time
+	AstronomicalDusk time.Time
+}
+
+// CalculateSunEvents computes the sunrise, sunset and twilight times for a
+// day. Sunrise and sunset allow for the dip of the horizon seen from altitude
+// meters up; the twilights keep their conventional elevations.
+func CalculateSunEvents(latitude, longitude, altitude float64, year int, month time.Month, day int) SunEvents {
+	var events SunEvents
+	if altitude > 0 {
+		events.Sunrise, events.Sunset = sunrise.TimeOfElevation(latitude, longitude, SunriseElevation(altitude), year, month, day)
+	} else {
+		events.Sunrise, events.Sunset = sunrise.SunriseSunset(latitude, longitude, year, month, day)
+	}
+	events.CivilDawn, events.CivilDusk = sunrise.TimeOfElevation(latitude, longitude, CivilTwilight, year, month, day)
+	events.NauticalDawn, events.NauticalDusk = sunrise.TimeOfElevation(latitude, longitude, NauticalTwilight, year, month, day)
+	events.AstronomicalDawn, events.AstronomicalDusk = sunrise.TimeOfElevation(latitude, longitude, AstronomicalTwilight, year, month, day)
+	return events
+}
+
+// DayLength returns the time between sunrise and sunset on a day, which is a
+// full day during polar day and zero during polar night
+func DayLength(latitude, longitude float64, events SunEvents, year int, month time.Month, day int) time.Duration {
+	if !events.Sunrise.IsZero() && !events.Sunset.IsZero() {
+		return events.Sunset.Sub(events.Sunrise)
+	}
+	noon := sunrise.JulianDayToTime(sunrise.MeanSolarNoon(longitude, year, month, day))
+	if sunrise.Elevation(latitude, longitude, noon) > 0 {
+		return 24 * time.Hour
+	}
+	return 0
+}
+
+// SolarNoon returns the apparent solar noon of a date at longitude, when the
+// sun crosses the meridian
+func SolarNoon(longitude float64, year int, month time.Month, day int) time.Time {
+	return apparentSolarTime(longitude, time.Date(year, month, day, 12, 0, 0, 0, time.UTC))
+}
+
+// SolarMidnight returns the apparent solar midnight starting a date at
+// longitude, when the sun crosses the meridian below the pole
+func SolarMidnight(longitude float64, year int, month time.Month, day int) time.Time {
+	return apparentSolarTime(longitude, time.Date(year, month, day, 0, 0, 0, 0, time.UTC))
+}
+
+// apparentSolarTime returns when apparent solar time at longitude reads the
+// clock time of mean, given in UTC: the local mean time corrected by the
+// equation of time
+func apparentSolarTime(longitude float64, mean time.Time) time.Time {
+	mean = mean.Add(time.Duration(-longitude * 4 * float64(time.Minute)))
+	t := mean
+	// The equation of time changes by under a second over the correction,
+	// so two rounds settle it
+	for i := 0; i < 2; i++ {
+		eot := CalculateSolarPosition(0, longitude, t).EquationOfTime
+		t = mean.Add(time.Duration(-eot * float64(time.Minute)))
+	}
+	return t
+}
@@ -0,0 +1,62 @@
+package daylight
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPolarDayAndNight(t *testing.T) {
+	tromso := Options{Latitude: 69.6492, Longitude: 18.9553}
+	mcmurdo := Options{Latitude: -77.8463, Longitude: 166.6683}
+	midsummer := time.Date(2026, 6, 21, 0, 0, 0, 0, time.UTC)
+	midwinter := time.Date(2026, 12, 21, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		opts     Options
+		day      time.Time
+		polarDay bool
+	}{
+		{"Tromsø at midsummer", tromso, midsummer, true},
+		{"Tromsø at midwinter", tromso, midwinter, false},
+		{"McMurdo in the austral winter", mcmurdo, midsummer, false},
+		{"McMurdo in the austral summer", mcmurdo, midwinter, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			events := CalculateSunEvents(test.opts.Latitude, test.opts.Longitude, test.opts.Altitude, test.day.Year(), test.day.Month(), test.day.Day())
+			if !events.Sunrise.IsZero() || !events.Sunset.IsZero() {
+				t.Fatalf("sunrise %s and sunset %s, want neither", events.Sunrise, events.Sunset)
+			}
+			dayLength := 0.0
+			if test.polarDay {
+				dayLength = (24 * time.Hour).Seconds()
+			}
+
+			// Sample the solar day from just after its midnight to just
+			// before the next
+			noon := SolarNoon(test.opts.Longitude, test.day.Year(), test.day.Month(), test.day.Day())
+			from, to := noon.Add(-11*time.Hour), noon.Add(11*time.Hour)
+			p := NewPoller(test.opts, from)
+			for now := from; !now.After(to); now = now.Add(time.Hour) {
+				sample, transitions := p.Poll(now)
+				for field, want := range map[string]interface{}{
+					"polar_day":          test.polarDay,
+					"polar_night":        !test.polarDay,
+					"daylight":           test.polarDay,
+					"daylight_offset":    test.polarDay,
+					"day_length_seconds": dayLength,
+					"data_quality":       QualityNoEvents,
+				} {
+					if got := sample.Fields[field]; got != want {
+						t.Errorf("%s at %s = %v, want %v", field, now.Format(time.RFC3339), got, want)
+					}
+				}
+				for _, transition := range transitions {
+					if transition.Field == "daylight" || transition.Field == "polar_day" || transition.Field == "polar_night" {
+						t.Errorf("unexpected %s at %s", transition.Event, now.Format(time.RFC3339))
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package daylight
+
+import (
+	"time"
+)
+
+// Transition is a change of a boolean field between consecutive samples
+type Transition struct {
+	Time  time.Time `json:"time"`
+	Event string    `json:"event"`
+	Field string    `json:"field"`
+	Value bool      `json:"value"`
+}
+
+// TransitionEvent names the event of a boolean field changing to value:
+// sunrise or sunset for daylight, the twilight's dawn or dusk, e.g.
+// civil_dawn, for <twilight>_daylight and <field>_start or <field>_end for
+// the others
+func TransitionEvent(field string, value bool) string {
+	if field == "daylight" {
+		if value {
+			return "sunrise"
+		}
+		return "sunset"
+	}
+	for _, twilight := range Twilights {
+		if field == twilight.Name+"_daylight" {
+			if value {
+				return twilight.Name + "_dawn"
+			}
+			return twilight.Name + "_dusk"
+		}
+	}
+	if value {
+		return field + "_start"
+	}
+	return field + "_end"
+}
+
+// DetectTransitions returns the boolean fields that changed from previous to
+// current, stamped with the time of current; extra names fields beyond the
+// built-in ones, such as thresholds
+func DetectTransitions(previous, current Sample, extra ...string) []Transition {
+	var transitions []Transition
+	for _, field := range append(SampleFields[:len(SampleFields):len(SampleFields)], extra...) {
+		value, ok := current.Fields[field].(bool)
+		if !ok {
+			continue
+		}
+		before, ok := previous.Fields[field].(bool)
+		if !ok || before == value {
+			continue
+		}
+		transitions = append(transitions, Transition{
+			Time:  current.Time,
+			Event: TransitionEvent(field, value),
+			Field: field,
+			Value: value,
+		})
+	}
+	return transitions
+}
+
+// StampTransitions moves transitions detected since the previous sample at
+// previous to the instants they happened, when exactTransitionTimes is set
+func StampTransitions(opts Options, transitions []Transition, previous time.Time) {
+	if !opts.ExactTransitionTimes {
+		return
+	}
+	for i := range transitions {
+		transitions[i].Time = TransitionTime(opts, transitions[i], previous)
+	}
+}
+
+// TransitionTime returns the computed instant of the sunrise, sunset or light
+// window edge behind a transition when it falls between previous and the
+// sample that changed, and the time of that sample otherwise, e.g. after the
+// clock jumped or for fields not driven by the astronomy. An instant equal to
+// previous counts, as the sample at previous did not show the change yet.
+func TransitionTime(opts Options, transition Transition, previous time.Time) time.Time {
+	t := transition.Time
+	if previous.IsZero() || !previous.Before(t) || t.Sub(previous) > 48*time.Hour {
+		return t
+	}
+	offset := opts.TimeOffset
+	for day := SolarDate(previous, opts.Longitude); !day.After(SolarDate(t, opts.Longitude)); day = day.AddDate(0, 0, 1) {
+		events := DayEvents(opts, day.Year(), day.Month(), day.Day())
+		var instants []time.Time
+		switch transition.Field {
+		case "daylight":
+			instants = []time.Time{events.Sunset}
+			if transition.Value {
+				instants = []time.Time{events.Sunrise}
+			}
+		case "daylight_offset":
+			instants = []time.Time{events.Sunset.Add(-offset)}
+			if transition.Value {
+				instants = []time.Time{events.Sunrise.Add(offset)}
+			}
+		case "civil_daylight", "nautical_daylight", "astronomical_daylight":
+			instants = []time.Time{events.Event(transition.Event)}
+		case "supplemental_light":
+			for _, window := range SupplementalWindows(opts.SupplementalLight, opts.Latitude, opts.Longitude, day, events.Sunrise, events.Sunset) {
+				if transition.Value {
+					instants = append(instants, window.On)
+				} else {
+					instants = append(instants, window.Off)
+				}
+			}
+		case "golden_hour", "blue_hour":
+			band, _ := opts.Band(transition.Field)
+			enter, leave := BandEdges(band, opts.Latitude, opts.Longitude, day)
+			instants = leave
+			if transition.Value {
+				instants = enter
+			}
+		default:
+			if threshold, ok := opts.Threshold(transition.Field); ok {
+				rising, setting := ThresholdCrossings(threshold, opts.Latitude, opts.Longitude, day)
+				instants = []time.Time{setting}
+				if transition.Value {
+					instants = []time.Time{rising}
+				}
+			}
+		}
+		for _, instant := range instants {
+			if !instant.IsZero() && !instant.Before(previous) && !instant.After(t) {
+				return instant
+			}
+		}
+	}
+	return t
+}
+
+// Replay runs a poller over [from, to) with a fake clock advancing by interval
+// and returns every transition it detected
+func Replay(opts Options, from, to time.Time, interval time.Duration) []Transition {
+	var transitions []Transition
+	poller := NewPoller(opts, from)
+	for now := from; now.Before(to); now = now.Add(interval) {
+		_, detected := poller.Poll(now)
+		transitions = append(transitions, detected...)
+	}
+	return transitions
+}
@@ -0,0 +1,50 @@
+package daylight
+
+import (
+	"context"
+	"time"
+)
+
+// subscribeMargin is how long after a computed change the subscription
+// polls, so that the sample reflects it despite the small differences
+// between the event and position calculations
+const subscribeMargin = time.Second
+
+// Subscribe returns a channel receiving the transitions of the configured
+// location as they happen, without writing samples anywhere, for programs
+// embedding the daylight computation. events selects transitions by event,
+// e.g. sunrise or civil_dusk, or by field, e.g. golden_hour for both its
+// start and end; every transition is sent when none is given. Rather than
+// polling, it sleeps until the next instant a field can change and stamps
+// transitions with the exact instant. The channel is closed once ctx is
+// done.
+func Subscribe(ctx context.Context, opts Options, events ...string) <-chan Transition {
+	opts.ExactTransitionTimes, opts.RecomputeInterval = true, 0
+	wanted := make(map[string]bool, len(events))
+	for _, event := range events {
+		wanted[event] = true
+	}
+	ch := make(chan Transition, 16)
+	go func() {
+		defer close(ch)
+		poller := NewPoller(opts, time.Now())
+		poller.Poll(time.Now())
+		for {
+			if !WaitUntil(ctx, poller.NextChange(time.Now()).Add(subscribeMargin), nil, WallClockCheck) {
+				return
+			}
+			_, transitions := poller.Poll(time.Now())
+			for _, transition := range transitions {
+				if len(wanted) > 0 && !wanted[transition.Event] && !wanted[transition.Field] {
+					continue
+				}
+				select {
+				case ch <- transition:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,121 @@
+package daylight
+
+import (
+	"github.com/nathan-osman/go-sunrise"
+	"math"
+	"time"
+)
+
+// SupplementalMeasurement is the measurement of the daily supplemental light
+// points
+const SupplementalMeasurement = Measurement + "_supplemental"
+
+// SupplementalLight is the photoperiod supplemental light tops the natural
+// day length up to, and with a Schedule when the light is switched on:
+// before sunrise (morning), after sunset (evening) or half of the time each
+// (split)
+type SupplementalLight struct {
+	TargetPhotoperiod time.Duration
+	Schedule          string
+}
+
+// Controls reports whether the light is switched on a schedule
+func (s SupplementalLight) Controls() bool {
+	return s.TargetPhotoperiod != 0 && s.Schedule != ""
+}
+
+// LightWindow is a period of artificial light, named morning, evening, or
+// midday for the window centered on solar noon on days without sunrise
+type LightWindow struct {
+	Name string
+	On   time.Time
+	Off  time.Time
+}
+
+// LightOn reports whether the artificial light is on at t
+func LightOn(windows []LightWindow, t time.Time) bool {
+	for _, window := range windows {
+		if !t.Before(window.On) && t.Before(window.Off) {
+			return true
+		}
+	}
+	return false
+}
+
+// SupplementalWindows returns when the artificial light is on during the solar date day
+// with the given sunrise and sunset, which are zero during polar night; there
+// are none when the natural day reaches the target
+func SupplementalWindows(s SupplementalLight, latitude, longitude float64, day, sunriseTime, sunsetTime time.Time) []LightWindow {
+	events := SunEvents{Sunrise: sunriseTime, Sunset: sunsetTime}
+	natural := DayLength(latitude, longitude, events, day.Year(), day.Month(), day.Day())
+	missing := s.TargetPhotoperiod - natural
+	if missing <= 0 {
+		return nil
+	}
+	if sunriseTime.IsZero() || sunsetTime.IsZero() {
+		noon := sunrise.JulianDayToTime(sunrise.MeanSolarNoon(longitude, day.Year(), day.Month(), day.Day()))
+		return []LightWindow{{Name: "midday", On: noon.Add(-s.TargetPhotoperiod / 2), Off: noon.Add(s.TargetPhotoperiod / 2)}}
+	}
+	switch s.Schedule {
+	case "evening":
+		return []LightWindow{{Name: "evening", On: sunsetTime, Off: sunsetTime.Add(missing)}}
+	case "split":
+		return []LightWindow{
+			{Name: "morning", On: sunriseTime.Add(-missing / 2), Off: sunriseTime},
+			{Name: "evening", On: sunsetTime, Off: sunsetTime.Add(missing - missing/2)},
+		}
+	}
+	return []LightWindow{{Name: "morning", On: sunriseTime.Add(-missing), Off: sunriseTime}}
+}
+
+// SupplementalPoller produces the daily supplemental light sample
+type SupplementalPoller struct {
+	Options Options
+	date    string
+}
+
+// NewSupplementalPoller returns a poller of the supplemental light samples
+// of opts, the first of which its first poll returns
+func NewSupplementalPoller(opts Options) *SupplementalPoller {
+	return &SupplementalPoller{Options: opts}
+}
+
+// Poll returns the sample of the solar day of now when it was not returned
+// yet, that is on the first poll of every day. Samples are stamped with the
+// local solar midnight starting the day, so writing one again after a restart
+// replaces it.
+func (p *SupplementalPoller) Poll(now time.Time) (Sample, bool) {
+	day := SolarDate(now, p.Options.Longitude)
+	date := day.Format("2006-01-02")
+	if date == p.date {
+		return Sample{}, false
+	}
+	p.date = date
+	return SupplementalSample(p.Options, day), true
+}
+
+// SupplementalSample computes the supplemental light sample of a solar date
+func SupplementalSample(opts Options, day time.Time) Sample {
+	events := DayEvents(opts, day.Year(), day.Month(), day.Day())
+	natural := DayLength(opts.Latitude, opts.Longitude, events, day.Year(), day.Month(), day.Day())
+	target := opts.SupplementalLight.TargetPhotoperiod
+	supplemental := math.Max(0, (target - natural).Hours())
+
+	sample := Sample{
+		Measurement: SupplementalMeasurement,
+		Time:        day.Add(-time.Duration(opts.Longitude / 15 * float64(time.Hour))),
+		Tags:        opts.tags(),
+		Fields: map[string]interface{}{
+			"natural_hours":      natural.Hours(),
+			"target_hours":       target.Hours(),
+			"supplemental_hours": supplemental,
+		},
+	}
+	if opts.SupplementalLight.Controls() {
+		for _, window := range SupplementalWindows(opts.SupplementalLight, opts.Latitude, opts.Longitude, day, events.Sunrise, events.Sunset) {
+			sample.Fields[window.Name+"_lights_on"] = window.On.Unix()
+			sample.Fields[window.Name+"_lights_off"] = window.Off.Unix()
+		}
+	}
+	return sample
+}
@@ -0,0 +1,50 @@
+package daylight
+
+import (
+	"fmt"
+	"github.com/nathan-osman/go-sunrise"
+	"time"
+)
+
+// Threshold is a named boolean field telling whether the sun is at or above
+// an elevation, e.g. lights_on = -4° or solar_panels = +10°
+type Threshold struct {
+	Name      string
+	Elevation float64
+}
+
+// ElevationBand is a range of solar elevations in degrees, from Low up to
+// but excluding High, such as the golden and blue hours
+type ElevationBand struct {
+	Low  float64
+	High float64
+}
+
+// Validate checks that the band lies within [-90, 90] and is not empty;
+// name is the field it is reported as
+func (b ElevationBand) Validate(name string) error {
+	if b.Low < -90 || b.High > 90 || b.Low >= b.High {
+		return fmt.Errorf("invalid %s, low %g and high %g must satisfy -90 <= low < high <= 90", name, b.Low, b.High)
+	}
+	return nil
+}
+
+// Contains reports whether the sun at an elevation is within the band
+func (b ElevationBand) Contains(elevation float64) bool {
+	return elevation >= b.Low && elevation < b.High
+}
+
+// ThresholdCrossings returns when the sun rises through and sets through the
+// threshold's elevation on a solar date, which are zero when it does not
+func ThresholdCrossings(t Threshold, latitude, longitude float64, day time.Time) (rising, setting time.Time) {
+	return sunrise.TimeOfElevation(latitude, longitude, t.Elevation, day.Year(), day.Month(), day.Day())
+}
+
+// BandEdges returns when the sun enters and leaves the band on a solar date: in
+// the morning through Low and High as it rises, in the evening through High
+// and Low as it sets. Edges that do not happen are zero.
+func BandEdges(b ElevationBand, latitude, longitude float64, day time.Time) (enter, leave []time.Time) {
+	lowRising, lowSetting := sunrise.TimeOfElevation(latitude, longitude, b.Low, day.Year(), day.Month(), day.Day())
+	highRising, highSetting := sunrise.TimeOfElevation(latitude, longitude, b.High, day.Year(), day.Month(), day.Day())
+	return []time.Time{lowRising, highSetting}, []time.Time{highRising, lowSetting}
+}
@@ -0,0 +1,63 @@
+package daylight
+
+import (
+	"context"
+	"time"
+)
+
+// WallClockCheck is how often a sleeping poll loop checks the wall clock by
+// default.
+// Timers run on the monotonic clock, which stands still while the system is
+// suspended, so without the check a loop would oversleep by the time spent
+// suspended.
+const WallClockCheck = 10 * time.Second
+
+// NextBoundary returns the first multiple of interval since the Unix epoch
+// after t, so that a pollInterval of 1m samples on the minute and one of 1h
+// on the hour
+func NextBoundary(t time.Time, interval time.Duration) time.Time {
+	// Truncate counts from the zero time, whose multiples of intervals that
+	// do not divide a day are not those since the epoch
+	t = t.Round(0)
+	since := t.Sub(time.Unix(0, 0)) % interval
+	if since < 0 {
+		since += interval
+	}
+	return t.Add(interval - since)
+}
+
+// WaitUntil sleeps until the wall clock reaches wake and returns true, or
+// returns false as soon as interrupt receives or ctx is done. Checking the
+// wall clock every check, it notices within check when the system was
+// suspended past wake; a check of zero never wakes in between, as low-power
+// mode needs.
+func WaitUntil(ctx context.Context, wake time.Time, interrupt <-chan struct{}, check time.Duration) bool {
+	wake = wake.Round(0)
+	timer := time.NewTimer(time.Until(wake))
+	defer timer.Stop()
+	var checks <-chan time.Time
+	if check > 0 {
+		ticker := time.NewTicker(check)
+		defer ticker.Stop()
+		checks = ticker.C
+	}
+	for {
+		select {
+		case <-timer.C:
+			// Comparing with the monotonic reading stripped, a timer firing
+			// early can not end the wait
+			if !time.Now().Round(0).Before(wake) {
+				return true
+			}
+			timer.Reset(time.Until(wake))
+		case <-checks:
+			if !time.Now().Round(0).Before(wake) {
+				return true
+			}
+		case <-interrupt:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
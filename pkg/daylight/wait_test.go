@@ -0,0 +1,30 @@
+package daylight
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextBoundary(t *testing.T) {
+	tests := []struct {
+		name     string
+		t        time.Time
+		interval time.Duration
+		want     time.Time
+	}{
+		{"on the minute", time.Date(2026, 6, 1, 12, 0, 30, 0, time.UTC), time.Minute, time.Date(2026, 6, 1, 12, 1, 0, 0, time.UTC)},
+		{"on the hour", time.Date(2026, 6, 1, 12, 59, 59, 0, time.UTC), time.Hour, time.Date(2026, 6, 1, 13, 0, 0, 0, time.UTC)},
+		{"from a boundary", time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC), time.Minute, time.Date(2026, 6, 1, 12, 1, 0, 0, time.UTC)},
+		{"not dividing a day", time.Unix(7*60*1000+1, 0), 7 * time.Minute, time.Unix(7*60*1001, 0)},
+		{"before the epoch", time.Unix(-7*60*1000-1, 0), 7 * time.Minute, time.Unix(-7*60*1000, 0)},
+		{"in another zone", time.Date(2026, 6, 1, 12, 10, 0, 0, time.FixedZone("UTC+5:30", 5*3600+1800)), time.Hour, time.Date(2026, 6, 1, 12, 30, 0, 0, time.FixedZone("UTC+5:30", 5*3600+1800))},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := NextBoundary(test.t, test.interval)
+			if !got.Equal(test.want) {
+				t.Errorf("NextBoundary(%s, %s) = %s, want %s", test.t, test.interval, got, test.want)
+			}
+		})
+	}
+}
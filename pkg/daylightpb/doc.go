@@ -0,0 +1,5 @@
+// Package daylightpb holds the protocol buffer messages and gRPC service of
+// the daylight event stream, generated from daylight.proto.
+package daylightpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative daylight.proto
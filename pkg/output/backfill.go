@@ -0,0 +1,246 @@
+//go:build !slim || influxdb
+
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	influx "github.com/influxdata/influxdb-client-go/v2"
+	influxAPI "github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"time"
+)
+
+// BackfillOptions tune long backfills. Rate limits the points written per
+// second, unlimited when 0. Every Progress the written points are flushed,
+// the progress is logged and, with Checkpoint set, the time of the next
+// sample is saved to that file so an interrupted backfill can resume there.
+type BackfillOptions struct {
+	Rate       float64
+	Checkpoint string
+	Progress   time.Duration
+}
+
+// BackfillCheckpoint is the progress of a backfill saved to its checkpoint
+// file; it only applies to a backfill from the same start at the same
+// interval, so that one ending now can resume later
+type BackfillCheckpoint struct {
+	From     time.Time     `json:"from"`
+	To       time.Time     `json:"to"`
+	Interval time.Duration `json:"interval"`
+	Next     time.Time     `json:"next"`
+}
+
+// LoadBackfillCheckpoint returns where to resume a backfill of [from, to),
+// which is from unless the checkpoint file holds the progress of a backfill
+// with the same start and interval
+func LoadBackfillCheckpoint(path string, from, to time.Time, interval time.Duration) time.Time {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return from
+	}
+	var checkpoint BackfillCheckpoint
+	err = json.Unmarshal(data, &checkpoint)
+	if err != nil || !checkpoint.From.Equal(from) || checkpoint.Interval != interval ||
+		checkpoint.Next.Before(from) || checkpoint.Next.After(to) {
+		log.WithFields(log.Fields{
+			"op":         "LoadBackfillCheckpoint",
+			"checkpoint": path,
+		}).Warn("checkpoint does not match the backfill, starting over")
+		return from
+	}
+	return checkpoint.Next
+}
+
+// SaveBackfillCheckpoint replaces the checkpoint file atomically
+func SaveBackfillCheckpoint(path string, checkpoint BackfillCheckpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("unable to encode backfill checkpoint, %s", err)
+	}
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, data, 0644)
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to save backfill checkpoint %s, %s", path, err)
+	}
+	return nil
+}
+
+// Backfill computes the samples at every interval in [from, to) with a poller
+// of its own and writes them to InfluxDB, returning how many were written
+func Backfill(ctx context.Context, cfg config.Configuration, writeAPI influxAPI.WriteAPI, from, to time.Time, interval time.Duration, options BackfillOptions) (int, error) {
+	if interval <= 0 {
+		return 0, fmt.Errorf("backfill interval must be positive")
+	}
+	// One request ID covers the whole backfill
+	ctx = config.WithRequestID(ctx, config.NewRequestID())
+	start := from
+	if options.Checkpoint != "" {
+		start = LoadBackfillCheckpoint(options.Checkpoint, from, to, interval)
+	}
+	total := int((to.Sub(start) + interval - 1) / interval)
+	poller := daylight.NewPoller(cfg.DaylightOptions(), start)
+	began := time.Now()
+	reported := began
+	written := 0
+	// interrupted saves the progress up to t when the backfill is cancelled
+	interrupted := func(t time.Time) (int, error) {
+		if options.Checkpoint != "" {
+			writeAPI.Flush()
+			err := SaveBackfillCheckpoint(options.Checkpoint, BackfillCheckpoint{From: from, To: to, Interval: interval, Next: t})
+			if err != nil {
+				return written, err
+			}
+		}
+		return written, ctx.Err()
+	}
+	for t := start; t.Before(to); t = t.Add(interval) {
+		if ctx.Err() != nil {
+			return interrupted(t)
+		}
+		if options.Rate > 0 {
+			// Pace the points evenly instead of in bursts
+			due := began.Add(time.Duration(float64(written) / options.Rate * float64(time.Second)))
+			if wait := time.Until(due); wait > 0 {
+				select {
+				case <-ctx.Done():
+					return interrupted(t)
+				case <-time.After(wait):
+				}
+			}
+		}
+		if options.Progress > 0 && time.Since(reported) >= options.Progress {
+			reported = time.Now()
+			err := backfillProgress(ctx, writeAPI, options, BackfillCheckpoint{From: from, To: to, Interval: interval, Next: t}, written, total, began)
+			if err != nil {
+				return written, err
+			}
+		}
+		sample, _ := poller.Poll(t)
+		if len(cfg.Hooks) > 0 {
+			sample = ApplyHooks(ctx, cfg.Hooks, sample)
+		}
+		writeAPI.WritePoint(SamplePoint(sample))
+		written++
+	}
+	if options.Checkpoint != "" {
+		writeAPI.Flush()
+		os.Remove(options.Checkpoint)
+	}
+	return written, nil
+}
+
+// backfillProgress flushes the points written so far, saves the checkpoint
+// and logs the progress of a backfill
+func backfillProgress(ctx context.Context, writeAPI influxAPI.WriteAPI, options BackfillOptions, checkpoint BackfillCheckpoint, written, total int, began time.Time) error {
+	writeAPI.Flush()
+	if options.Checkpoint != "" {
+		err := SaveBackfillCheckpoint(options.Checkpoint, checkpoint)
+		if err != nil {
+			return err
+		}
+	}
+	elapsed := time.Since(began)
+	rate := float64(written) / elapsed.Seconds()
+	fields := log.Fields{
+		"op":       "Backfill",
+		"at":       checkpoint.Next.Format(time.RFC3339),
+		"written":  written,
+		"total":    total,
+		"progress": fmt.Sprintf("%.1f%%", 100*float64(written)/float64(total)),
+		"rate":     fmt.Sprintf("%.0f/s", rate),
+	}
+	if rate > 0 {
+		fields["remaining"] = config.FormatDuration(time.Duration(float64(total-written) / rate * float64(time.Second)).Round(time.Second))
+	}
+	config.Logger(ctx).WithFields(fields).Info("backfilling")
+	return nil
+}
+
+// LastPointTime returns the time of the newest sample in InfluxDB within
+// window of now, which is zero when there is none
+func LastPointTime(ctx context.Context, cfg *config.Configuration, client influx.Client, now time.Time, window time.Duration) (time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	if cfg.InfluxDB.Version == config.InfluxDB3Version {
+		return lastPointTimeFlightSQL(ctx, cfg, now, window)
+	}
+
+	bucket, err := config.InfluxWriteDestination(cfg)
+	if err != nil {
+		return time.Time{}, err
+	}
+	query := fmt.Sprintf(`from(bucket: %s)
+  |> range(start: %s)
+  |> filter(fn: (r) => r._measurement == %s)
+  |> last()
+  |> group()
+  |> sort(columns: ["_time"], desc: true)
+  |> limit(n: 1)`,
+		FluxString(bucket),
+		now.Add(-window).UTC().Format(time.RFC3339Nano),
+		FluxString(daylight.Measurement))
+	result, err := client.QueryAPI(cfg.InfluxDB.Organization).Query(ctx, query)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to query for the last point, %s", err)
+	}
+	defer result.Close()
+
+	var last time.Time
+	for result.Next() {
+		if t := result.Record().Time(); t.After(last) {
+			last = t
+		}
+	}
+	if result.Err() != nil {
+		return time.Time{}, fmt.Errorf("unable to read query result, %s", result.Err())
+	}
+	return last, nil
+}
+
+// FillGap backfills the samples since the last point in InfluxDB up to now,
+// at most MaxGap (a day by default) of them. Nothing is written when no point
+// is found within MaxGap, e.g. on a new installation.
+func FillGap(ctx context.Context, cfg *config.Configuration, client influx.Client, writeAPI influxAPI.WriteAPI, now time.Time) error {
+	maxGap := cfg.GapFill.MaxGap
+	if maxGap == 0 {
+		maxGap = 24 * time.Hour
+	}
+	interval := cfg.PollInterval
+
+	last, err := LastPointTime(ctx, cfg, client, now, maxGap)
+	if err != nil {
+		return err
+	}
+	if last.IsZero() {
+		log.WithFields(log.Fields{
+			"op":     "FillGap",
+			"maxGap": config.FormatDuration(maxGap),
+		}).Info("no recent point found, not backfilling")
+		return nil
+	}
+
+	from := last.Add(interval)
+	if from.Before(now.Add(-maxGap)) {
+		from = now.Add(-maxGap)
+	}
+	if !from.Before(now) {
+		return nil
+	}
+	written, err := Backfill(ctx, *cfg, writeAPI, from, now, interval, BackfillOptions{})
+	log.WithFields(log.Fields{
+		"op":      "FillGap",
+		"from":    from.Format(time.RFC3339),
+		"to":      now.Format(time.RFC3339),
+		"written": written,
+	}).Info("backfilled samples missed since the last point")
+	return err
+}
@@ -0,0 +1,101 @@
+package output
+
+import (
+	"fmt"
+	"net"
+)
+
+// ValidateNetwork checks a listen network: tcp binds dual-stack where the
+// system allows it, tcp4 and tcp6 restrict a server to one address family
+func ValidateNetwork(network string) error {
+	switch network {
+	case "", "tcp", "tcp4", "tcp6":
+		return nil
+	}
+	return fmt.Errorf("unknown network %q, expected tcp, tcp4 or tcp6", network)
+}
+
+// InterfaceAddress returns an address of the named network interface in the
+// family of network, preferring global addresses over link-local ones; IPv6
+// link-local addresses carry the interface as their zone
+func InterfaceAddress(name, network string) (string, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return "", fmt.Errorf("unable to find interface %s, %s", name, err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", fmt.Errorf("unable to list addresses of interface %s, %s", name, err)
+	}
+
+	var linkLocal string
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		ip := ipNet.IP
+		isIPv4 := ip.To4() != nil
+		if (network == "tcp4" && !isIPv4) || (network == "tcp6" && isIPv4) {
+			continue
+		}
+		if ip.IsLinkLocalUnicast() {
+			if linkLocal == "" {
+				linkLocal = ip.String()
+				if !isIPv4 {
+					linkLocal += "%" + iface.Name
+				}
+			}
+			continue
+		}
+		return ip.String(), nil
+	}
+	if linkLocal != "" {
+		return linkLocal, nil
+	}
+	return "", fmt.Errorf("interface %s has no usable %s address", name, networkFamily(network))
+}
+
+func networkFamily(network string) string {
+	switch network {
+	case "tcp4":
+		return "IPv4"
+	case "tcp6":
+		return "IPv6"
+	}
+	return "IP"
+}
+
+// BindAddress returns address with its host replaced by an address of iface
+// when iface is set; address may be a bare port such as :8080
+func BindAddress(network, iface, address string) (string, error) {
+	err := ValidateNetwork(network)
+	if err != nil {
+		return "", err
+	}
+	if iface == "" {
+		return address, nil
+	}
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid address %s, %s", address, err)
+	}
+	host, err := InterfaceAddress(iface, network)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// Listen opens a TCP listener on address, or on an address of iface when it
+// is set, in the family selected by network
+func Listen(network, iface, address string) (net.Listener, error) {
+	if network == "" {
+		network = "tcp"
+	}
+	bind, err := BindAddress(network, iface, address)
+	if err != nil {
+		return nil, err
+	}
+	return net.Listen(network, bind)
+}
@@ -0,0 +1,5 @@
+// Package output writes daylight samples to InfluxDB, files, named pipes,
+// MQTT brokers, OPC UA clients and LoRaWAN network servers. OpenOutputs opens
+// the outputs of a configuration, each behind a queue of its own; a single
+// writer such as NewFileWriter can also be used on its own.
+package output
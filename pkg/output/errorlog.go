@@ -0,0 +1,113 @@
+package output
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// WriteErrors counts failed InfluxDB writes, served with the other expvar
+// metrics on /v1/metrics
+var WriteErrors = expvar.NewInt("influxdb_write_errors")
+
+// RateLimitedLog logs the errors of one source according to an ErrorLog
+type RateLimitedLog struct {
+	config  config.ErrorLog
+	op      string
+	message string
+	noun    string
+	counter *expvar.Int
+
+	mu         sync.Mutex
+	tokens     float64
+	refilled   time.Time
+	lastLogged string
+	lastError  string
+	errors     uint64
+	suppressed uint64
+	window     time.Time
+}
+
+// NewRateLimitedLog creates a log of errors reported as message under op and
+// summarized as noun, e.g. "write errors", counting every error on counter
+// when it is not nil
+func NewRateLimitedLog(cfg config.ErrorLog, op, message, noun string, counter *expvar.Int, now time.Time) *RateLimitedLog {
+	cfg = cfg.WithDefaults()
+	return &RateLimitedLog{
+		config:   cfg,
+		op:       op,
+		message:  message,
+		noun:     noun,
+		counter:  counter,
+		tokens:   float64(cfg.Burst),
+		refilled: now,
+		window:   now,
+	}
+}
+
+// Error records an error, logging it unless it is rate limited or repeats
+// the previous logged error
+func (l *RateLimitedLog) Error(now time.Time, err error) {
+	if l.counter != nil {
+		l.counter.Add(1)
+	}
+	l.mu.Lock()
+	l.errors++
+	l.lastError = err.Error()
+	l.tokens += float64(now.Sub(l.refilled)) / float64(l.config.Interval)
+	if l.tokens > float64(l.config.Burst) {
+		l.tokens = float64(l.config.Burst)
+	}
+	l.refilled = now
+	if l.tokens < 1 || l.lastError == l.lastLogged {
+		l.suppressed++
+		l.mu.Unlock()
+		return
+	}
+	l.tokens--
+	l.lastLogged = l.lastError
+	l.mu.Unlock()
+
+	log.WithFields(log.Fields{
+		"op":    l.op,
+		"error": err,
+	}).Error(l.message)
+}
+
+// Summarize logs how many errors happened since the previous summary when any
+// of them were suppressed, and starts a new window in which each distinct
+// error is logged again
+func (l *RateLimitedLog) Summarize(now time.Time) {
+	l.mu.Lock()
+	errors, suppressed, lastError, window := l.errors, l.suppressed, l.lastError, now.Sub(l.window)
+	l.errors, l.suppressed, l.lastLogged, l.window = 0, 0, "", now
+	l.mu.Unlock()
+
+	if suppressed == 0 {
+		return
+	}
+	log.WithFields(log.Fields{
+		"op":         l.op,
+		"errors":     errors,
+		"suppressed": suppressed,
+		"error":      lastError,
+	}).Warn(fmt.Sprintf("%d %s in last %s", errors, l.noun, config.FormatDuration(window.Round(time.Second))))
+}
+
+// Run summarizes the log every SummaryInterval until ctx is done
+func (l *RateLimitedLog) Run(ctx context.Context) {
+	ticker := time.NewTicker(l.config.SummaryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			l.Summarize(now)
+		}
+	}
+}
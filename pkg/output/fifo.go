@@ -0,0 +1,144 @@
+//go:build !slim || fifo
+
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"syscall"
+	"time"
+)
+
+// FIFOBuiltIn reports whether the fifo output is built in
+const FIFOBuiltIn = true
+
+// FIFOWriter writes samples to a named pipe from a background goroutine, so
+// the poll loop never blocks waiting for a reader. Samples arriving while no
+// reader is attached, or while the reader lags, are dropped.
+type FIFOWriter struct {
+	config  config.FIFO
+	samples chan daylight.Sample
+	done    chan struct{}
+}
+
+// NewFIFOWriter prepares the pipe, creating it when configured to, and starts
+// writing to it in the background
+func NewFIFOWriter(cfg config.FIFO) (*FIFOWriter, error) {
+	info, err := os.Stat(cfg.Path)
+	if errors.Is(err, os.ErrNotExist) && cfg.Create {
+		err = mkfifo(cfg.Path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create fifo %s, %s", cfg.Path, err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to open fifo %s, %s", cfg.Path, err)
+	} else if info.Mode()&os.ModeNamedPipe == 0 {
+		return nil, fmt.Errorf("%s is not a named pipe", cfg.Path)
+	}
+
+	w := &FIFOWriter{
+		config:  cfg,
+		samples: make(chan daylight.Sample, 64),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Name returns fifo
+func (w *FIFOWriter) Name() string {
+	return "fifo"
+}
+
+// Write queues a sample for the pipe; samples are dropped rather than
+// reported when the reader is too slow
+func (w *FIFOWriter) Write(ctx context.Context, sample daylight.Sample) error {
+	select {
+	case <-w.done:
+	case w.samples <- sample:
+	default:
+		config.Logger(ctx).WithFields(log.Fields{
+			"op":   "FIFOWriter.Write",
+			"path": w.config.Path,
+		}).Warn("fifo reader too slow, dropping sample")
+	}
+	return nil
+}
+
+// Flush does nothing, samples are written as soon as a reader takes them
+func (w *FIFOWriter) Flush() {}
+
+// Close stops the writer; samples still queued are discarded
+func (w *FIFOWriter) Close() error {
+	close(w.done)
+	return nil
+}
+
+// encode formats a sample as one line of line protocol or JSON
+func (w *FIFOWriter) encode(sample daylight.Sample) ([]byte, error) {
+	if w.config.Format == "json" {
+		data, err := json.Marshal(sample)
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	}
+	return []byte(write.PointToLineProtocol(SamplePoint(sample), time.Nanosecond)), nil
+}
+
+func (w *FIFOWriter) run() {
+	var pipe *os.File
+	defer func() {
+		if pipe != nil {
+			pipe.Close()
+		}
+	}()
+	for {
+		var sample daylight.Sample
+		select {
+		case <-w.done:
+			return
+		case sample = <-w.samples:
+		}
+
+		if pipe == nil {
+			// Opening without blocking fails while nobody reads the pipe, in
+			// which case the sample is dropped rather than queued up stale
+			var err error
+			pipe, err = os.OpenFile(w.config.Path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"op":    "FIFOWriter.run",
+					"error": err,
+				}).Debug("no fifo reader, dropping sample")
+				continue
+			}
+		}
+
+		data, err := w.encode(sample)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"op":    "FIFOWriter.run",
+				"error": err,
+			}).Error("failed to encode sample")
+			continue
+		}
+		_, err = pipe.Write(data)
+		if err != nil {
+			// The reader went away; reopen for the next one
+			log.WithFields(log.Fields{
+				"op":    "FIFOWriter.run",
+				"error": err,
+			}).Warn("fifo reader disconnected")
+			pipe.Close()
+			pipe = nil
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix && (!slim || fifo)
+
+package output
+
+import (
+	"fmt"
+)
+
+func mkfifo(path string) error {
+	return fmt.Errorf("named pipes are not supported on this platform")
+}
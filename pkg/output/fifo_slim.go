@@ -0,0 +1,38 @@
+//go:build slim && !fifo
+
+package output
+
+import (
+	"context"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+)
+
+// FIFOBuiltIn reports whether the fifo output is built in
+const FIFOBuiltIn = false
+
+// FIFOWriter stands in for the fifo output in builds without it
+type FIFOWriter struct{}
+
+// NewFIFOWriter fails, the fifo output is not built in
+func NewFIFOWriter(cfg config.FIFO) (*FIFOWriter, error) {
+	return nil, NotBuiltIn("fifo")
+}
+
+// Name returns fifo
+func (w *FIFOWriter) Name() string {
+	return "fifo"
+}
+
+// Write fails, the fifo output is not built in
+func (w *FIFOWriter) Write(ctx context.Context, sample daylight.Sample) error {
+	return NotBuiltIn("fifo")
+}
+
+// Flush does nothing
+func (w *FIFOWriter) Flush() {}
+
+// Close does nothing
+func (w *FIFOWriter) Close() error {
+	return nil
+}
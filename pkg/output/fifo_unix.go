@@ -0,0 +1,11 @@
+//go:build unix && (!slim || fifo)
+
+package output
+
+import (
+	"syscall"
+)
+
+func mkfifo(path string) error {
+	return syscall.Mkfifo(path, 0600)
+}
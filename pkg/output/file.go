@@ -0,0 +1,186 @@
+//go:build !slim || file
+
+package output
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileBuiltIn reports whether the file output is built in
+const FileBuiltIn = true
+
+// FileWriter is the file output
+type FileWriter struct {
+	name    string
+	config  config.FileOutput
+	file    *os.File
+	size    int64
+	opened  time.Time
+	batch   bytes.Buffer
+	batched int
+}
+
+// NewFileWriter opens the configured file, creating it when missing, for
+// samples to be appended to
+func NewFileWriter(name string, cfg config.FileOutput) (*FileWriter, error) {
+	w := &FileWriter{name: name, config: cfg}
+	err := w.open()
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *FileWriter) open() error {
+	file, err := os.OpenFile(w.config.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to open output file %s, %s", w.config.Path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("unable to open output file %s, %s", w.config.Path, err)
+	}
+	w.file, w.size, w.opened = file, info.Size(), time.Now()
+	if info.Size() > 0 {
+		// Rotate by age from when the existing file was last written
+		w.opened = info.ModTime()
+	}
+	return nil
+}
+
+// Name returns the name of the output
+func (w *FileWriter) Name() string {
+	return w.name
+}
+
+// Write adds a sample to the batch, writing the batch once it is full
+func (w *FileWriter) Write(ctx context.Context, sample daylight.Sample) error {
+	if w.config.Format == "json" {
+		data, err := json.Marshal(sample)
+		if err != nil {
+			return fmt.Errorf("unable to encode sample, %s", err)
+		}
+		w.batch.Write(data)
+		w.batch.WriteByte('\n')
+	} else {
+		w.batch.WriteString(write.PointToLineProtocol(SamplePoint(sample), time.Nanosecond))
+	}
+	w.batched++
+	if w.batched < w.config.BatchSize {
+		return nil
+	}
+	return w.writeBatch()
+}
+
+// writeBatch appends the batch to the file, compressed as configured, syncs
+// it and rotates the file when due
+func (w *FileWriter) writeBatch() error {
+	if w.batched == 0 {
+		return nil
+	}
+	data, err := w.compress(w.batch.Bytes())
+	w.batch.Reset()
+	w.batched = 0
+	if err != nil {
+		return err
+	}
+	n, err := w.file.Write(data)
+	w.size += int64(n)
+	if err == nil {
+		err = w.file.Sync()
+	}
+	if err != nil {
+		return fmt.Errorf("unable to write to output file %s, %s", w.config.Path, err)
+	}
+
+	if (w.config.RotateSize > 0 && w.size >= w.config.RotateSize) ||
+		(w.config.RotateInterval > 0 && time.Since(w.opened) >= w.config.RotateInterval) {
+		return w.rotate()
+	}
+	return nil
+}
+
+// compress returns data as a complete gzip member or zstd frame, which
+// concatenated form a valid stream
+func (w *FileWriter) compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var encoder io.WriteCloser
+	var err error
+	switch w.config.Compression {
+	case "gzip":
+		encoder = gzip.NewWriter(&buf)
+	case "zstd":
+		encoder, err = zstd.NewWriter(&buf)
+		if err != nil {
+			return nil, fmt.Errorf("unable to compress samples, %s", err)
+		}
+	default:
+		return data, nil
+	}
+	_, err = encoder.Write(data)
+	if err == nil {
+		err = encoder.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to compress samples, %s", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// RotatedPath returns the name a file is renamed to when rotated at t, the
+// path with the time inserted before its extensions, e.g.
+// samples-20250102T150405Z.json.gz
+func RotatedPath(path string, t time.Time) string {
+	dir, base := filepath.Split(path)
+	stem, ext := base, ""
+	if i := strings.Index(base, "."); i > 0 {
+		stem, ext = base[:i], base[i:]
+	}
+	return filepath.Join(dir, stem+"-"+t.UTC().Format("20060102T150405Z")+ext)
+}
+
+func (w *FileWriter) rotate() error {
+	err := w.file.Close()
+	if err != nil {
+		return fmt.Errorf("unable to close output file %s, %s", w.config.Path, err)
+	}
+	err = os.Rename(w.config.Path, RotatedPath(w.config.Path, time.Now()))
+	if err != nil {
+		return fmt.Errorf("unable to rotate output file %s, %s", w.config.Path, err)
+	}
+	// Persist the rename before the new file appears
+	if dir, err := os.Open(filepath.Dir(w.config.Path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return w.open()
+}
+
+// Flush writes the pending batch
+func (w *FileWriter) Flush() {
+	w.writeBatch()
+}
+
+// Close writes the pending batch and closes the file
+func (w *FileWriter) Close() error {
+	err := w.writeBatch()
+	closeErr := w.file.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
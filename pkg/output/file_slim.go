@@ -0,0 +1,40 @@
+//go:build slim && !file
+
+package output
+
+import (
+	"context"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+)
+
+// FileBuiltIn reports whether the file output is built in
+const FileBuiltIn = false
+
+// FileWriter stands in for the file output in builds without it
+type FileWriter struct {
+	name string
+}
+
+// NewFileWriter fails, the file output is not built in
+func NewFileWriter(name string, cfg config.FileOutput) (*FileWriter, error) {
+	return nil, NotBuiltIn("file")
+}
+
+// Name returns the name of the output
+func (w *FileWriter) Name() string {
+	return w.name
+}
+
+// Write fails, the file output is not built in
+func (w *FileWriter) Write(ctx context.Context, sample daylight.Sample) error {
+	return NotBuiltIn("file")
+}
+
+// Flush does nothing
+func (w *FileWriter) Flush() {}
+
+// Close does nothing
+func (w *FileWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,124 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	log "github.com/sirupsen/logrus"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ApplyHook runs the hook on a sample
+func ApplyHook(ctx context.Context, h config.Hook, sample daylight.Sample) (daylight.Sample, error) {
+	if len(h.Command) == 0 {
+		return sample, fmt.Errorf("hook has no command")
+	}
+	timeout := h.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	input, err := json.Marshal(sample)
+	if err != nil {
+		return sample, fmt.Errorf("unable to encode sample, %s", err)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, h.Command[0], h.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if err != nil {
+		return sample, fmt.Errorf("hook %s failed, %s: %s", h.Command[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	var output struct {
+		Time   time.Time                  `json:"time"`
+		Tags   map[string]string          `json:"tags"`
+		Fields map[string]json.RawMessage `json:"fields"`
+	}
+	err = json.Unmarshal(stdout.Bytes(), &output)
+	if err != nil {
+		return sample, fmt.Errorf("unable to decode output of hook %s, %s", h.Command[0], err)
+	}
+	if output.Fields == nil {
+		return sample, fmt.Errorf("output of hook %s has no fields", h.Command[0])
+	}
+
+	result := daylight.Sample{
+		Time:   sample.Time,
+		Tags:   output.Tags,
+		Fields: make(map[string]interface{}, len(output.Fields)),
+	}
+	if result.Tags == nil {
+		// Hooks only adding fields may leave the tags out; an empty object
+		// removes them all
+		result.Tags = sample.Tags
+	}
+	if !output.Time.IsZero() {
+		result.Time = output.Time
+	}
+	for name, raw := range output.Fields {
+		value, err := decodeHookField(raw, sample.Fields[name])
+		if err != nil {
+			return sample, fmt.Errorf("invalid field %s from hook %s, %s", name, h.Command[0], err)
+		}
+		result.Fields[name] = value
+	}
+	return result, nil
+}
+
+// decodeHookField decodes a field value from hook output. JSON does not tell
+// integers and floats apart, so a field keeps the type it had going in to
+// avoid InfluxDB field type conflicts; new numeric fields are integers when
+// written without a fraction or exponent.
+func decodeHookField(raw json.RawMessage, previous interface{}) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value interface{}
+	err := decoder.Decode(&value)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := value.(type) {
+	case json.Number:
+		switch previous.(type) {
+		case float64:
+			return v.Float64()
+		case int64:
+			return v.Int64()
+		}
+		if i, err := v.Int64(); err == nil && !strings.ContainsAny(v.String(), ".eE") {
+			return i, nil
+		}
+		return v.Float64()
+	case bool, string:
+		return v, nil
+	}
+	return nil, fmt.Errorf("fields must be numbers, booleans or strings")
+}
+
+// ApplyHooks runs every hook on the sample in turn; a failing hook is logged
+// and skipped so that the sample is still written
+func ApplyHooks(ctx context.Context, hooks []config.Hook, sample daylight.Sample) daylight.Sample {
+	for _, hook := range hooks {
+		enriched, err := ApplyHook(ctx, hook, sample)
+		if err != nil {
+			config.Logger(ctx).WithFields(log.Fields{
+				"op":    "ApplyHooks",
+				"error": err,
+			}).Error("failed to apply hook")
+			continue
+		}
+		sample = enriched
+	}
+	return sample
+}
@@ -0,0 +1,479 @@
+//go:build !slim || influxdb
+
+package output
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	influx "github.com/influxdata/influxdb-client-go/v2"
+	influxAPI "github.com/influxdata/influxdb-client-go/v2/api"
+	influxLog "github.com/influxdata/influxdb-client-go/v2/log"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// InfluxDBBuiltIn reports whether the influxdb output is built in
+const InfluxDBBuiltIn = true
+
+// CheckRetention fails when the bucket written to keeps points for less than
+// the flush interval, so that points would expire before being flushed. Only
+// InfluxDB 2 reports the retention of a bucket; a failed lookup passes.
+func CheckRetention(ctx context.Context, client influx.Client, cfg *config.Configuration) error {
+	if cfg.InfluxDB.Bucket == "" || cfg.InfluxDB.Version == config.InfluxDB3Version || cfg.InfluxDB.Token == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	bucket, err := client.BucketsAPI().FindBucketByName(ctx, cfg.InfluxDB.Bucket)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"op":     "CheckRetention",
+			"bucket": cfg.InfluxDB.Bucket,
+			"error":  err,
+		}).Debug("unable to look up the retention of the bucket")
+		return nil
+	}
+	for _, rule := range bucket.RetentionRules {
+		retention := time.Duration(rule.EverySeconds) * time.Second
+		if flushInterval := cfg.InfluxDB.FlushEvery(); retention > 0 && flushInterval > retention {
+			return fmt.Errorf("flush interval %s exceeds the %s retention of bucket %s", config.FormatDuration(flushInterval), config.FormatDuration(retention), cfg.InfluxDB.Bucket)
+		}
+	}
+	return nil
+}
+
+// NewInfluxClient creates an InfluxDB client; write outcomes are reported to
+// tracker, for the output named name, when it is not nil
+func NewInfluxClient(cfg *config.Configuration, tracker *StatusTracker, name string) influx.Client {
+	var auth string
+	if cfg.InfluxDB.Token != "" {
+		auth = cfg.InfluxDB.Token
+	} else if cfg.InfluxDB.Username != "" && cfg.InfluxDB.Password != "" {
+		auth = fmt.Sprintf("%s:%s", cfg.InfluxDB.Username, cfg.InfluxDB.Password)
+	} else {
+		auth = ""
+	}
+
+	options := influx.DefaultOptions().
+		SetFlushInterval(uint(cfg.InfluxDB.FlushEvery().Milliseconds())).
+		SetTLSConfig(&tls.Config{
+			InsecureSkipVerify: cfg.InfluxDB.SkipVerifySsl,
+		})
+	delivery := cfg.InfluxDB.Delivery()
+	if delivery.BatchSize > 0 {
+		options.SetBatchSize(uint(delivery.BatchSize))
+	}
+	if delivery.Retry.MaxRetries > 0 {
+		retry := delivery.Retry.WithDefaults()
+		options.SetMaxRetries(uint(retry.MaxRetries)).
+			SetRetryInterval(uint(retry.Interval.Milliseconds())).
+			SetMaxRetryInterval(uint(retry.MaxInterval.Milliseconds()))
+	}
+	if cfg.Memory.MaxRetryPoints > 0 {
+		options.SetRetryBufferLimit(uint(cfg.Memory.MaxRetryPoints))
+	}
+	if tracker != nil {
+		httpClient := options.HTTPOptions().HTTPClient()
+		httpClient.Transport = &StatusTransport{
+			Next:    httpClient.Transport,
+			Tracker: tracker,
+			Output:  name,
+		}
+	}
+	if cfg.InfluxDB.Version == config.InfluxDB3Version {
+		httpClient := options.HTTPOptions().HTTPClient()
+		httpClient.Transport = &V3WriteTransport{
+			Next:     httpClient.Transport,
+			Database: cfg.InfluxDB.Database,
+			Token:    cfg.InfluxDB.Token,
+		}
+	}
+	if cfg.InfluxDB.UserAgent != "" || len(cfg.InfluxDB.Headers) > 0 {
+		httpClient := options.HTTPOptions().HTTPClient()
+		httpClient.Transport = &HeaderTransport{
+			Next:      httpClient.Transport,
+			UserAgent: cfg.InfluxDB.UserAgent,
+			Headers:   cfg.InfluxDB.Headers,
+		}
+	}
+
+	return influx.NewClientWithOptions(cfg.InfluxDB.Address, auth, options)
+}
+
+// HeaderTransport sets a custom User-Agent and extra headers on InfluxDB
+// requests, e.g. for gateways that route on a header
+type HeaderTransport struct {
+	Next      http.RoundTripper
+	UserAgent string
+	Headers   map[string]string
+}
+
+// RoundTrip sets the headers on a copy of the request and sends it
+func (t *HeaderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, value := range t.Headers {
+		req.Header.Set(name, value)
+	}
+	if t.UserAgent != "" {
+		req.Header.Set("User-Agent", t.UserAgent)
+	}
+	return t.Next.RoundTrip(req)
+}
+
+// InfluxConnect returns a client of the configured InfluxDB and its
+// non-blocking write API for the bucket or database samples go to
+func InfluxConnect(cfg *config.Configuration, tracker *StatusTracker, name string) (influx.Client, influxAPI.WriteAPI, error) {
+	writeDest, err := config.InfluxWriteDestination(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client := NewInfluxClient(cfg, tracker, name)
+
+	writeAPI := client.WriteAPI(cfg.InfluxDB.Organization, writeDest)
+
+	return client, writeAPI, nil
+}
+
+// WaitForInflux blocks until InfluxDB answers a ping, retrying with
+// exponential backoff; a timeout of zero waits indefinitely
+func WaitForInflux(ctx context.Context, client influx.Client, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	backoff := time.Second
+	for {
+		ok, err := client.Ping(ctx)
+		if ok {
+			return nil
+		}
+		if err == nil {
+			err = fmt.Errorf("ping failed")
+		}
+		log.WithFields(log.Fields{
+			"op":    "WaitForInflux",
+			"error": err,
+			"retry": backoff,
+		}).Warn("InfluxDB not reachable yet")
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("InfluxDB not reachable after %s, %s", timeout, err)
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, 30*time.Second)
+	}
+}
+
+// FluxString quotes a value for use in a Flux string literal
+func FluxString(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// V3WriteTransport redirects the client library's v2 write requests to the
+// native InfluxDB 3 write_lp endpoint of the configured database, so that
+// batching, retries and error reporting stay with the library
+type V3WriteTransport struct {
+	Next     http.RoundTripper
+	Database string
+	Token    string
+}
+
+// RoundTrip sends v2 write requests to write_lp and everything else as is
+func (t *V3WriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !strings.HasSuffix(req.URL.Path, "/api/v2/write") {
+		return t.Next.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	query := url.Values{
+		"db":        {t.Database},
+		"precision": {"nanosecond"},
+	}
+	req.URL.Path = strings.TrimSuffix(req.URL.Path, "/api/v2/write") + "/api/v3/write_lp"
+	req.URL.RawQuery = query.Encode()
+	if t.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.Token)
+	}
+	return t.Next.RoundTrip(req)
+}
+
+// InfluxTransport delivers spooled lines with blocking InfluxDB writes, so
+// that they only leave the spool once InfluxDB accepted them
+type InfluxTransport struct {
+	WriteAPI  influxAPI.WriteAPIBlocking
+	Tracker   *StatusTracker
+	Notifiers map[string]config.Notifier
+}
+
+// Name returns influxdb, naming the output the spool delivers to
+func (t InfluxTransport) Name() string {
+	return "influxdb"
+}
+
+// Deliver writes lines to InfluxDB, recording and notifying failures
+func (t InfluxTransport) Deliver(ctx context.Context, lines []string) error {
+	err := t.WriteAPI.WriteRecord(ctx, lines...)
+	if err != nil && t.Tracker != nil {
+		NotifyWriteError(ctx, t.Notifiers, t.Tracker, err)
+		if saveErr := t.Tracker.WriteFailed(time.Now(), err); saveErr != nil {
+			log.WithFields(log.Fields{
+				"op":    "InfluxTransport.Deliver",
+				"error": saveErr,
+			}).Error("failed to write status file")
+		}
+	}
+	return err
+}
+
+// InfluxOutput writes samples to InfluxDB, directly or through the spool,
+// verifies them by reading back when configured and monitors write errors
+type InfluxOutput struct {
+	name     string
+	client   influx.Client
+	writeAPI influxAPI.WriteAPI
+	spool    *SpoolStore
+	verifier *ReadBackVerifier
+	// cancel stops the background workers of the output, of which Close
+	// waits for those using the client
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+
+	// Write errors during Close mean the last batches were lost
+	mu       sync.Mutex
+	closing  bool
+	closeErr error
+	monitor  chan struct{}
+}
+
+// NewInfluxOutput connects to InfluxDB, waiting for it when configured to,
+// and backfills the gap since the last point when enabled
+func NewInfluxOutput(cfg *config.Configuration, output config.OutputConfig, tracker *StatusTracker) (*InfluxOutput, error) {
+	if output.InfluxDB != nil {
+		instance := *cfg
+		instance.InfluxDB = *output.InfluxDB
+		instance.Spool = output.Spool
+		instance.GapFill = config.GapFill{}
+		cfg = &instance
+	}
+	// The client batches and retries on its own, so hand it the settings
+	// of the output
+	if output.FlushInterval > 0 || output.BatchSize > 0 || output.Retry != (config.RetryPolicy{}) {
+		instance := *cfg
+		instance.InfluxDB = instance.InfluxDB.WithDelivery(output)
+		cfg = &instance
+	}
+	client, writeAPI, err := InfluxConnect(cfg, tracker, output.Name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize InfluxDB connection, %s", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	o := &InfluxOutput{name: output.Name, client: client, writeAPI: writeAPI, cancel: cancel}
+
+	if cfg.InfluxDB.Wait {
+		err = WaitForInflux(ctx, client, cfg.InfluxDB.WaitTimeout)
+		if err != nil {
+			o.Close()
+			return nil, err
+		}
+	}
+	err = CheckRetention(ctx, client, cfg)
+	if err != nil {
+		o.Close()
+		return nil, err
+	}
+
+	// With a spool, samples are stored locally and delivered to InfluxDB by
+	// a separate drainer whenever it is reachable
+	if cfg.Spool.Enabled() {
+		o.spool, err = OpenSpool(cfg.Spool)
+		if err != nil {
+			o.Close()
+			return nil, err
+		}
+		writeDest, _ := config.InfluxWriteDestination(cfg)
+		transport := InfluxTransport{
+			WriteAPI:  client.WriteAPIBlocking(cfg.InfluxDB.Organization, writeDest),
+			Tracker:   tracker,
+			Notifiers: cfg.Notifiers,
+		}
+		spoolErrorLog := NewRateLimitedLog(cfg.InfluxDB.ErrorLog, "SpoolStore.Drain", "failed to deliver spooled samples", "spool delivery errors", WriteErrors, time.Now())
+		go spoolErrorLog.Run(ctx)
+		o.workers.Add(1)
+		go func() {
+			defer o.workers.Done()
+			Supervise(ctx, output.Name+".spool", func(ctx context.Context) {
+				o.spool.Drain(ctx, transport, spoolErrorLog)
+			})
+		}()
+	}
+
+	// Gap filling writes a sample per poll interval, which transitions mode
+	// is meant to avoid
+	if cfg.GapFill.Enabled && cfg.WriteMode == config.WriteTransitions {
+		log.WithFields(log.Fields{
+			"op": "NewInfluxOutput",
+		}).Warn("gapFill does not apply in transitions write mode, skipping it")
+	} else if cfg.GapFill.Enabled {
+		err = FillGap(ctx, cfg, client, writeAPI, time.Now())
+		if err != nil {
+			log.WithFields(log.Fields{
+				"op":    "NewInfluxOutput",
+				"error": err,
+			}).Error("failed to backfill the gap since the last point")
+		}
+	}
+
+	if cfg.InfluxDB.VerifyInterval != 0 {
+		if time.Duration(cfg.InfluxDB.VerifyInterval)*time.Second <= cfg.InfluxDB.FlushInterval {
+			log.WithFields(log.Fields{
+				"op": "NewInfluxOutput",
+			}).Warn("verifyInterval should be longer than flushInterval or points may be checked before they are flushed")
+		}
+		o.verifier = NewReadBackVerifier(cfg, client, tracker)
+		o.workers.Add(1)
+		go func() {
+			defer o.workers.Done()
+			o.verifier.Run(ctx)
+		}()
+	}
+
+	// Monitor InfluxDB write errors, which repeat for as long as InfluxDB is
+	// down, so they are logged at a limited rate with periodic summaries
+	errorsCh := writeAPI.Errors()
+	writeErrorLog := NewRateLimitedLog(cfg.InfluxDB.ErrorLog, "output."+output.Name, "encountered error on writing to InfluxDB", "write errors", WriteErrors, time.Now())
+	go writeErrorLog.Run(ctx)
+	// The client library has a single logger for the whole process
+	influxLogOnce.Do(func() {
+		clientErrorLog := NewRateLimitedLog(cfg.InfluxDB.ErrorLog, "influxdb2client", "InfluxDB client error", "InfluxDB client errors", nil, time.Now())
+		go clientErrorLog.Run(context.Background())
+		influxLog.Log = influxLogger{errors: clientErrorLog}
+	})
+	o.monitor = make(chan struct{})
+	go Supervise(ctx, output.Name+".write_errors", func(context.Context) {
+		for err := range errorsCh {
+			o.failed(err)
+			NotifyWriteError(context.Background(), cfg.Notifiers, tracker, err)
+			tracker.OutputFailed(output.Name, time.Now(), err)
+			if saveErr := tracker.WriteFailed(time.Now(), err); saveErr != nil {
+				log.WithFields(log.Fields{
+					"op":    "output." + output.Name,
+					"error": saveErr,
+				}).Error("failed to write status file")
+			}
+			writeErrorLog.Error(time.Now(), err)
+		}
+		// The client closes the channel once its last batch is written
+		close(o.monitor)
+	})
+
+	return o, nil
+}
+
+// Name returns the name of the output
+func (o *InfluxOutput) Name() string {
+	return o.name
+}
+
+// batches marks InfluxOutput as a batchingOutput: the client writes batches
+// in the background and StatusTransport reports the successful ones
+func (o *InfluxOutput) batches() {}
+
+// Write appends the sample to the spool when there is one, and otherwise
+// hands it to the client, which writes it with the next batch
+func (o *InfluxOutput) Write(ctx context.Context, sample daylight.Sample) error {
+	if o.verifier != nil && sample.Measurement == "" {
+		o.verifier.Queued(sample)
+	}
+	if o.spool != nil {
+		return o.spool.Append(SamplePoint(sample))
+	}
+	o.writeAPI.WritePoint(SamplePoint(sample))
+	return nil
+}
+
+// Flush writes the pending batch
+func (o *InfluxOutput) Flush() {
+	o.writeAPI.Flush()
+}
+
+// failed records a write error reported while the output is closing
+func (o *InfluxOutput) failed(err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closing && o.closeErr == nil {
+		o.closeErr = err
+	}
+}
+
+// Close flushes the buffered points, stops the spool drainer and the
+// read-back verifier and closes the spool and the connection. It returns the
+// first write error reported by the flush.
+func (o *InfluxOutput) Close() error {
+	o.mu.Lock()
+	o.closing = true
+	o.mu.Unlock()
+	o.writeAPI.Flush()
+	o.cancel()
+	o.workers.Wait()
+	if o.spool != nil {
+		o.spool.Close()
+	}
+	o.client.Close()
+	if o.monitor != nil {
+		<-o.monitor
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closeErr != nil {
+		return fmt.Errorf("unable to flush points to InfluxDB, %s", o.closeErr)
+	}
+	return nil
+}
+
+// influxLogOnce sets the logger of the client library with the first output
+var influxLogOnce sync.Once
+
+// influxLogger adapts logrus to the InfluxDB client library's logger. The
+// library leaves level filtering to the logger and also reports every failed
+// write, so its errors go through a rate limited log and everything else is
+// logged at debug level.
+type influxLogger struct {
+	errors *RateLimitedLog
+}
+
+func (l influxLogger) Debugf(format string, v ...interface{}) {
+	log.WithField("op", "influxdb2client").Debugf(format, v...)
+}
+
+func (l influxLogger) Errorf(format string, v ...interface{}) {
+	l.errors.Error(time.Now(), errors.New(strings.TrimSpace(fmt.Sprintf(format, v...))))
+}
+
+func (l influxLogger) Debug(msg string) { l.Debugf("%s", msg) }
+
+func (l influxLogger) Infof(format string, v ...interface{}) { l.Debugf(format, v...) }
+
+func (l influxLogger) Info(msg string) { l.Debugf("%s", msg) }
+
+func (l influxLogger) Warnf(format string, v ...interface{}) { l.Debugf(format, v...) }
+
+func (l influxLogger) Warn(msg string) { l.Debugf("%s", msg) }
+
+func (l influxLogger) Error(msg string) { l.Errorf("%s", msg) }
+
+func (l influxLogger) SetLogLevel(logLevel uint) {}
+
+func (l influxLogger) LogLevel() uint { return influxLog.ErrorLevel }
+
+func (l influxLogger) SetPrefix(prefix string) {}
@@ -0,0 +1,115 @@
+//go:build !slim || (influxdb && influxdb3)
+
+package output
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"time"
+)
+
+// InfluxDB3BuiltIn reports whether Flight SQL queries of InfluxDB 3, for
+// read-back verification and gap filling, are built in
+const InfluxDB3BuiltIn = true
+
+// QueryFlightSQL runs a SQL query against the configured InfluxDB 3 database
+// over Flight SQL, passing each record batch of the result to handle
+func QueryFlightSQL(ctx context.Context, cfg config.InfluxDB, query string, handle func(arrow.Record)) error {
+	address, secure, err := cfg.FlightAddress()
+	if err != nil {
+		return err
+	}
+	creds := insecure.NewCredentials()
+	if secure {
+		creds = credentials.NewTLS(&tls.Config{InsecureSkipVerify: cfg.SkipVerifySsl})
+	}
+	client, err := flightsql.NewClient(address, nil, nil, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return fmt.Errorf("unable to connect to Flight SQL at %s, %s", address, err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, "database", cfg.Database)
+	if cfg.Token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+cfg.Token)
+	}
+
+	info, err := client.Execute(ctx, query)
+	if err != nil {
+		return fmt.Errorf("unable to run Flight SQL query, %s", err)
+	}
+	for _, endpoint := range info.Endpoint {
+		reader, err := client.DoGet(ctx, endpoint.Ticket)
+		if err != nil {
+			return fmt.Errorf("unable to fetch Flight SQL result, %s", err)
+		}
+		for reader.Next() {
+			handle(reader.Record())
+		}
+		err = reader.Err()
+		reader.Release()
+		if err != nil {
+			return fmt.Errorf("unable to read Flight SQL result, %s", err)
+		}
+	}
+	return nil
+}
+
+// verifyFlightSQL checks over Flight SQL that the point of sample exists with
+// its exact timestamp
+func (v *ReadBackVerifier) verifyFlightSQL(ctx context.Context, sample daylight.Sample) error {
+	query := fmt.Sprintf(`SELECT time FROM "%s" WHERE time = '%s' LIMIT 1`,
+		daylight.Measurement, sample.Time.UTC().Format(time.RFC3339Nano))
+	var rows int64
+	err := QueryFlightSQL(ctx, v.config.InfluxDB, query, func(record arrow.Record) {
+		rows += record.NumRows()
+	})
+	if err != nil {
+		return fmt.Errorf("unable to query for written point, %s", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("no point at %s in %s", sample.Time.Format(time.RFC3339Nano), v.config.InfluxDB.Database)
+	}
+	return nil
+}
+
+// lastPointTimeFlightSQL returns the time of the newest sample in InfluxDB 3
+// within window of now, which is zero when there is none
+func lastPointTimeFlightSQL(ctx context.Context, cfg *config.Configuration, now time.Time, window time.Duration) (time.Time, error) {
+	query := fmt.Sprintf(`SELECT max(time) AS time FROM "%s" WHERE time >= '%s'`,
+		daylight.Measurement, now.Add(-window).UTC().Format(time.RFC3339Nano))
+	var last time.Time
+	err := QueryFlightSQL(ctx, cfg.InfluxDB, query, func(record arrow.Record) {
+		if record.NumCols() == 0 {
+			return
+		}
+		column, ok := record.Column(0).(*array.Timestamp)
+		if !ok {
+			return
+		}
+		unit := column.DataType().(*arrow.TimestampType).Unit
+		for i := 0; i < column.Len(); i++ {
+			if column.IsValid(i) {
+				if t := column.Value(i).ToTime(unit); t.After(last) {
+					last = t
+				}
+			}
+		}
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to query for the last point, %s", err)
+	}
+	return last, nil
+}
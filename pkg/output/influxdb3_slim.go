@@ -0,0 +1,27 @@
+//go:build slim && influxdb && !influxdb3
+
+package output
+
+import (
+	"context"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"time"
+)
+
+// InfluxDB3BuiltIn reports whether Flight SQL queries of InfluxDB 3, for
+// read-back verification and gap filling, are built in
+const InfluxDB3BuiltIn = false
+
+// errInfluxDB3NotBuiltIn is the error of Flight SQL queries left out of a
+// slim build
+var errInfluxDB3NotBuiltIn = fmt.Errorf("querying InfluxDB 3 over Flight SQL is not built into this binary, rebuild it without -tags slim or with -tags slim,influxdb,influxdb3")
+
+func (v *ReadBackVerifier) verifyFlightSQL(ctx context.Context, sample daylight.Sample) error {
+	return errInfluxDB3NotBuiltIn
+}
+
+func lastPointTimeFlightSQL(ctx context.Context, cfg *config.Configuration, now time.Time, window time.Duration) (time.Time, error) {
+	return time.Time{}, errInfluxDB3NotBuiltIn
+}
@@ -0,0 +1,42 @@
+//go:build slim && !influxdb
+
+package output
+
+import (
+	"context"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+)
+
+// InfluxDBBuiltIn reports whether the influxdb output is built in
+const InfluxDBBuiltIn = false
+
+// InfluxDB3BuiltIn reports whether Flight SQL queries of InfluxDB 3, for
+// read-back verification and gap filling, are built in
+const InfluxDB3BuiltIn = false
+
+// InfluxOutput stands in for the influxdb output in builds without it
+type InfluxOutput struct{}
+
+// NewInfluxOutput fails, the influxdb output is not built in
+func NewInfluxOutput(cfg *config.Configuration, output config.OutputConfig, tracker *StatusTracker) (*InfluxOutput, error) {
+	return nil, NotBuiltIn("influxdb")
+}
+
+// Name returns influxdb
+func (o *InfluxOutput) Name() string {
+	return "influxdb"
+}
+
+// Write fails, the influxdb output is not built in
+func (o *InfluxOutput) Write(ctx context.Context, sample daylight.Sample) error {
+	return NotBuiltIn("influxdb")
+}
+
+// Flush does nothing
+func (o *InfluxOutput) Flush() {}
+
+// Close does nothing
+func (o *InfluxOutput) Close() error {
+	return nil
+}
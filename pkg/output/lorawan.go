@@ -0,0 +1,209 @@
+//go:build !slim || lorawan
+
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"math"
+	"net/http"
+	"time"
+)
+
+// LoRaWANBuiltIn reports whether the lorawan output is built in
+const LoRaWANBuiltIn = true
+
+// LoRaWANPayloadVersion is the first byte of every uplink payload, bumped
+// whenever the layout changes
+const LoRaWANPayloadVersion = 1
+
+// Flags of the second payload byte
+const (
+	LoRaWANDaylight byte = 1 << iota
+	LoRaWANDaylightOffset
+	LoRaWANCivilDaylight
+	LoRaWANNauticalDaylight
+	LoRaWANAstronomicalDaylight
+	LoRaWANSupplementalLight
+)
+
+// LoRaWANNone marks a missing value in the payload
+const LoRaWANNone = 0xFFFF
+
+// LoRaWANPayload encodes a sample of the configured location into 10 bytes,
+// big-endian:
+//
+//	0    version (1)
+//	1    flags: daylight, daylight_offset, civil, nautical and astronomical
+//	     daylight and supplemental_light from the lowest bit up
+//	2-3  solar elevation in hundredths of a degree, signed
+//	4-5  minutes until the next sunrise
+//	6-7  minutes until the next sunset
+//	8-9  day length in minutes
+//
+// Unsigned values are 0xFFFF when missing, e.g. no sunrise within the next
+// two days during polar night, and the elevation is -32768.
+func LoRaWANPayload(opts daylight.Options, sample daylight.Sample) []byte {
+	var flags byte
+	for i, field := range []string{"daylight", "daylight_offset", "civil_daylight", "nautical_daylight", "astronomical_daylight", "supplemental_light"} {
+		if on, _ := sample.Fields[field].(bool); on {
+			flags |= 1 << i
+		}
+	}
+	elevation := int16(math.MinInt16)
+	if value, ok := sample.Fields["elevation"].(float64); ok {
+		elevation = int16(math.Max(-9000, math.Min(9000, math.Round(value*100))))
+	}
+
+	day := daylight.SolarDate(sample.Time, opts.Longitude)
+	today := daylight.DayEvents(opts, day.Year(), day.Month(), day.Day())
+	next := day.AddDate(0, 0, 1)
+	tomorrow := daylight.DayEvents(opts, next.Year(), next.Month(), next.Day())
+	until := func(events ...time.Time) uint16 {
+		for _, event := range events {
+			if event.After(sample.Time) {
+				return loRaWANMinutes(event.Sub(sample.Time))
+			}
+		}
+		return LoRaWANNone
+	}
+	dayLength := uint16(LoRaWANNone)
+	if value, ok := sample.Fields["day_length_seconds"].(float64); ok {
+		dayLength = loRaWANMinutes(time.Duration(value * float64(time.Second)))
+	}
+
+	payload := []byte{LoRaWANPayloadVersion, flags}
+	payload = binary.BigEndian.AppendUint16(payload, uint16(elevation))
+	payload = binary.BigEndian.AppendUint16(payload, until(today.Sunrise, tomorrow.Sunrise))
+	payload = binary.BigEndian.AppendUint16(payload, until(today.Sunset, tomorrow.Sunset))
+	payload = binary.BigEndian.AppendUint16(payload, dayLength)
+	return payload
+}
+
+// loRaWANMinutes rounds a duration to minutes, capped below LoRaWANNone
+func loRaWANMinutes(d time.Duration) uint16 {
+	return uint16(math.Min(LoRaWANNone-1, math.Round(d.Minutes())))
+}
+
+// LoRaWANUplink is the json encoding of an uplink; Data is the payload in
+// base64
+type LoRaWANUplink struct {
+	DevEUI    string    `json:"devEUI,omitempty"`
+	FPort     uint8     `json:"fPort"`
+	Confirmed bool      `json:"confirmed"`
+	Data      []byte    `json:"data"`
+	Time      time.Time `json:"time"`
+}
+
+// LoRaWANOutput is the lorawan output
+type LoRaWANOutput struct {
+	name      string
+	config    config.LoRaWAN
+	site      daylight.Options
+	publisher *MQTTPublisher
+	client    *http.Client
+	flags     byte
+	sent      time.Time
+}
+
+// NewLoRaWANOutput returns the lorawan output of the configured location,
+// sending uplinks through the network server or, with mqtt, a broker
+func NewLoRaWANOutput(name string, cfg config.LoRaWAN, site config.Configuration) (*LoRaWANOutput, error) {
+	cfg = cfg.WithDefaults()
+	o := &LoRaWANOutput{
+		name:   name,
+		config: cfg,
+		site:   site.DaylightOptions(),
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+	if cfg.MQTT != nil {
+		publisher, err := NewMQTTPublisher(name, *cfg.MQTT, site)
+		if err != nil {
+			return nil, err
+		}
+		o.publisher = publisher
+	}
+	return o, nil
+}
+
+// Name returns the name of the output
+func (o *LoRaWANOutput) Name() string {
+	return o.name
+}
+
+// Write sends an uplink for a sample of the configured location when its
+// flags changed or the interval passed since the previous uplink
+func (o *LoRaWANOutput) Write(ctx context.Context, sample daylight.Sample) error {
+	if sample.Measurement != "" {
+		return nil
+	}
+	payload := LoRaWANPayload(o.site, sample)
+	if !o.sent.IsZero() && payload[1] == o.flags && sample.Time.Sub(o.sent) < o.config.Interval {
+		return nil
+	}
+
+	body := payload
+	if o.config.Encoding == "json" {
+		var err error
+		body, err = json.Marshal(LoRaWANUplink{
+			DevEUI:    o.config.DevEUI,
+			FPort:     o.config.FPort,
+			Confirmed: o.config.Confirmed,
+			Data:      payload,
+			Time:      sample.Time,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to encode LoRaWAN uplink, %s", err)
+		}
+	}
+
+	err := o.send(ctx, body)
+	if err != nil {
+		return err
+	}
+	o.flags, o.sent = payload[1], sample.Time
+	return nil
+}
+
+func (o *LoRaWANOutput) send(ctx context.Context, body []byte) error {
+	if o.publisher != nil {
+		return o.publisher.publishPayload(o.publisher.config.Topic, false, body)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to create LoRaWAN uplink request, %s", err)
+	}
+	if o.config.Encoding == "json" {
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	for name, value := range o.config.Headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to send LoRaWAN uplink, %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unable to send LoRaWAN uplink, %s", resp.Status)
+	}
+	return nil
+}
+
+// Flush does nothing, uplinks are sent as samples are written
+func (o *LoRaWANOutput) Flush() {}
+
+// Close disconnects from the broker when uplinks go through MQTT
+func (o *LoRaWANOutput) Close() error {
+	if o.publisher != nil {
+		return o.publisher.Close()
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+//go:build slim && !lorawan
+
+package output
+
+import (
+	"context"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+)
+
+// LoRaWANBuiltIn reports whether the lorawan output is built in
+const LoRaWANBuiltIn = false
+
+// LoRaWANOutput stands in for the lorawan output in builds without it
+type LoRaWANOutput struct {
+	name string
+}
+
+// NewLoRaWANOutput fails, the lorawan output is not built in
+func NewLoRaWANOutput(name string, cfg config.LoRaWAN, site config.Configuration) (*LoRaWANOutput, error) {
+	return nil, NotBuiltIn("lorawan")
+}
+
+// Name returns lorawan
+func (o *LoRaWANOutput) Name() string {
+	return o.name
+}
+
+// Write fails, the lorawan output is not built in
+func (o *LoRaWANOutput) Write(ctx context.Context, sample daylight.Sample) error {
+	return NotBuiltIn("lorawan")
+}
+
+// Flush does nothing
+func (o *LoRaWANOutput) Flush() {}
+
+// Close does nothing
+func (o *LoRaWANOutput) Close() error {
+	return nil
+}
@@ -0,0 +1,81 @@
+package output
+
+import (
+	"expvar"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"sync"
+)
+
+// QueuedSamples and QueuedBytes are the samples waiting in all output queues
+// and their estimated size, and QueueDrops counts the samples each output
+// dropped because its queue or the memory caps were full, served with the
+// other expvar metrics on /v1/metrics
+var (
+	QueuedSamples = expvar.NewInt("queued_samples")
+	QueuedBytes   = expvar.NewInt("queued_bytes")
+	QueueDrops    = expvar.NewMap("queue_drops")
+)
+
+// queueBudget accounts for the samples queued by all outputs against the
+// memory caps
+type queueBudget struct {
+	mu      sync.Mutex
+	limits  config.Memory
+	samples int
+	bytes   int64
+}
+
+var budget queueBudget
+
+// LimitQueues sets the caps on the samples queued by all outputs together,
+// applying to samples queued from then on
+func LimitQueues(memory config.Memory) {
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+	budget.limits = memory
+}
+
+// reserve accounts for a sample of size bytes about to be queued, reporting
+// false when it does not fit within the caps
+func (b *queueBudget) reserve(size int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.limits.MaxQueuedSamples > 0 && b.samples >= b.limits.MaxQueuedSamples {
+		return false
+	}
+	if b.limits.MaxQueuedBytes > 0 && b.bytes+size > b.limits.MaxQueuedBytes {
+		return false
+	}
+	b.samples++
+	b.bytes += size
+	QueuedSamples.Set(int64(b.samples))
+	QueuedBytes.Set(b.bytes)
+	return true
+}
+
+// release accounts for a reserved sample leaving its queue
+func (b *queueBudget) release(size int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.samples--
+	b.bytes -= size
+	QueuedSamples.Set(int64(b.samples))
+	QueuedBytes.Set(b.bytes)
+}
+
+// SampleSize estimates the bytes a queued sample holds: its strings plus a
+// fixed overhead for the sample and each tag and field
+func SampleSize(sample daylight.Sample) int64 {
+	size := 96 + len(sample.Measurement)
+	for key, value := range sample.Tags {
+		size += 48 + len(key) + len(value)
+	}
+	for key, value := range sample.Fields {
+		size += 48 + len(key)
+		if s, ok := value.(string); ok {
+			size += len(s)
+		}
+	}
+	return int64(size)
+}
@@ -0,0 +1,190 @@
+//go:build !slim || mqtt
+
+package output
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"github.com/eclipse/paho.mqtt.golang"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// MQTTBuiltIn reports whether the mqtt output is built in
+const MQTTBuiltIn = true
+
+// MQTTPublisher is the mqtt output
+type MQTTPublisher struct {
+	name     string
+	config   config.MQTT
+	client   mqtt.Client
+	previous daylight.Sample
+	// site stamps the transitions of the configured location
+	site daylight.Options
+	// sunDate is the solar date whose sunrise and sunset were published
+	sunDate string
+}
+
+// NewMQTTPublisher connects to the broker in the background, retrying until
+// it is reachable, and reconnects whenever the connection is lost. With
+// discovery the configurations are published, retained, on every connect so
+// that they survive the broker losing them.
+func NewMQTTPublisher(name string, cfg config.MQTT, site config.Configuration) (*MQTTPublisher, error) {
+	cfg = cfg.WithDefaults()
+	options := mqtt.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID(cfg.ClientID).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetTLSConfig(&tls.Config{InsecureSkipVerify: cfg.SkipVerifySsl}).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectionLostHandler(func(client mqtt.Client, err error) {
+			log.WithFields(log.Fields{
+				"op":     "MQTTPublisher",
+				"broker": cfg.Broker,
+				"error":  err,
+			}).Warn("lost connection to MQTT broker, reconnecting")
+		})
+	p := &MQTTPublisher{name: name, config: cfg, site: site.DaylightOptions()}
+	if cfg.Discovery {
+		options.SetWill(cfg.StatusTopic(), "offline", cfg.QoS, true)
+		options.SetOnConnectHandler(func(client mqtt.Client) {
+			// Publishing blocks until acknowledged, which the handler must not
+			go p.discover()
+		})
+	}
+	p.client = mqtt.NewClient(options)
+	// With connect retry the token only completes once connected; give the
+	// first connection a chance before the first sample
+	p.client.Connect().WaitTimeout(p.config.Timeout)
+	return p, nil
+}
+
+// discover publishes the discovery configurations and marks the device online
+func (p *MQTTPublisher) discover() {
+	for topic, message := range p.config.DiscoveryMessages() {
+		err := p.publish(topic, true, message)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"op":    "MQTTPublisher.discover",
+				"error": err,
+			}).Error("failed to publish Home Assistant discovery")
+			return
+		}
+	}
+	token := p.client.Publish(p.config.StatusTopic(), p.config.QoS, true, "online")
+	if token.WaitTimeout(p.config.Timeout) && token.Error() != nil {
+		log.WithFields(log.Fields{
+			"op":    "MQTTPublisher.discover",
+			"error": token.Error(),
+		}).Error("failed to publish Home Assistant availability")
+	}
+}
+
+// publishSun publishes the sunrise and sunset of the solar date of t, retained,
+// once per day
+func (p *MQTTPublisher) publishSun(t time.Time) error {
+	day := daylight.SolarDate(t, p.site.Longitude)
+	date := day.Format("2006-01-02")
+	if date == p.sunDate {
+		return nil
+	}
+	sunrise, sunset := daylight.SunriseSunset(p.site, day.Year(), day.Month(), day.Day())
+	times := SunTimes{Date: date}
+	if !sunrise.IsZero() {
+		times.Sunrise = &sunrise
+	}
+	if !sunset.IsZero() {
+		times.Sunset = &sunset
+	}
+	err := p.publish(p.config.SunTopic(), true, times)
+	if err == nil {
+		p.sunDate = date
+	}
+	return err
+}
+
+// Name returns the name of the output
+func (p *MQTTPublisher) Name() string {
+	return p.name
+}
+
+// Write publishes a sample, and the transitions since the previous sample of
+// the configured location as events
+func (p *MQTTPublisher) Write(ctx context.Context, sample daylight.Sample) error {
+	topic := p.config.Topic
+	if sample.Measurement == daylight.Measurement {
+		topic += "/locations/" + sample.Tags["location"]
+	} else if sample.Measurement != "" {
+		topic += "/" + sample.Measurement
+	}
+	err := p.publish(topic, p.config.Retain, sample)
+	if err != nil || sample.Measurement != "" {
+		return err
+	}
+
+	if p.config.Discovery {
+		err = p.publishSun(sample.Time)
+		if err != nil {
+			return err
+		}
+	}
+
+	transitions := daylight.DetectTransitions(p.previous, sample, p.site.ThresholdNames()...)
+	daylight.StampTransitions(p.site, transitions, p.previous.Time)
+	p.previous = sample
+	for _, transition := range transitions {
+		err = p.publish(p.config.EventTopic, p.config.EventRetain, transition)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *MQTTPublisher) publish(topic string, retain bool, value interface{}) error {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("unable to encode MQTT message, %s", err)
+	}
+	return p.publishPayload(topic, retain, payload)
+}
+
+// publishPayload publishes a message as is
+func (p *MQTTPublisher) publishPayload(topic string, retain bool, payload []byte) error {
+	// Messages published while disconnected are never completed, so drop
+	// them instead of waiting out the timeout
+	if !p.client.IsConnectionOpen() {
+		return fmt.Errorf("not connected to %s", p.config.Broker)
+	}
+	token := p.client.Publish(topic, p.config.QoS, retain, payload)
+	if !token.WaitTimeout(p.config.Timeout) {
+		return fmt.Errorf("timed out publishing to %s on %s", topic, p.config.Broker)
+	}
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("unable to publish to %s on %s, %s", topic, p.config.Broker, err)
+	}
+	return nil
+}
+
+// Flush does nothing, samples are published as they are written
+func (p *MQTTPublisher) Flush() {}
+
+// Close disconnects from the broker, waiting briefly for pending messages
+func (p *MQTTPublisher) Close() error {
+	p.client.Disconnect(250)
+	return nil
+}
+
+// SunTimes is the payload of the sun topic, with null for events that do not
+// happen during polar day or night
+type SunTimes struct {
+	Date    string     `json:"date"`
+	Sunrise *time.Time `json:"sunrise"`
+	Sunset  *time.Time `json:"sunset"`
+}
@@ -0,0 +1,44 @@
+//go:build slim && !mqtt
+
+package output
+
+import (
+	"context"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+)
+
+// MQTTBuiltIn reports whether the mqtt output is built in
+const MQTTBuiltIn = false
+
+// MQTTPublisher stands in for the mqtt output in builds without it
+type MQTTPublisher struct {
+	config config.MQTT
+}
+
+// NewMQTTPublisher fails, the mqtt output is not built in
+func NewMQTTPublisher(name string, cfg config.MQTT, site config.Configuration) (*MQTTPublisher, error) {
+	return nil, NotBuiltIn("mqtt")
+}
+
+// Name returns mqtt
+func (p *MQTTPublisher) Name() string {
+	return "mqtt"
+}
+
+// Write fails, the mqtt output is not built in
+func (p *MQTTPublisher) Write(ctx context.Context, sample daylight.Sample) error {
+	return NotBuiltIn("mqtt")
+}
+
+func (p *MQTTPublisher) publishPayload(topic string, retain bool, payload []byte) error {
+	return NotBuiltIn("mqtt")
+}
+
+// Flush does nothing
+func (p *MQTTPublisher) Flush() {}
+
+// Close does nothing
+func (p *MQTTPublisher) Close() error {
+	return nil
+}
@@ -0,0 +1,73 @@
+package output
+
+import (
+	"context"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Notify sends a notification to the named notifiers, or to all of them when
+// names is empty, in the background; failures are logged
+func Notify(ctx context.Context, notifiers map[string]config.Notifier, names []string, notification config.Notification) {
+	if len(names) == 0 {
+		for name := range notifiers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	for _, name := range names {
+		notifier, ok := notifiers[strings.ToLower(name)]
+		if !ok {
+			continue
+		}
+		go func(name string, notifier config.Notifier) {
+			// Delivery outlives the poll iteration, so only its request ID
+			// is carried over
+			err := notifier.Send(context.Background(), notification)
+			if err != nil {
+				config.Logger(ctx).WithFields(log.Fields{
+					"op":       "Notify",
+					"notifier": name,
+					"rule":     notification.Rule,
+					"error":    err,
+				}).Error("failed to send notification")
+			}
+		}(name, notifier)
+	}
+}
+
+// NotifyWriteError sends the write_error event when writes start failing,
+// that is on the first error since startup or since the last successful
+// write; call it before recording err with the tracker
+func NotifyWriteError(ctx context.Context, notifiers map[string]config.Notifier, tracker *StatusTracker, err error) {
+	status := tracker.Snapshot()
+	if !status.LastError.IsZero() && !status.LastWrite.After(status.LastError) {
+		return
+	}
+	NotifyEvent(ctx, notifiers, config.EventWriteError, config.Notification{
+		Time:      time.Now(),
+		Rule:      config.EventWriteError,
+		Message:   fmt.Sprintf("writing to InfluxDB failed, %s", err),
+		RequestID: config.RequestID(ctx),
+	})
+}
+
+// NotifyEvent sends the notification of a built-in event to the notifiers
+// subscribed to it
+func NotifyEvent(ctx context.Context, notifiers map[string]config.Notifier, event string, notification config.Notification) {
+	var names []string
+	for name, notifier := range notifiers {
+		if notifier.Subscribed(event) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+	Notify(ctx, notifiers, names, notification)
+}
@@ -0,0 +1,155 @@
+//go:build !slim || opcua
+
+package output
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/tls"
+	"fmt"
+	"github.com/gopcua/opcua/server"
+	"github.com/gopcua/opcua/ua"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"strings"
+)
+
+// OPCUABuiltIn reports whether the opcua output is built in
+const OPCUABuiltIn = true
+
+// OPCUAServer exposes the most recent sample as variables of an OPC UA
+// namespace so SCADA clients can browse and subscribe to them
+type OPCUAServer struct {
+	server    *server.Server
+	namespace *server.MapNamespace
+}
+
+// opcuaLogger adapts logrus to the printf-style logger gopcua expects
+type opcuaLogger struct{}
+
+func (opcuaLogger) Debug(msg string, args ...any) { log.WithField("op", "opcua").Debugf(msg, args...) }
+
+func (opcuaLogger) Info(msg string, args ...any) { log.WithField("op", "opcua").Infof(msg, args...) }
+
+func (opcuaLogger) Warn(msg string, args ...any) { log.WithField("op", "opcua").Warnf(msg, args...) }
+
+func (opcuaLogger) Error(msg string, args ...any) { log.WithField("op", "opcua").Errorf(msg, args...) }
+
+// NewOPCUAServer returns an OPC UA server exposing the sample fields as
+// variables, listening on port 4840 of all interfaces by default once started
+func NewOPCUAServer(cfg config.OPCUA) (*OPCUAServer, error) {
+	if cfg.Host == "" {
+		cfg.Host = "0.0.0.0"
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 4840
+	}
+	err := ValidateNetwork(cfg.Network)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Interface != "" {
+		cfg.Host, err = InterfaceAddress(cfg.Interface, cfg.Network)
+		if err != nil {
+			return nil, err
+		}
+	} else if cfg.Network == "tcp6" && cfg.Host == "0.0.0.0" {
+		cfg.Host = "::"
+	}
+
+	opts := []server.Option{
+		server.EnableSecurity("None", ua.MessageSecurityModeNone),
+		server.EnableAuthMode(ua.UserTokenTypeAnonymous),
+		server.ServerName("daylight-timeseries"),
+		server.ProductName("daylight-timeseries"),
+		server.SetLogger(opcuaLogger{}),
+		server.EndPoint(endpointHost(cfg.Host), cfg.Port),
+	}
+	// Clients are picky about the endpoint URL matching the address they
+	// connected to, so advertise every name they may use
+	hostnames := cfg.Hostnames
+	if len(hostnames) == 0 {
+		hostnames = []string{"localhost"}
+		if hostname, err := os.Hostname(); err == nil {
+			hostnames = append(hostnames, hostname)
+		}
+	}
+	for _, hostname := range hostnames {
+		opts = append(opts, server.EndPoint(endpointHost(hostname), cfg.Port))
+	}
+
+	if cfg.CertFile != "" && cfg.KeyFile != "" {
+		c, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load OPC UA certificate %s, %s", cfg.CertFile, err)
+		}
+		key, ok := c.PrivateKey.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("OPC UA private key %s must be RSA", cfg.KeyFile)
+		}
+		opts = append(opts, server.PrivateKey(key), server.Certificate(c.Certificate[0]))
+	}
+
+	s := server.New(opts...)
+	namespace := server.NewMapNamespace(s, "urn:daylight-timeseries")
+
+	return &OPCUAServer{
+		server:    s,
+		namespace: namespace,
+	}, nil
+}
+
+// endpointHost brackets IPv6 literals for use in an opc.tcp:// URL
+func endpointHost(host string) string {
+	if strings.Contains(host, ":") {
+		return "[" + strings.Replace(host, "%", "%25", 1) + "]"
+	}
+	return host
+}
+
+// Start starts listening for clients
+func (s *OPCUAServer) Start(ctx context.Context) error {
+	err := s.server.Start(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to start OPC UA server, %s", err)
+	}
+	return nil
+}
+
+// Publish updates the namespace variables from a sample and notifies
+// subscribers of the changes
+func (s *OPCUAServer) Publish(sample daylight.Sample) {
+	s.namespace.Mu.Lock()
+	defer s.namespace.Mu.Unlock()
+
+	s.namespace.Data["timestamp"] = sample.Time
+	s.namespace.ChangeNotification("timestamp")
+	for name, value := range sample.Fields {
+		s.namespace.Data[name] = value
+		s.namespace.ChangeNotification(name)
+	}
+}
+
+// Name returns opcua
+func (s *OPCUAServer) Name() string {
+	return "opcua"
+}
+
+// Write publishes the samples of the configured location; the namespace has
+// no place for location groups
+func (s *OPCUAServer) Write(ctx context.Context, sample daylight.Sample) error {
+	if sample.Measurement == "" {
+		s.Publish(sample)
+	}
+	return nil
+}
+
+// Flush does nothing, samples are published as they are written
+func (s *OPCUAServer) Flush() {}
+
+// Close stops the server, disconnecting its clients
+func (s *OPCUAServer) Close() error {
+	return s.server.Close()
+}
@@ -0,0 +1,43 @@
+//go:build slim && !opcua
+
+package output
+
+import (
+	"context"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+)
+
+// OPCUABuiltIn reports whether the opcua output is built in
+const OPCUABuiltIn = false
+
+// OPCUAServer stands in for the opcua output in builds without it
+type OPCUAServer struct{}
+
+// NewOPCUAServer fails, the opcua output is not built in
+func NewOPCUAServer(cfg config.OPCUA) (*OPCUAServer, error) {
+	return nil, NotBuiltIn("opcua")
+}
+
+// Start fails, the opcua output is not built in
+func (s *OPCUAServer) Start(ctx context.Context) error {
+	return NotBuiltIn("opcua")
+}
+
+// Name returns opcua
+func (s *OPCUAServer) Name() string {
+	return "opcua"
+}
+
+// Write fails, the opcua output is not built in
+func (s *OPCUAServer) Write(ctx context.Context, sample daylight.Sample) error {
+	return NotBuiltIn("opcua")
+}
+
+// Flush does nothing
+func (s *OPCUAServer) Flush() {}
+
+// Close does nothing
+func (s *OPCUAServer) Close() error {
+	return nil
+}
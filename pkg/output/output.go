@@ -0,0 +1,375 @@
+package output
+
+import (
+	"context"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Output is a destination the collector writes every sample to
+type Output interface {
+	Name() string
+	// Write hands a sample to the output, which may buffer it
+	Write(ctx context.Context, sample daylight.Sample) error
+	// Flush delivers buffered samples
+	Flush()
+	Close() error
+}
+
+// batchingOutput is implemented by outputs accepting samples into batches
+// that are delivered later, whose deliveries the output reports itself
+type batchingOutput interface {
+	batches()
+}
+
+// OutputFactories open the outputs of each type
+var OutputFactories = map[string]func(cfg *config.Configuration, output config.OutputConfig, tracker *StatusTracker) (Output, error){
+	"influxdb": func(cfg *config.Configuration, output config.OutputConfig, tracker *StatusTracker) (Output, error) {
+		return NewInfluxOutput(cfg, output, tracker)
+	},
+	"fifo": func(cfg *config.Configuration, output config.OutputConfig, tracker *StatusTracker) (Output, error) {
+		fifo := cfg.FIFO
+		if output.FIFO != nil {
+			fifo = *output.FIFO
+		}
+		if fifo.Path == "" {
+			return nil, fmt.Errorf("the fifo output needs fifo.path")
+		}
+		return NewFIFOWriter(fifo)
+	},
+	"opcua": func(cfg *config.Configuration, output config.OutputConfig, tracker *StatusTracker) (Output, error) {
+		server, err := NewOPCUAServer(cfg.OPCUA)
+		if err != nil {
+			return nil, err
+		}
+		err = server.Start(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		return server, nil
+	},
+	"file": func(cfg *config.Configuration, output config.OutputConfig, tracker *StatusTracker) (Output, error) {
+		if output.File.BatchSize == 0 {
+			output.File.BatchSize = output.BatchSize
+		}
+		return NewFileWriter(output.Name, output.File)
+	},
+	"mqtt": func(cfg *config.Configuration, output config.OutputConfig, tracker *StatusTracker) (Output, error) {
+		return NewMQTTPublisher(output.Name, output.Broker(cfg), *cfg)
+	},
+	"lorawan": func(cfg *config.Configuration, output config.OutputConfig, tracker *StatusTracker) (Output, error) {
+		return NewLoRaWANOutput(output.Name, output.LoRaWAN, *cfg)
+	},
+}
+
+// builtIn tells the output types this binary was built with
+var builtIn = map[string]bool{
+	"influxdb": InfluxDBBuiltIn,
+	"fifo":     FIFOBuiltIn,
+	"opcua":    OPCUABuiltIn,
+	"file":     FileBuiltIn,
+	"mqtt":     MQTTBuiltIn,
+	"lorawan":  LoRaWANBuiltIn,
+}
+
+// NotBuiltIn is the error of an output type left out of a slim build
+func NotBuiltIn(kind string) error {
+	return fmt.Errorf("the %s output is not built into this binary, rebuild it without -tags slim or with -tags slim,%s", kind, kind)
+}
+
+// ValidateOutputTypes checks that the outputs of the configuration are of
+// types this binary was built with
+func ValidateOutputTypes(cfg config.Configuration) error {
+	for _, output := range cfg.OutputConfigs() {
+		if _, ok := OutputFactories[output.Type]; !ok {
+			known := make([]string, 0, len(OutputFactories))
+			for kind := range OutputFactories {
+				known = append(known, kind)
+			}
+			sort.Strings(known)
+			return fmt.Errorf("unknown type %q of output %s, expected one of %s", output.Type, output.Name, strings.Join(known, ", "))
+		}
+		var err error
+		if !builtIn[output.Type] {
+			err = NotBuiltIn(output.Type)
+		}
+		switch {
+		case err != nil:
+		case output.Type == "lorawan" && output.LoRaWAN.MQTT != nil && !MQTTBuiltIn:
+			// Sending uplinks through a broker needs the mqtt output
+			err = NotBuiltIn("mqtt")
+		case output.Type == "influxdb" && !InfluxDB3BuiltIn:
+			influxDB := cfg.InfluxDB
+			if output.InfluxDB != nil {
+				influxDB = *output.InfluxDB
+			}
+			// Verifying and gap filling query InfluxDB 3 over Flight SQL
+			if influxDB.Version == config.InfluxDB3Version && (influxDB.VerifyInterval != 0 || (cfg.GapFill.Enabled && output.InfluxDB == nil)) {
+				err = fmt.Errorf("verifyInterval and gapFill with InfluxDB 3 need Flight SQL, which is not built into this binary, rebuild it without -tags slim or with -tags slim,influxdb,influxdb3")
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("invalid output %s, %s", output.Name, err)
+		}
+	}
+	return nil
+}
+
+// OpenOutputs opens the selected outputs in order, closing those already
+// open when one fails. Every output gets a queue and a goroutine of its own,
+// so that a slow or failing one does not hold up the others.
+func OpenOutputs(cfg *config.Configuration, tracker *StatusTracker) ([]Output, error) {
+	var outputs []Output
+	for _, outputConfig := range cfg.OutputConfigs() {
+		output, err := OpenOutput(cfg, outputConfig, tracker)
+		if err != nil {
+			for _, opened := range outputs {
+				opened.Close()
+			}
+			return nil, err
+		}
+		outputs = append(outputs, output)
+	}
+	return outputs, nil
+}
+
+// OpenOutput opens one output with its queue
+func OpenOutput(cfg *config.Configuration, outputConfig config.OutputConfig, tracker *StatusTracker) (Output, error) {
+	output, err := OutputFactories[outputConfig.Type](cfg, outputConfig, tracker)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open output %s, %s", outputConfig.Name, err)
+	}
+	return NewQueuedOutput(outputConfig, output, cfg.InfluxDB.ErrorLog, tracker), nil
+}
+
+// QueuedOutput writes to an output from a goroutine of its own, dropping
+// samples while its queue or the memory caps are full, logging its errors at
+// a limited rate and counting its writes in the tracker
+type QueuedOutput struct {
+	output   Output
+	samples  chan queuedSample
+	done     chan struct{}
+	errorLog *RateLimitedLog
+	dropLog  *RateLimitedLog
+	tracker  *StatusTracker
+	config   config.OutputConfig
+	// mu guards closed, since the poll loop may still be writing while the
+	// outputs are closed on shutdown
+	mu     sync.Mutex
+	closed bool
+	// sending counts the WriteWait and Flush calls sending to samples
+	// outside mu, which Close waits for before closing it
+	sending   sync.WaitGroup
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// queuedSample is a sample to write, of the estimated size reserved against
+// the memory caps, or, with flushed set, a request to flush that is answered
+// by closing flushed
+type queuedSample struct {
+	ctx     context.Context
+	sample  daylight.Sample
+	size    int64
+	flushed chan struct{}
+}
+
+// NewQueuedOutput wraps an output in a queue of QueueSize samples, 1000 by
+// default, written in the background
+func NewQueuedOutput(cfg config.OutputConfig, output Output, errorLog config.ErrorLog, tracker *StatusTracker) *QueuedOutput {
+	size := cfg.QueueSize
+	if size == 0 {
+		size = 1000
+	}
+	op := "output." + output.Name()
+	q := &QueuedOutput{
+		output:   output,
+		tracker:  tracker,
+		config:   cfg,
+		samples:  make(chan queuedSample, size),
+		done:     make(chan struct{}),
+		errorLog: NewRateLimitedLog(errorLog, op, "failed to write sample", "write errors of output "+output.Name(), nil, time.Now()),
+		dropLog:  NewRateLimitedLog(errorLog, op, "output queue full, dropping sample", "samples dropped by output "+output.Name(), nil, time.Now()),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go q.errorLog.Run(ctx)
+	go q.dropLog.Run(ctx)
+	go func() {
+		defer cancel()
+		defer close(q.done)
+		q.run()
+	}()
+	return q
+}
+
+// run writes the queued samples, flushing the output every batchSize samples
+// and flushInterval when configured
+func (q *QueuedOutput) run() {
+	var tick <-chan time.Time
+	if q.config.FlushInterval > 0 {
+		ticker := time.NewTicker(q.config.FlushInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	pending := 0
+	for {
+		select {
+		case item, ok := <-q.samples:
+			if !ok {
+				return
+			}
+			if item.flushed != nil {
+				q.output.Flush()
+				pending = 0
+				close(item.flushed)
+				continue
+			}
+			q.write(item)
+			budget.release(item.size)
+			pending++
+			if q.config.BatchSize > 0 && pending >= q.config.BatchSize {
+				q.output.Flush()
+				pending = 0
+			}
+		case <-tick:
+			if pending > 0 {
+				q.output.Flush()
+				pending = 0
+			}
+		}
+	}
+}
+
+// write writes a sample, retrying as configured
+func (q *QueuedOutput) write(item queuedSample) {
+	retry := q.config.Retry.WithDefaults()
+	wait := retry.Interval
+	for attempt := 0; ; attempt++ {
+		err := q.output.Write(item.ctx, item.sample)
+		if err == nil {
+			_, batched := q.output.(batchingOutput)
+			q.saveStatus(q.tracker.OutputWritten(q.output.Name(), time.Now(), PointSize(item.sample), !batched))
+			return
+		}
+		if attempt >= retry.MaxRetries {
+			q.saveStatus(q.tracker.OutputFailed(q.output.Name(), time.Now(), err))
+			q.errorLog.Error(time.Now(), err)
+			return
+		}
+		q.tracker.OutputRetried(q.output.Name())
+		time.Sleep(wait)
+		wait = min(2*wait, retry.MaxInterval)
+	}
+}
+
+// saveStatus logs a failure to save the status file
+func (q *QueuedOutput) saveStatus(err error) {
+	if err != nil {
+		log.WithFields(log.Fields{
+			"op":    "output." + q.output.Name(),
+			"error": err,
+		}).Error("failed to write status file")
+	}
+}
+
+// Name returns the name of the wrapped output
+func (q *QueuedOutput) Name() string {
+	return q.output.Name()
+}
+
+// Write queues the sample, never blocking the caller
+func (q *QueuedOutput) Write(ctx context.Context, sample daylight.Sample) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		// Samples of the last poll during shutdown are dropped
+		return nil
+	}
+	size := SampleSize(sample)
+	if !budget.reserve(size) {
+		QueueDrops.Add(q.output.Name(), 1)
+		q.dropLog.Error(time.Now(), fmt.Errorf("memory caps on queued samples reached"))
+		return nil
+	}
+	select {
+	case q.samples <- queuedSample{ctx: ctx, sample: sample, size: size}:
+		return nil
+	default:
+		budget.release(size)
+		QueueDrops.Add(q.output.Name(), 1)
+		q.dropLog.Error(time.Now(), fmt.Errorf("queue of %d samples full", cap(q.samples)))
+		return nil
+	}
+}
+
+// WriteWait queues the sample like Write, but waits for room in the queue and
+// within the memory caps instead of dropping it, until ctx is done
+func (q *QueuedOutput) WriteWait(ctx context.Context, sample daylight.Sample) error {
+	size := SampleSize(sample)
+	for !budget.reserve(size) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(queueWaitInterval):
+		}
+	}
+	if !q.startSending() {
+		budget.release(size)
+		return nil
+	}
+	defer q.sending.Done()
+	select {
+	case q.samples <- queuedSample{ctx: ctx, sample: sample, size: size}:
+		return nil
+	case <-ctx.Done():
+		budget.release(size)
+		return ctx.Err()
+	}
+}
+
+// queueWaitInterval is how often WriteWait checks whether queued samples
+// freed room within the memory caps
+const queueWaitInterval = 10 * time.Millisecond
+
+// startSending registers a send to samples, unless the output is closed
+func (q *QueuedOutput) startSending() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return false
+	}
+	q.sending.Add(1)
+	return true
+}
+
+// Flush waits for the queued samples to be written and flushes the output
+func (q *QueuedOutput) Flush() {
+	if !q.startSending() {
+		return
+	}
+	flushed := make(chan struct{})
+	q.samples <- queuedSample{flushed: flushed}
+	q.sending.Done()
+	<-flushed
+}
+
+// Close writes the queued samples and closes the output; only the first call
+// does, later ones return its error
+func (q *QueuedOutput) Close() error {
+	q.closeOnce.Do(func() {
+		q.mu.Lock()
+		q.closed = true
+		q.mu.Unlock()
+		q.sending.Wait()
+		close(q.samples)
+		<-q.done
+		q.closeErr = q.output.Close()
+	})
+	return q.closeErr
+}
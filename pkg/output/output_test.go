@@ -0,0 +1,227 @@
+package output
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeOutput counts the calls the queue makes to it
+type fakeOutput struct {
+	name string
+	// block, when set, holds up every write until it is closed, and started
+	// receives once per write before it waits
+	block   chan struct{}
+	started chan struct{}
+
+	mu                      sync.Mutex
+	writes, flushes, closes int
+}
+
+func (o *fakeOutput) Name() string {
+	return o.name
+}
+
+func (o *fakeOutput) Write(ctx context.Context, sample daylight.Sample) error {
+	if o.block != nil {
+		o.started <- struct{}{}
+		<-o.block
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.writes++
+	return nil
+}
+
+func (o *fakeOutput) Flush() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.flushes++
+}
+
+func (o *fakeOutput) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.closes++
+	return fmt.Errorf("closed %d times", o.closes)
+}
+
+// queueDrops returns the samples dropped by the output named name so far
+func queueDrops(name string) int64 {
+	if v, ok := QueueDrops.Get(name).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func TestQueuedOutput(t *testing.T) {
+	sample := daylight.Sample{Fields: map[string]interface{}{"daylight": true}}
+	tests := []struct {
+		name      string
+		queueSize int
+		batchSize int
+		blocking  bool
+		// run drives the queue, which is closed once more afterwards
+		run     func(t *testing.T, q *QueuedOutput, o *fakeOutput)
+		writes  int
+		flushes int
+		drops   int64
+	}{
+		{
+			name: "flush writes the queued samples",
+			run: func(t *testing.T, q *QueuedOutput, o *fakeOutput) {
+				for range 3 {
+					q.Write(context.Background(), sample)
+				}
+				q.Flush()
+				o.mu.Lock()
+				defer o.mu.Unlock()
+				if o.writes != 3 {
+					t.Errorf("%d writes after the flush, want 3", o.writes)
+				}
+			},
+			writes:  3,
+			flushes: 1,
+		},
+		{
+			name:      "every batch is flushed",
+			batchSize: 2,
+			run: func(t *testing.T, q *QueuedOutput, o *fakeOutput) {
+				for range 5 {
+					q.Write(context.Background(), sample)
+				}
+				q.Flush()
+			},
+			writes:  5,
+			flushes: 3,
+		},
+		{
+			name: "close writes the queued samples",
+			run: func(t *testing.T, q *QueuedOutput, o *fakeOutput) {
+				for range 3 {
+					q.Write(context.Background(), sample)
+				}
+				q.Close()
+			},
+			writes: 3,
+		},
+		{
+			name: "samples after close are dropped",
+			run: func(t *testing.T, q *QueuedOutput, o *fakeOutput) {
+				q.Close()
+				if err := q.Write(context.Background(), sample); err != nil {
+					t.Errorf("Write after Close = %v, want nil", err)
+				}
+				if err := q.WriteWait(context.Background(), sample); err != nil {
+					t.Errorf("WriteWait after Close = %v, want nil", err)
+				}
+				q.Flush()
+			},
+		},
+		{
+			name: "close twice",
+			run: func(t *testing.T, q *QueuedOutput, o *fakeOutput) {
+				first, second := q.Close(), q.Close()
+				if first == nil || second != first {
+					t.Errorf("Close returned %v, then %v, want the error of the output both times", first, second)
+				}
+			},
+		},
+		{
+			name:      "full queue drops",
+			queueSize: 1,
+			blocking:  true,
+			run: func(t *testing.T, q *QueuedOutput, o *fakeOutput) {
+				q.Write(context.Background(), sample)
+				<-o.started
+				// The first sample is being written, the second waits in the
+				// queue and the third finds it full
+				q.Write(context.Background(), sample)
+				q.Write(context.Background(), sample)
+				close(o.block)
+			},
+			writes: 2,
+			drops:  1,
+		},
+		{
+			name:      "write wait gives up with its context",
+			queueSize: 1,
+			blocking:  true,
+			run: func(t *testing.T, q *QueuedOutput, o *fakeOutput) {
+				q.Write(context.Background(), sample)
+				<-o.started
+				q.Write(context.Background(), sample)
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+				defer cancel()
+				if err := q.WriteWait(ctx, sample); err != context.DeadlineExceeded {
+					t.Errorf("WriteWait on a full queue = %v, want %v", err, context.DeadlineExceeded)
+				}
+				close(o.block)
+			},
+			writes: 2,
+		},
+		{
+			name:      "close waits for write wait and flush",
+			queueSize: 1,
+			blocking:  true,
+			run: func(t *testing.T, q *QueuedOutput, o *fakeOutput) {
+				q.Write(context.Background(), sample)
+				<-o.started
+				var wg sync.WaitGroup
+				wg.Add(3)
+				go func() {
+					defer wg.Done()
+					q.WriteWait(context.Background(), sample)
+				}()
+				go func() {
+					defer wg.Done()
+					q.Flush()
+				}()
+				go func() {
+					defer wg.Done()
+					q.Close()
+				}()
+				close(o.block)
+				wg.Wait()
+				if o.closes != 1 {
+					t.Errorf("output closed %d times, want once", o.closes)
+				}
+			},
+			// Whether the WriteWait and Flush calls make it in before the
+			// queue closes depends on scheduling
+			writes:  -1,
+			flushes: -1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			o := &fakeOutput{name: "fake " + test.name}
+			if test.blocking {
+				o.block = make(chan struct{})
+				o.started = make(chan struct{}, 10)
+			}
+			cfg := config.OutputConfig{Name: o.name, QueueSize: test.queueSize, BatchSize: test.batchSize}
+			q := NewQueuedOutput(cfg, o, config.ErrorLog{}, nil)
+			dropped := queueDrops(o.name)
+			test.run(t, q, o)
+			q.Close()
+			if o.closes != 1 {
+				t.Errorf("output closed %d times, want once", o.closes)
+			}
+			if test.writes >= 0 && o.writes != test.writes {
+				t.Errorf("%d writes, want %d", o.writes, test.writes)
+			}
+			if test.flushes >= 0 && o.flushes != test.flushes {
+				t.Errorf("%d flushes, want %d", o.flushes, test.flushes)
+			}
+			if drops := queueDrops(o.name) - dropped; drops != test.drops {
+				t.Errorf("%d samples dropped, want %d", drops, test.drops)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package output
+
+import (
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	"time"
+)
+
+// SamplePoint converts a sample to the point written to InfluxDB, tagged with
+// the schema version of its fields
+func SamplePoint(sample daylight.Sample) *write.Point {
+	if sample.Measurement != "" {
+		return MeasurementPoint(sample.Measurement, sample)
+	}
+	return MeasurementPoint(daylight.Measurement, sample)
+}
+
+// PointSize returns the bytes of a sample as line protocol
+func PointSize(sample daylight.Sample) int {
+	return len(write.PointToLineProtocol(SamplePoint(sample), time.Nanosecond))
+}
+
+// MeasurementPoint converts a sample to a point of the given measurement,
+// tagged with the schema version of its fields
+func MeasurementPoint(measurement string, sample daylight.Sample) *write.Point {
+	tags := map[string]string{"schema_version": SchemaVersion}
+	for key, value := range sample.Tags {
+		tags[key] = value
+	}
+	return write.NewPoint(
+		measurement,
+		tags,
+		sample.Fields,
+		sample.Time,
+	)
+}
+
+// SchemaVersion is written as the schema_version tag of every point and is
+// bumped whenever fields are renamed or change type, so that historical
+// points can be told apart and migrated
+const SchemaVersion = "1"
@@ -0,0 +1,124 @@
+//go:build !slim || influxdb
+
+package output
+
+import (
+	"context"
+	"fmt"
+	influx "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"github.com/iwvelando/daylight-timeseries/pkg/daylight"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// ReadBackVerifier periodically queries InfluxDB for a previously written
+// sample, catching writes that are accepted but never land (wrong bucket,
+// retention policy or permissions) which the write error channel misses
+type ReadBackVerifier struct {
+	mu       sync.Mutex
+	client   influx.Client
+	config   *config.Configuration
+	tracker  *StatusTracker
+	latest   *daylight.Sample
+	next     *daylight.Sample
+	interval time.Duration
+}
+
+// NewReadBackVerifier returns a verifier of the samples written through
+// client, to be started with Run
+func NewReadBackVerifier(cfg *config.Configuration, client influx.Client, tracker *StatusTracker) *ReadBackVerifier {
+	return &ReadBackVerifier{
+		client:   client,
+		config:   cfg,
+		tracker:  tracker,
+		interval: time.Duration(cfg.InfluxDB.VerifyInterval) * time.Second,
+	}
+}
+
+// Queued records the most recent sample handed to the write API
+func (v *ReadBackVerifier) Queued(sample daylight.Sample) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.latest = &sample
+}
+
+// Run checks, every interval, the sample that was the latest one at the
+// previous check so that it has had a whole interval to be flushed
+func (v *ReadBackVerifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(v.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		v.mu.Lock()
+		candidate := v.next
+		v.next = v.latest
+		v.mu.Unlock()
+		if candidate == nil {
+			continue
+		}
+
+		err := v.Verify(ctx, *candidate)
+		// A verifier stopped mid-query has nothing to report
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.WithFields(log.Fields{
+				"op":    "ReadBackVerifier.Run",
+				"point": candidate.Time.Format(time.RFC3339Nano),
+				"error": err,
+			}).Error("written point not found in InfluxDB")
+		}
+		if saveErr := v.tracker.VerifyResult(time.Now(), err); saveErr != nil {
+			log.WithFields(log.Fields{
+				"op":    "ReadBackVerifier.Run",
+				"error": saveErr,
+			}).Error("failed to write status file")
+		}
+	}
+}
+
+// Verify queries for the point of sample and returns an error unless it is
+// found with its exact timestamp
+func (v *ReadBackVerifier) Verify(ctx context.Context, sample daylight.Sample) error {
+	if v.config.InfluxDB.Version == config.InfluxDB3Version {
+		return v.verifyFlightSQL(ctx, sample)
+	}
+	bucket, err := config.InfluxWriteDestination(v.config)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf(`from(bucket: %s)
+  |> range(start: %s, stop: %s)
+  |> filter(fn: (r) => r._measurement == %s)
+  |> limit(n: 1)`,
+		FluxString(bucket),
+		sample.Time.UTC().Format(time.RFC3339Nano),
+		sample.Time.Add(time.Second).UTC().Format(time.RFC3339Nano),
+		FluxString(daylight.Measurement))
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	result, err := v.client.QueryAPI(v.config.InfluxDB.Organization).Query(ctx, query)
+	if err != nil {
+		return fmt.Errorf("unable to query for written point, %s", err)
+	}
+	defer result.Close()
+
+	for result.Next() {
+		if result.Record().Time().Equal(sample.Time) {
+			return nil
+		}
+	}
+	if result.Err() != nil {
+		return fmt.Errorf("unable to read query result, %s", result.Err())
+	}
+	return fmt.Errorf("no point at %s in %s", sample.Time.Format(time.RFC3339Nano), bucket)
+}
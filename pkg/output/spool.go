@@ -0,0 +1,343 @@
+package output
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transport delivers line protocol drained from the spool to a destination
+type Transport interface {
+	Name() string
+	Deliver(ctx context.Context, lines []string) error
+}
+
+// SpoolStore is the on-disk store of the spool, a directory of numbered
+// segment files of line protocol and one cursor file per transport recording
+// how far it has delivered
+type SpoolStore struct {
+	config  config.Spool
+	mu      sync.Mutex
+	current *os.File
+	segment int64
+	size    int64
+	cursors map[string]spoolCursor
+	notify  chan struct{}
+}
+
+// spoolCursor is a position in the store: a segment and a byte offset in it
+type spoolCursor struct {
+	segment int64
+	offset  int64
+}
+
+func (c spoolCursor) before(other spoolCursor) bool {
+	return c.segment < other.segment || (c.segment == other.segment && c.offset < other.offset)
+}
+
+// OpenSpool opens the store in the configured directory, creating it when
+// needed, and continues the newest segment
+func OpenSpool(cfg config.Spool) (*SpoolStore, error) {
+	cfg = cfg.WithDefaults()
+	err := os.MkdirAll(cfg.Directory, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create spool directory %s, %s", cfg.Directory, err)
+	}
+	s := &SpoolStore{config: cfg, cursors: map[string]spoolCursor{}, notify: make(chan struct{}, 1)}
+	segments, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) > 0 {
+		s.segment = segments[len(segments)-1]
+	}
+	err = s.open(s.segment)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SpoolStore) path(segment int64) string {
+	return filepath.Join(s.config.Directory, fmt.Sprintf("%016d.lp", segment))
+}
+
+// segments returns the numbers of the segment files in ascending order
+func (s *SpoolStore) segments() ([]int64, error) {
+	entries, err := os.ReadDir(s.config.Directory)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read spool directory %s, %s", s.config.Directory, err)
+	}
+	var segments []int64
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".lp")
+		if !ok {
+			continue
+		}
+		if segment, err := strconv.ParseInt(name, 10, 64); err == nil {
+			segments = append(segments, segment)
+		}
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i] < segments[j] })
+	return segments, nil
+}
+
+// open makes segment the one appended to; callers must hold the lock
+func (s *SpoolStore) open(segment int64) error {
+	f, err := os.OpenFile(s.path(segment), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to open spool segment %s, %s", s.path(segment), err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("unable to open spool segment %s, %s", s.path(segment), err)
+	}
+	if s.current != nil {
+		s.current.Close()
+	}
+	s.current, s.segment, s.size = f, segment, info.Size()
+	return nil
+}
+
+// Append stores points, starting a new segment once the current one reached
+// SegmentSize and dropping the oldest segments beyond MaxSize
+func (s *SpoolStore) Append(points ...*write.Point) error {
+	var b strings.Builder
+	for _, point := range points {
+		b.WriteString(write.PointToLineProtocol(point, time.Nanosecond))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.size >= s.config.SegmentSize {
+		err := s.open(s.segment + 1)
+		if err != nil {
+			return err
+		}
+		s.enforceMaxSize()
+	}
+	n, err := s.current.WriteString(b.String())
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("unable to append to spool segment %s, %s", s.current.Name(), err)
+	}
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// enforceMaxSize removes the oldest segments while the store is larger than
+// MaxSize, never the current one; callers must hold the lock
+func (s *SpoolStore) enforceMaxSize() {
+	segments, err := s.segments()
+	if err != nil {
+		return
+	}
+	var total int64
+	sizes := make([]int64, len(segments))
+	for i, segment := range segments {
+		if info, err := os.Stat(s.path(segment)); err == nil {
+			sizes[i] = info.Size()
+			total += sizes[i]
+		}
+	}
+	for i := 0; total > s.config.MaxSize && i < len(segments)-1; i++ {
+		err := os.Remove(s.path(segments[i]))
+		if err != nil {
+			continue
+		}
+		total -= sizes[i]
+		log.WithFields(log.Fields{
+			"op":      "SpoolStore.Append",
+			"segment": s.path(segments[i]),
+		}).Warn("spool full, dropped oldest undelivered samples")
+	}
+}
+
+func (s *SpoolStore) cursorPath(transport string) string {
+	return filepath.Join(s.config.Directory, transport+".cursor")
+}
+
+// loadCursor returns the saved position of a transport, the oldest segment
+// when it has none
+func (s *SpoolStore) loadCursor(transport string) spoolCursor {
+	var cursor spoolCursor
+	data, err := os.ReadFile(s.cursorPath(transport))
+	if err == nil {
+		fmt.Sscanf(string(data), "%d %d", &cursor.segment, &cursor.offset)
+	}
+	return cursor
+}
+
+// saveCursor persists the position of a transport and removes the segments
+// that every transport has delivered
+func (s *SpoolStore) saveCursor(transport string, cursor spoolCursor) error {
+	tmp := s.cursorPath(transport) + ".tmp"
+	err := os.WriteFile(tmp, []byte(fmt.Sprintf("%d %d\n", cursor.segment, cursor.offset)), 0644)
+	if err == nil {
+		err = os.Rename(tmp, s.cursorPath(transport))
+	}
+	if err != nil {
+		return fmt.Errorf("unable to save spool cursor of %s, %s", transport, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cursors[transport] = cursor
+	oldest := cursor
+	for _, c := range s.cursors {
+		if c.before(oldest) {
+			oldest = c
+		}
+	}
+	segments, err := s.segments()
+	if err != nil {
+		return err
+	}
+	for _, segment := range segments {
+		if segment < oldest.segment && segment != s.segment {
+			os.Remove(s.path(segment))
+		}
+	}
+	return nil
+}
+
+// read returns up to max complete lines at cursor and the position after
+// them, moving on to the next segment once a segment is exhausted
+func (s *SpoolStore) read(cursor spoolCursor, max int) ([]string, spoolCursor, error) {
+	for {
+		s.mu.Lock()
+		current := s.segment
+		s.mu.Unlock()
+
+		f, err := os.Open(s.path(cursor.segment))
+		if os.IsNotExist(err) {
+			// Dropped for size or never written; skip to the next segment
+			// that exists
+			segments, err := s.segments()
+			if err != nil {
+				return nil, cursor, err
+			}
+			next := spoolCursor{segment: current}
+			for _, segment := range segments {
+				if segment > cursor.segment {
+					next.segment = segment
+					break
+				}
+			}
+			if next.segment == cursor.segment {
+				return nil, cursor, nil
+			}
+			cursor = next
+			continue
+		}
+		if err != nil {
+			return nil, cursor, fmt.Errorf("unable to open spool segment %s, %s", s.path(cursor.segment), err)
+		}
+
+		var lines []string
+		next := cursor
+		_, err = f.Seek(cursor.offset, io.SeekStart)
+		if err == nil {
+			reader := bufio.NewReader(f)
+			for len(lines) < max {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					// A partial line is still being appended
+					break
+				}
+				next.offset += int64(len(line))
+				lines = append(lines, strings.TrimSuffix(line, "\n"))
+			}
+		}
+		f.Close()
+		if err != nil {
+			return nil, cursor, fmt.Errorf("unable to read spool segment %s, %s", s.path(cursor.segment), err)
+		}
+		if len(lines) > 0 || cursor.segment >= current {
+			return lines, next, nil
+		}
+		cursor = spoolCursor{segment: cursor.segment + 1}
+	}
+}
+
+// Drain delivers the store to transport until ctx is done, in order and in
+// batches of BatchSize. Failed deliveries are retried with backoff from one
+// second up to a minute, leaving the lines in the store meanwhile.
+func (s *SpoolStore) Drain(ctx context.Context, transport Transport, errorLog *RateLimitedLog) {
+	name := transport.Name()
+	cursor := s.loadCursor(name)
+	s.mu.Lock()
+	s.cursors[name] = cursor
+	s.mu.Unlock()
+
+	backoff := time.Second
+	for {
+		lines, next, err := s.read(cursor, s.config.BatchSize)
+		if err == nil && len(lines) > 0 {
+			err = transport.Deliver(ctx, lines)
+			if ctx.Err() != nil {
+				return
+			}
+			if err == nil {
+				cursor = next
+				backoff = time.Second
+				err = s.saveCursor(name, cursor)
+				if err != nil {
+					errorLog.Error(time.Now(), err)
+				}
+				continue
+			}
+		}
+		if err == nil && next != cursor {
+			// Moved past segments without lines
+			cursor = next
+			s.saveCursor(name, cursor)
+			continue
+		}
+
+		wait := time.Second
+		if err != nil {
+			errorLog.Error(time.Now(), err)
+			wait = backoff
+			if backoff *= 2; backoff > time.Minute {
+				backoff = time.Minute
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.notify:
+			if err != nil {
+				// New samples do not cut a backoff short
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(wait):
+				}
+			}
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Close closes the current segment
+func (s *SpoolStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current.Close()
+}
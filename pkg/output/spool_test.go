@@ -0,0 +1,210 @@
+package output
+
+import (
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// openTestSpool opens a store in a temporary directory holding segments,
+// keyed by number
+func openTestSpool(t *testing.T, segments map[int64]string) *SpoolStore {
+	t.Helper()
+	dir := t.TempDir()
+	layout := &SpoolStore{config: config.Spool{Directory: dir}}
+	for segment, text := range segments {
+		err := os.WriteFile(layout.path(segment), []byte(text), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := OpenSpool(config.Spool{Directory: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSpoolCursorFile(t *testing.T) {
+	tests := []struct {
+		name string
+		// file is the content of the cursor file, none when empty
+		file string
+		want spoolCursor
+	}{
+		{"no cursor", "", spoolCursor{}},
+		{"saved cursor", "3 120\n", spoolCursor{segment: 3, offset: 120}},
+		{"garbage", "three", spoolCursor{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := openTestSpool(t, nil)
+			if test.file != "" {
+				err := os.WriteFile(s.cursorPath("influxdb"), []byte(test.file), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := s.loadCursor("influxdb"); got != test.want {
+				t.Fatalf("loadCursor() = %+v, want %+v", got, test.want)
+			}
+			if got := s.loadCursor("other"); got != (spoolCursor{}) {
+				t.Errorf("loadCursor() of another transport = %+v, want none", got)
+			}
+			saved := spoolCursor{segment: test.want.segment + 1, offset: 7}
+			err := s.saveCursor("influxdb", saved)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.loadCursor("influxdb"); got != saved {
+				t.Errorf("loadCursor() after saveCursor(%+v) = %+v", saved, got)
+			}
+		})
+	}
+}
+
+func TestSpoolRead(t *testing.T) {
+	tests := []struct {
+		name     string
+		segments map[int64]string
+		cursor   spoolCursor
+		max      int
+		want     []string
+		next     spoolCursor
+	}{
+		{
+			name:     "from the start",
+			segments: map[int64]string{0: "a 1\nb 2\n"},
+			max:      10,
+			want:     []string{"a 1", "b 2"},
+			next:     spoolCursor{segment: 0, offset: 8},
+		},
+		{
+			name:     "up to max lines",
+			segments: map[int64]string{0: "a 1\nb 2\nc 3\n"},
+			max:      2,
+			want:     []string{"a 1", "b 2"},
+			next:     spoolCursor{segment: 0, offset: 8},
+		},
+		{
+			name:     "from an offset",
+			segments: map[int64]string{0: "a 1\nb 2\n"},
+			cursor:   spoolCursor{segment: 0, offset: 4},
+			max:      10,
+			want:     []string{"b 2"},
+			next:     spoolCursor{segment: 0, offset: 8},
+		},
+		{
+			name:     "partial line being appended",
+			segments: map[int64]string{0: "a 1\nb"},
+			max:      10,
+			want:     []string{"a 1"},
+			next:     spoolCursor{segment: 0, offset: 4},
+		},
+		{
+			name:     "end of the current segment",
+			segments: map[int64]string{0: "a 1\n"},
+			cursor:   spoolCursor{segment: 0, offset: 4},
+			max:      10,
+			next:     spoolCursor{segment: 0, offset: 4},
+		},
+		{
+			name:     "on to the next segment",
+			segments: map[int64]string{0: "a 1\n", 1: "b 2\n"},
+			cursor:   spoolCursor{segment: 0, offset: 4},
+			max:      10,
+			want:     []string{"b 2"},
+			next:     spoolCursor{segment: 1, offset: 4},
+		},
+		{
+			name:     "past dropped segments",
+			segments: map[int64]string{3: "c 3\n", 4: "d 4\n"},
+			cursor:   spoolCursor{segment: 1, offset: 4},
+			max:      10,
+			want:     []string{"c 3"},
+			next:     spoolCursor{segment: 3, offset: 4},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := openTestSpool(t, test.segments)
+			lines, next, err := s.read(test.cursor, test.max)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(lines, test.want) {
+				t.Errorf("read(%+v) = %q, want %q", test.cursor, lines, test.want)
+			}
+			if next != test.next {
+				t.Errorf("read(%+v) moved to %+v, want %+v", test.cursor, next, test.next)
+			}
+		})
+	}
+}
+
+// savedCursor is the cursor a transport saves
+type savedCursor struct {
+	transport string
+	cursor    spoolCursor
+}
+
+func TestSpoolCursorRemovesDelivered(t *testing.T) {
+	segments := map[int64]string{0: "a 1\n", 1: "b 2\n", 2: "c 3\n"}
+	tests := []struct {
+		name string
+		// cursors are saved in order, once every transport is draining
+		cursors []savedCursor
+		// left are the segments still in the store
+		left []int64
+	}{
+		{
+			name:    "one transport",
+			cursors: []savedCursor{{"influxdb", spoolCursor{segment: 1, offset: 4}}},
+			left:    []int64{1, 2},
+		},
+		{
+			name:    "the slowest transport",
+			cursors: []savedCursor{{"influxdb", spoolCursor{segment: 2, offset: 4}}, {"other", spoolCursor{segment: 1, offset: 0}}},
+			left:    []int64{1, 2},
+		},
+		{
+			name:    "every transport done",
+			cursors: []savedCursor{{"influxdb", spoolCursor{segment: 2, offset: 4}}, {"other", spoolCursor{segment: 2, offset: 4}}},
+			left:    []int64{2},
+		},
+		{
+			name:    "never the current segment",
+			cursors: []savedCursor{{"influxdb", spoolCursor{segment: 5, offset: 0}}},
+			left:    []int64{2},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := openTestSpool(t, segments)
+			for _, c := range test.cursors {
+				// As Drain does when it starts
+				s.cursors[c.transport] = s.loadCursor(c.transport)
+			}
+			for _, c := range test.cursors {
+				err := s.saveCursor(c.transport, c.cursor)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			left, err := s.segments()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(left, test.left) {
+				t.Errorf("segments %v left, want %v", left, test.left)
+			}
+			for _, c := range test.cursors {
+				if _, err := os.Stat(s.cursorPath(c.transport)); err != nil {
+					t.Errorf("cursor of %s not saved, %s", c.transport, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,352 @@
+package output
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"github.com/iwvelando/daylight-timeseries/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status is the health snapshot the daemon persists to the status file
+type Status struct {
+	Started      time.Time `json:"started"`
+	LastWrite    time.Time `json:"lastWrite"`
+	LastError    time.Time `json:"lastError"`
+	Error        string    `json:"error,omitempty"`
+	WritesOk     uint64    `json:"writesOk"`
+	WritesFailed uint64    `json:"writesFailed"`
+	LastVerified time.Time `json:"lastVerified"`
+	VerifyError  string    `json:"verifyError,omitempty"`
+	VerifyFailed uint64    `json:"verifyFailed"`
+	// Outputs are the write counters of each output by name
+	Outputs map[string]OutputStatus `json:"outputs,omitempty"`
+}
+
+// OutputStatus counts the writes of one output. Points and bytes, the size of
+// the points as line protocol, count the samples the output accepted; an
+// influxdb output accepts them into the client's batches, whose failed
+// writes count as failures later and whose successful writes set
+// LastSuccess. Retries counts the writes retried after an error.
+type OutputStatus struct {
+	Points      uint64    `json:"points"`
+	Bytes       uint64    `json:"bytes"`
+	Failures    uint64    `json:"failures"`
+	Retries     uint64    `json:"retries"`
+	LastSuccess time.Time `json:"lastSuccess"`
+	LastFailure time.Time `json:"lastFailure"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// OutputWrites holds the OutputStatus counters of each output as expvar
+// metrics, served on /v1/metrics
+var OutputWrites = expvar.NewMap("output_writes")
+
+// statusSaveInterval is how often the status file is saved at most for
+// successful output writes alone, which happen every poll
+const statusSaveInterval = 10 * time.Second
+
+// StatusTracker records write outcomes and persists them to the status file
+type StatusTracker struct {
+	mu     sync.Mutex
+	path   string
+	status Status
+	saved  time.Time
+	// interval is how often successful writes alone save the status file,
+	// often enough for the status subcommand to see them within maxAge
+	interval time.Duration
+}
+
+// NewStatusTracker creates a tracker saving to the status file at path, if
+// any, for an instance judged on writes within maxAge
+func NewStatusTracker(path string, started time.Time, maxAge time.Duration) *StatusTracker {
+	return &StatusTracker{
+		path:     path,
+		status:   Status{Started: started},
+		interval: min(statusSaveInterval, maxAge/4),
+	}
+}
+
+// WriteSucceeded records a successful write to the database
+func (s *StatusTracker) WriteSucceeded(t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.LastWrite = t
+	s.status.WritesOk++
+	return s.save()
+}
+
+// WriteFailed records a failed write to the database
+func (s *StatusTracker) WriteFailed(t time.Time, err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.LastError = t
+	s.status.Error = err.Error()
+	s.status.WritesFailed++
+	return s.save()
+}
+
+// output returns the counters of an output, creating them on its first
+// write; callers must hold the lock
+func (s *StatusTracker) output(name string) (OutputStatus, *expvar.Map) {
+	if s.status.Outputs == nil {
+		s.status.Outputs = map[string]OutputStatus{}
+	}
+	metrics, ok := OutputWrites.Get(name).(*expvar.Map)
+	if !ok {
+		metrics = new(expvar.Map).Init()
+		OutputWrites.Set(name, metrics)
+	}
+	return s.status.Outputs[name], metrics
+}
+
+// OutputWritten records an output accepting a point of size bytes, as its
+// last success too when delivered; after the first, the status file is saved
+// with it at most every save interval
+func (s *StatusTracker) OutputWritten(name string, t time.Time, size int, delivered bool) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status, metrics := s.output(name)
+	status.Points++
+	status.Bytes += uint64(size)
+	if delivered {
+		status.LastSuccess = t
+		metrics.Set("last_success", timeVar(t))
+	}
+	s.status.Outputs[name] = status
+	metrics.Add("points", 1)
+	metrics.Add("bytes", int64(size))
+	if status.Points > 1 && t.Sub(s.saved) < s.interval {
+		return nil
+	}
+	return s.save()
+}
+
+// OutputDelivered records an output delivering the points it accepted
+// earlier, e.g. InfluxDB accepting a batch
+func (s *StatusTracker) OutputDelivered(name string, t time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status, metrics := s.output(name)
+	status.LastSuccess = t
+	s.status.Outputs[name] = status
+	metrics.Set("last_success", timeVar(t))
+}
+
+// OutputRetried records an output write being retried after an error
+func (s *StatusTracker) OutputRetried(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status, metrics := s.output(name)
+	status.Retries++
+	s.status.Outputs[name] = status
+	metrics.Add("retries", 1)
+}
+
+// OutputFailed records an output failing to write
+func (s *StatusTracker) OutputFailed(name string, t time.Time, err error) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status, metrics := s.output(name)
+	status.Failures++
+	status.LastFailure = t
+	status.Error = err.Error()
+	s.status.Outputs[name] = status
+	metrics.Add("failures", 1)
+	return s.save()
+}
+
+// timeVar is an expvar metric of a time, served in RFC 3339
+type timeVar time.Time
+
+func (t timeVar) String() string {
+	return strconv.Quote(time.Time(t).Format(time.RFC3339Nano))
+}
+
+// VerifyResult records the outcome of a read-back verification
+func (s *StatusTracker) VerifyResult(t time.Time, err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.status.VerifyError = err.Error()
+		s.status.VerifyFailed++
+	} else {
+		s.status.LastVerified = t
+		s.status.VerifyError = ""
+	}
+	return s.save()
+}
+
+// Save persists the current status
+func (s *StatusTracker) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.save()
+}
+
+// save atomically replaces the status file; callers must hold the lock
+func (s *StatusTracker) save() error {
+	if s.path == "" {
+		return nil
+	}
+	s.saved = time.Now()
+	data, err := json.Marshal(s.status)
+	if err != nil {
+		return fmt.Errorf("unable to encode status, %s", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("unable to create status file, %s", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to write status file %s, %s", tmp.Name(), err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("unable to write status file %s, %s", tmp.Name(), err)
+	}
+	if err = os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("unable to replace status file %s, %s", s.path, err)
+	}
+	return nil
+}
+
+// ReadStatus loads a status file written by a running instance
+func ReadStatus(path string) (Status, error) {
+	var status Status
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return status, fmt.Errorf("unable to read status file %s, %s", path, err)
+	}
+	err = json.Unmarshal(data, &status)
+	if err != nil {
+		return status, fmt.Errorf("unable to decode status file %s, %s", path, err)
+	}
+	return status, nil
+}
+
+// LastSuccess returns the time of the last successful write to any of the
+// named outputs, or to any output when names is empty
+func (s Status) LastSuccess(names []string) time.Time {
+	last := s.LastWrite
+	for name, output := range s.Outputs {
+		if len(names) > 0 && !slices.Contains(names, name) {
+			continue
+		}
+		if output.LastSuccess.After(last) {
+			last = output.LastSuccess
+		}
+	}
+	return last
+}
+
+// lastError returns the latest write error of the named outputs
+func (s Status) lastError(names []string) string {
+	last, message := s.LastError, s.Error
+	for name, output := range s.Outputs {
+		if len(names) > 0 && !slices.Contains(names, name) {
+			continue
+		}
+		if output.Error != "" && output.LastFailure.After(last) {
+			last, message = output.LastFailure, output.Error
+		}
+	}
+	return message
+}
+
+// Check returns an error unless a write to any of the named outputs, or to
+// any output when names is empty, succeeded within maxAge of now; an instance
+// that has not written yet is given maxAge from startup
+func (s Status) Check(now time.Time, maxAge time.Duration, names []string) error {
+	if s.VerifyError != "" {
+		return fmt.Errorf("read-back verification failing: %s", s.VerifyError)
+	}
+	last := s.LastSuccess(names)
+	if last.IsZero() {
+		if now.Sub(s.Started) <= maxAge {
+			return nil
+		}
+		return fmt.Errorf("no successful write since start at %s", s.Started.Format(time.RFC3339))
+	}
+	if age := now.Sub(last); age > maxAge {
+		if message := s.lastError(names); message != "" {
+			return fmt.Errorf("last successful write %s ago, last error: %s", age.Round(time.Second), message)
+		}
+		return fmt.Errorf("last successful write %s ago", age.Round(time.Second))
+	}
+	return nil
+}
+
+// StatusMaxAge returns the configured status age limit, defaulting to two
+// flush intervals plus one poll interval, or plus the heartbeat (a day
+// without one) in transitions mode
+func StatusMaxAge(cfg config.Configuration) time.Duration {
+	if cfg.Status.MaxAge != 0 {
+		return time.Duration(cfg.Status.MaxAge) * time.Second
+	}
+	flushInterval := cfg.InfluxDB.FlushInterval
+	if flushInterval == 0 {
+		flushInterval = config.DefaultFlushInterval
+	}
+	if cfg.WriteMode == config.WriteTransitions {
+		if cfg.Heartbeat > 0 {
+			return 2*flushInterval + cfg.Heartbeat
+		}
+		return 2*flushInterval + 24*time.Hour
+	}
+	return 2*flushInterval + cfg.PollInterval
+}
+
+// Snapshot returns a copy of the current status
+func (s *StatusTracker) Snapshot() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.status
+	status.Outputs = maps.Clone(s.status.Outputs)
+	return status
+}
+
+// StatusTransport reports the outcome of InfluxDB write requests to a
+// tracker, as deliveries of the named output
+type StatusTransport struct {
+	Next    http.RoundTripper
+	Tracker *StatusTracker
+	Output  string
+}
+
+// RoundTrip sends the request, recording a delivery when a write succeeded
+func (t *StatusTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.Next.RoundTrip(req)
+	if err == nil && resp.StatusCode/100 == 2 && (strings.HasSuffix(req.URL.Path, "/write") || strings.HasSuffix(req.URL.Path, "/write_lp")) {
+		t.Tracker.OutputDelivered(t.Output, time.Now())
+		if saveErr := t.Tracker.WriteSucceeded(time.Now()); saveErr != nil {
+			log.WithFields(log.Fields{
+				"op":    "StatusTransport.RoundTrip",
+				"error": saveErr,
+			}).Error("failed to write status file")
+		}
+	}
+	return resp, err
+}
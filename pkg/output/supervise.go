@@ -0,0 +1,59 @@
+package output
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"runtime/debug"
+	"time"
+)
+
+// workerPanics counts the panics recovered by Supervise, by worker name
+var workerPanics = expvar.NewMap("worker_panics")
+
+// Supervise runs worker until it returns or ctx is done, recovering from
+// panics: each is logged with its stack trace, counted in worker_panics and
+// followed by a restart, after a second and backing off up to a minute when
+// the worker keeps panicking
+func Supervise(ctx context.Context, name string, worker func(ctx context.Context)) {
+	backoff := time.Second
+	for {
+		started := time.Now()
+		if !runSupervised(ctx, name, worker) {
+			return
+		}
+		// A worker that ran for a while before panicking restarts quickly
+		if time.Since(started) > time.Minute {
+			backoff = time.Second
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, time.Minute)
+		log.WithFields(log.Fields{
+			"op":     "Supervise",
+			"worker": name,
+		}).Info("restarting worker")
+	}
+}
+
+// runSupervised runs worker once and reports whether it panicked
+func runSupervised(ctx context.Context, name string, worker func(ctx context.Context)) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+			workerPanics.Add(name, 1)
+			log.WithFields(log.Fields{
+				"op":     "Supervise",
+				"worker": name,
+				"error":  fmt.Sprint(r),
+				"stack":  string(debug.Stack()),
+			}).Error("worker panicked")
+		}
+	}()
+	worker(ctx)
+	return false
+}